@@ -0,0 +1,198 @@
+package log
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout of the time of requests in access logs
+const accessTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessFormat defines the format of lines written to the access log, see [SetAccessLogFormat].
+type AccessFormat int
+
+// Supported formats of the access log
+const (
+	// Combined Log Format: Common Log Format followed by the referer and the user agent (default)
+	AccessCombined	=	AccessFormat(iota)
+	// Common Log Format (CLF): host, ident, user, time, request, status and size of the response
+	AccessCommon
+	// Combined Log Format followed by the duration of the request in microseconds (%D of Apache)
+	AccessCombinedDuration
+)
+
+// AccessFields are attributes of the request written to the access log, see [AccessLog].
+// Empty values are written as "-".
+type AccessFields struct {
+	RemoteAddr	string
+	User		string
+	Method		string
+	Path		string
+	Proto		string
+	Status		int
+	Bytes		int64
+	Duration	time.Duration
+	Referer		string
+	UserAgent	string
+	// Time of the request, the current time (see [SetClock]) is used if not set
+	Time		time.Time
+}
+
+// SetAccessLogOutput calls [SetAccessLogOutput] on the l object.
+func (l *Logger) SetAccessLogOutput(path string) error {
+	var err error
+
+	l.execute(func() {
+		if err = l.closeAccess(); err != nil {
+			return
+		}
+
+		l.accessName = path
+		err = l.openAccess()
+	})
+
+	return err
+}
+
+// SetAccessLogFormat calls [SetAccessLogFormat] on the l object.
+func (l *Logger) SetAccessLogFormat(f AccessFormat) {
+	l.execute(func() {
+		l.accessFormat = f
+	})
+}
+
+// AccessLog calls [AccessLog] on the l object.
+func (l *Logger) AccessLog(fields AccessFields) error {
+	// Check for log already closed, the record cannot be written
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	if fields.Time.IsZero() {
+		fields.Time = l.now()
+	}
+
+	var err error
+	l.execute(func() {
+		if l.accessFd == nil {
+			// Access log is not configured, the record is dropped
+			return
+		}
+
+		if _, wErr := io.WriteString(l.accessFd, l.accessLine(&fields)); wErr != nil {
+			err = NewFilePathError(l.accessName, "cannot write record to access log: %w", wErr)
+		}
+	})
+
+	return err
+}
+
+// accessLine formats the record of the access log in the configured format
+func (l *Logger) accessLine(f *AccessFields) string {
+	request := f.Method + " " + f.Path
+	if f.Proto != "" {
+		request += " " + f.Proto
+	}
+
+	size := "-"
+	if f.Bytes > 0 {
+		size = strconv.FormatInt(f.Bytes, 10)
+	}
+
+	line := accessValue(f.RemoteAddr) + " - " + accessValue(f.User) +
+		" [" + f.Time.Format(accessTimeFormat) + "] " +
+		accessQuote(request) + " " + strconv.Itoa(f.Status) + " " + size
+
+	if l.accessFormat != AccessCommon {
+		line += " " + accessQuote(accessValue(f.Referer)) + " " + accessQuote(accessValue(f.UserAgent))
+	}
+	if l.accessFormat == AccessCombinedDuration {
+		line += " " + strconv.FormatInt(f.Duration.Microseconds(), 10)
+	}
+
+	return line + "\n"
+}
+
+func accessValue(v string) string {
+	if v == "" {
+		return "-"
+	}
+
+	return v
+}
+
+// accessQuote quotes the value, escaping quotes and line breaks that would break the record
+func accessQuote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(v) + `"`
+}
+
+// openAccess opens the access log file, if configured
+func (l *Logger) openAccess() error {
+	if l.accessName == "" {
+		// Access log is disabled
+		return nil
+	}
+
+	fd, err := l.fileSystem().OpenFile(l.accessName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, defaultPermMode)
+	if err != nil {
+		return NewFilePathError(l.accessName, "cannot open access log: %w", err)
+	}
+
+	l.accessFd = fd
+
+	return nil
+}
+
+// reopenAccess closes the access log file and opens it again, e.g. after renaming by logrotate
+func (l *Logger) reopenAccess() error {
+	if err := l.closeAccess(); err != nil {
+		return err
+	}
+
+	return l.openAccess()
+}
+
+func (l *Logger) closeAccess() error {
+	if l.accessFd == nil {
+		// Nothing to close
+		return nil
+	}
+
+	fd := l.accessFd
+	l.accessFd = nil
+
+	if err := fd.Close(); err != nil {
+		return NewFilePathError(l.accessName, "cannot close access log: %w", err)
+	}
+
+	return nil
+}
+
+// SetAccessLogOutput opens the file of the access log, records written by [AccessLog] are appended to it.
+// The access log is managed together with the application log: it is reopened by [Reopen] (so external
+// rotation tools such as logrotate can rotate both logs by the single signal) and closed by [Close].
+// The previous file of the access log, if any, is closed. The empty path closes the access log.
+// The log must be opened before calling SetAccessLogOutput.
+func SetAccessLogOutput(path string) error {
+	return logger.SetAccessLogOutput(path)
+}
+
+// SetAccessLogFormat sets the format of records of the access log, default is [AccessCombined].
+func SetAccessLogFormat(f AccessFormat) {
+	logger.SetAccessLogFormat(f)
+}
+
+// AccessLog writes the record about the request to the access log (see [SetAccessLogOutput]), e.g.:
+//
+//	192.0.2.1 - alice [08/Nov/2022:10:00:00 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "curl/7.86.0"
+//
+// Records are written by the writer goroutine of the log after queued messages, AccessLog waits for
+// writing of the record and returns the error of writing, if any. Levels, fields and other settings
+// of the application log do not apply to the access log. If the access log is not configured,
+// the record is dropped.
+func AccessLog(fields AccessFields) error {
+	return logger.AccessLog(fields)
+}
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Period of measuring the rate of messages by the adaptive sampler
+const adaptiveWindow = time.Second
+
+// adaptiveSampler adjusts the share of written messages to keep their rate close to the target,
+// it is used only by the writer goroutine
+type adaptiveSampler struct {
+	target	int
+	// Share of written messages, adjusted at the end of each window
+	ratio	float64
+	// Accumulated share, the message is written when it reaches 1
+	acc		float64
+
+	// Beginning of the current window and the number of messages received since it
+	start	time.Time
+	seen	int
+}
+
+func newAdaptiveSampler(target int) *adaptiveSampler {
+	return &adaptiveSampler{target: target, ratio: 1}
+}
+
+// allow returns false if the message has to be dropped
+func (as *adaptiveSampler) allow(now time.Time) bool {
+	if as.start.IsZero() {
+		as.start = now
+	}
+
+	if elapsed := now.Sub(as.start); elapsed >= adaptiveWindow {
+		// Proportional control: the share of written messages is the target divided by the incoming rate
+		rate := float64(as.seen) * float64(time.Second) / float64(elapsed)
+		as.ratio = 1
+		if rate > float64(as.target) {
+			as.ratio = float64(as.target) / rate
+		}
+
+		as.start, as.seen = now, 0
+	}
+
+	as.seen++
+
+	// Messages are written evenly, e.g. each third message with the ratio 1/3
+	as.acc += as.ratio
+	if as.acc < 1 {
+		return false
+	}
+
+	as.acc--
+	return true
+}
+
+// SetAdaptiveSampling calls [SetAdaptiveSampling] on the l object.
+func (l *Logger) SetAdaptiveSampling(targetPerSec int) {
+	l.execute(func() {
+		atomic.StoreUint64(&l.adaptEmitted, 0)
+		atomic.StoreUint64(&l.adaptDropped, 0)
+
+		if targetPerSec <= 0 {
+			l.adaptive = nil
+			return
+		}
+
+		l.adaptive = newAdaptiveSampler(targetPerSec)
+	})
+}
+
+// AdaptiveSampled calls [AdaptiveSampled] on the l object.
+func (l *Logger) AdaptiveSampled() (emitted, dropped uint64) {
+	return atomic.LoadUint64(&l.adaptEmitted), atomic.LoadUint64(&l.adaptDropped)
+}
+
+// allowAdaptive returns false if the message is dropped by the adaptive sampling
+func (l *Logger) allowAdaptive(level Level) bool {
+	if l.adaptive == nil || level.exits() {
+		// Sampling is disabled, fatal messages are never sampled
+		return true
+	}
+
+	if !l.adaptive.allow(l.now()) {
+		atomic.AddUint64(&l.adaptDropped, 1)
+		return false
+	}
+
+	atomic.AddUint64(&l.adaptEmitted, 1)
+	return true
+}
+
+// SetAdaptiveSampling enables the adaptive sampling that keeps the rate of written messages close
+// to targetPerSec messages per second under unpredictable load, without tuning static limits (see
+// [SetRateLimit]). The rate of incoming messages is measured each second (by the clock set by [SetClock]),
+// then the share of written messages for the next second is set to targetPerSec divided by this rate,
+// so more messages are dropped as the volume rises, and all messages are written when it falls below
+// the target. Dropped messages are spread evenly, regardless of their levels, and counted (see
+// [AdaptiveSampled]). Sampling is performed by the writer goroutine after other limits, so statistic
+// functions (see [SetStatFuncs]) are called for dropped messages too. Fatal messages and raw records
+// (see [Raw]) are never sampled. Zero or negative targetPerSec disables the adaptive sampling.
+func SetAdaptiveSampling(targetPerSec int) {
+	logger.SetAdaptiveSampling(targetPerSec)
+}
+
+// AdaptiveSampled returns the numbers of messages written and dropped by the adaptive sampling
+// (see [SetAdaptiveSampling]). Counters are reset when the sampling is changed.
+func AdaptiveSampled() (emitted, dropped uint64) {
+	return logger.AdaptiveSampled()
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DebugAt calls [DebugAt] on the l object.
+func (l *Logger) DebugAt(t time.Time, format string, v ...any) {
+	if !l.WouldLog(LevelDebug) {
+		return
+	}
+	l.writeEvent(&logMsg{level: LevelDebug, format: format, args: v, time: t, timeSet: true})
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
+
+// InfoAt calls [InfoAt] on the l object.
+func (l *Logger) InfoAt(t time.Time, format string, v ...any) {
+	l.writeEvent(&logMsg{level: LevelInfo, format: format, args: v, time: t, timeSet: true})
+
+	// Call statistic function if was set
+	if l.infEventStat != nil {
+		l.infEventStat(format, v...)
+	}
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
+
+// WarnAt calls [WarnAt] on the l object.
+func (l *Logger) WarnAt(t time.Time, format string, v ...any) {
+	l.writeEvent(&logMsg{level: LevelWarn, format: format, args: v, time: t, timeSet: true})
+
+	// Call statistic function if was set
+	if l.wrnEventStat != nil {
+		l.wrnEventStat(format, v...)
+	}
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
+
+// ErrAt calls [ErrAt] on the l object.
+func (l *Logger) ErrAt(t time.Time, format string, v ...any) {
+	l.writeEvent(&logMsg{level: LevelError, format: format, args: v, time: t, timeSet: true})
+
+	// Call statistic function if was set
+	if l.errEventStat != nil {
+		l.errEventStat(format, v...)
+	}
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
+
+// DebugAt works like [Debug], but the message is logged with the timestamp t instead
+// of the current time, e.g. to replay historical events. See [InfoAt] for details.
+func DebugAt(t time.Time, format string, v ...any) {
+	if !DebugBuild || atomic.LoadInt32(&pkgLevel) > int32(LevelDebug) {
+		return
+	}
+	logger.DebugAt(t, format, v...)
+}
+
+// InfoAt works like [Info], but the message is logged with the timestamp t instead
+// of the current time, e.g. to replay historical events.
+//
+// The standard date and time flags (log.Ldate, log.Ltime and log.Lmicroseconds) cannot
+// show an arbitrary time, so they are ignored for such messages. Instead, t is formatted
+// by the package using the time format set by [SetTimeFormat] and written at the beginning
+// of the line, before the prefix. The timestamp is written regardless of the flags.
+// Targets that receive structured entries (e.g. journald) get t as the time of the entry.
+func InfoAt(t time.Time, format string, v ...any) {
+	logger.InfoAt(t, format, v...)
+}
+
+// WarnAt works like [Warn], but the message is logged with the timestamp t instead
+// of the current time, e.g. to replay historical events. See [InfoAt] for details.
+func WarnAt(t time.Time, format string, v ...any) {
+	logger.WarnAt(t, format, v...)
+}
+
+// ErrAt works like [Err], but the message is logged with the timestamp t instead
+// of the current time, e.g. to replay historical events. See [InfoAt] for details.
+func ErrAt(t time.Time, format string, v ...any) {
+	logger.ErrAt(t, format, v...)
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"fmt"
+)
+
+// Tag of audit messages
+const auditTag = "<AUDIT> "
+
+// AuditKey is the key of the field that marks audit messages written to targets
+// that receive structured entries (e.g. journald), see [Audit].
+const AuditKey = "audit"
+
+// SetAuditStatFunc calls [SetAuditStatFunc] on the l object.
+func (l *Logger) SetAuditStatFunc(af StatFunc) {
+	l.audEventStat = af
+}
+
+// Audit calls [Audit] on the l object.
+func (l *Logger) Audit(format string, v ...any) error {
+	// Check for log already closed, the message cannot be written
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	msg := &logMsg{
+		level:		LevelInfo,
+		format:		format,
+		args:		v,
+		fields:		l.fields,
+		component:	l.component,
+		time:		l.now(),
+	}
+	if l.callerFunc {
+		msg.caller = callerName()
+	}
+
+	var err error
+	l.execute(func() {
+		err = l.writeAudit(msg)
+	})
+
+	// Call statistic function if was set
+	if l.audEventStat != nil {
+		l.audEventStat(format, v...)
+	}
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+
+	return err
+}
+
+// writeAudit writes the audit message and syncs the log target, errors are returned instead
+// of passing them to the error handler
+func (l *Logger) writeAudit(msg *logMsg) error {
+	text := fmt.Sprintf(msg.format, msg.args...)
+
+	// Send the entry to subscribers if any
+	l.publish(msg, text)
+
+	full := l.versionTag + componentTag(msg.component) + auditTag + text + l.formatFields(msg.fields) + callerTag(msg.caller)
+
+	// Rotate the log file if required
+	l.rotateByAge(l.now())
+
+	var line []byte
+	var entry *Entry
+	var err error
+
+	// Lines are rendered in the output format, JSON objects are marked by the audit field
+	render := func(f OutputFormat) []byte {
+		if f == FormatJSON {
+			if entry == nil {
+				entry = l.auditEntry(msg, text)
+			}
+			return l.jsonLine(entry)
+		}
+		return l.formatLine(msg, full)
+	}
+
+	// Does the target write structured entries?
+	if ew, ok := l.target.(entryWriter); ok {
+		entry = l.auditEntry(msg, text)
+		err = ew.writeEntry(l, entry)
+	} else {
+		line = render(l.outFormat)
+		err = l.writeTarget(msg.level, line)
+	}
+	if err != nil {
+		return NewFilePathError(l.logName, "cannot write audit message to log: %w", err)
+	}
+	l.unflushed++
+	l.countWritten(msg.level)
+
+	// Write the message to additional writers, their errors are passed to the error handler
+	l.writeSinks(msg.level, entry, line, func() ([]byte, *Entry) {
+		if line == nil {
+			line = render(l.outFormat)
+		}
+		if entry == nil {
+			entry = l.auditEntry(msg, text)
+		}
+
+		return line, entry
+	}, render)
+
+	return l.syncTarget()
+}
+
+// auditEntry returns the entry of the audit message, marked by the audit field
+func (l *Logger) auditEntry(msg *logMsg, text string) *Entry {
+	entry := newEntry(msg, text)
+	entry.Fields = append(entry.Fields[:len(entry.Fields):len(entry.Fields)], Field{Key: AuditKey, Value: true})
+
+	return entry
+}
+
+// Audit writes an audit message prefixed with <AUDIT> to the log, e.g. for security-relevant events.
+// Unlike other logging functions, audit messages cannot be dropped: they are written regardless of
+// the level, pausing (see [Pause]) and rate limits (see [SetRateLimit]), stack traces are not
+// deduplicated. Audit waits until the message is written and the log file is synced to the storage
+// (like [Barrier]), then it returns the error of writing or syncing to the caller, instead of passing
+// it to the error handler and to the fallback file. Targets that receive structured entries
+// (e.g. journald) and the [FormatJSON] format (see [SetOutputFormat]) get the message as an information
+// entry with the [AuditKey] field set to true.
+// It also calls the audit statistics handler, if previously set with [SetAuditStatFunc].
+//
+// Each call costs at least one fsync, so Audit is intended only for rare important events.
+func Audit(format string, v ...any) error {
+	return logger.Audit(format, v...)
+}
+
+// SetAuditStatFunc sets the af audit message statistics handler, it is called
+// in the same way as the error and warning handlers set by [SetStatFuncs].
+func SetAuditStatFunc(af StatFunc) {
+	logger.SetAuditStatFunc(af)
+}
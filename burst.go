@@ -0,0 +1,42 @@
+package log
+
+import (
+	"time"
+)
+
+// SetErrorBurstCallback calls [SetErrorBurstCallback] on the l object.
+func (l *Logger) SetErrorBurstCallback(quiet time.Duration, fn func()) {
+	l.execute(func() {
+		l.burstQuiet = quiet
+		l.burstFn = fn
+		l.lastErr = time.Time{}
+	})
+}
+
+// detectBurst calls the callback set by SetErrorBurstCallback if the error message
+// is the first one after the quiet period
+func (l *Logger) detectBurst(level Level) {
+	if l.burstFn == nil || level < LevelError {
+		return
+	}
+
+	now := l.now()
+	quiet := l.lastErr.IsZero() || now.Sub(l.lastErr) >= l.burstQuiet
+	l.lastErr = now
+
+	if quiet {
+		l.burstFn()
+	}
+}
+
+// SetErrorBurstCallback sets the function called when errors start to happen after the quiet period: fn is
+// called for the error message (including fatal messages and custom levels above errors) written after at
+// least quiet without error messages, and for the first error message after setting the callback. Errors
+// that follow within quiet after the previous error do not call fn, so it fires once per burst of errors,
+// e.g. to raise the alert when the incident starts instead of alerting on each error. Time is taken from
+// the clock set by [SetClock]. fn is called by the writer goroutine before writing the message, so it must
+// be fast and must not write to the same log, nil disables the callback (default). SetErrorBurstCallback
+// must be called after opening the log.
+func SetErrorBurstCallback(quiet time.Duration, fn func()) {
+	logger.SetErrorBurstCallback(quiet, fn)
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// FuncKey is the key of the field with the name of the calling function, see [SetCallerFunc].
+const FuncKey = "func"
+
+// Maximum number of frames inspected to find the caller outside of the package
+const callerMaxDepth = 16
+
+type pkgMarker struct{}
+
+// Prefix of names of the package functions, that are skipped when looking for the caller
+//
+//nolint:gochecknoglobals // constant computed at startup
+var pkgFuncPrefix = reflect.TypeOf(pkgMarker{}).PkgPath() + "."
+
+// SetCallerFunc calls [SetCallerFunc] on the l object.
+func (l *Logger) SetCallerFunc(v bool) {
+	l.callerFunc = v
+}
+
+// callerName returns the fully-qualified name of the first function outside of the package
+func callerName() string {
+	pcs := make([]uintptr, callerMaxDepth)
+	// Skip runtime.Callers and callerName itself
+	n := runtime.Callers(2, pcs)	//nolint:gomnd // described above
+
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+
+		// Functions of tests of the package are callers too
+		if !strings.HasPrefix(frame.Function, pkgFuncPrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.Function
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
+
+// shortFuncName returns the function name without the package path, e.g. pkg.Func
+func shortFuncName(name string) string {
+	return name[strings.LastIndexByte(name, '/') + 1:]
+}
+
+// callerTag returns the text representation of the calling function name
+func callerTag(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return " (" + shortFuncName(name) + ")"
+}
+
+// SetCallerFunc enables (v == true) or disables (default) adding of the name of the function
+// that called the logging function to messages. Text logs get the short name in parentheses
+// after the message (e.g. "app: message (main.run)"), targets that receive structured entries
+// (e.g. journald) get the fully-qualified name as the field with the [FuncKey] key.
+// Wrappers of the package (e.g. [Info] and [Logger.Info]) are skipped, so the name is the same
+// regardless of which function was used. It works independently from the log.Lshortfile
+// and log.Llongfile flags. Getting the name costs a stack walk for each message.
+func SetCallerFunc(v bool) {
+	logger.SetCallerFunc(v)
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"reflect"
+)
+
+// Keys of fields of messages written by Change
+const (
+	ChangeEntityKey	=	"entity"
+	ChangeFieldKey	=	"field"
+	ChangeOldKey	=	"old"
+	ChangeNewKey	=	"new"
+)
+
+// Format of messages written by Change
+const changeFormat = "%s.%s changed: old=%v new=%v"
+
+// changeValue formats the value of the changed field like values of fields, it is formatted
+// by the writer goroutine, that owns settings of formatting (see SetDurationFormat)
+type changeValue struct {
+	l	*Logger
+	v	any
+}
+
+func (cv changeValue) String() string {
+	return quoteValue(cv.l.fieldValue(cv.v))
+}
+
+// Change calls [Change] on the l object.
+func (l *Logger) Change(entity, field string, oldValue, newValue any) {
+	if unchanged(oldValue, newValue) {
+		return
+	}
+
+	args := []any{entity, field, changeValue{l, oldValue}, changeValue{l, newValue}}
+	l.writeEvent(&logMsg{level: LevelInfo, format: changeFormat, args: args, extra: []Field{
+		{Key: ChangeEntityKey, Value: entity},
+		{Key: ChangeFieldKey, Value: field},
+		{Key: ChangeOldKey, Value: oldValue},
+		{Key: ChangeNewKey, Value: newValue},
+	}})
+
+	// Call statistic function if was set
+	if l.infEventStat != nil {
+		l.infEventStat(changeFormat, args...)
+	}
+}
+
+// unchanged reports whether values are equal. Values of basic types are compared by ==, other values
+// (e.g. slices or structs, which can hold not comparable values in interface fields) are compared deeply
+func unchanged(oldValue, newValue any) bool {
+	t := reflect.TypeOf(oldValue)
+	if t == nil || t != reflect.TypeOf(newValue) {
+		// Values of different types are not equal
+		return oldValue == nil && newValue == nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128,
+		reflect.String, reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return oldValue == newValue
+	default:
+		return reflect.DeepEqual(oldValue, newValue)
+	}
+}
+
+// Change writes the informational message about the change of the field of the entity, e.g. for audit
+// trails: Change("user", "email", "a@example.com", "b@example.com") writes
+//
+//	user.email changed: old=a@example.com new=b@example.com
+//
+// Values are formatted like values of fields (see [SetDurationFormat] and [SetTimeFormat]) and quoted
+// if needed. Structured entries (e.g. the [FormatJSON] format, see [SetOutputFormat], and subscribers,
+// see [Subscribe]) also get the entity, the field and both values as fields with keys [ChangeEntityKey],
+// [ChangeFieldKey], [ChangeOldKey] and [ChangeNewKey], in the text format they are not duplicated.
+// If oldValue equals newValue, nothing is written, values that are not numbers, strings, booleans
+// or pointers (e.g. slices and structs) are compared deeply by [reflect.DeepEqual].
+func Change(entity, field string, oldValue, newValue any) {
+	logger.Change(entity, field, oldValue, newValue)
+}
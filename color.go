@@ -0,0 +1,132 @@
+package log
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Color is the ANSI color of level tags, see [SetLevelColor].
+type Color int
+
+// Supported colors
+const (
+	// Level tags are not colored
+	ColorNone	=	Color(iota)
+	ColorBlack
+	ColorRed
+	ColorGreen
+	ColorYellow
+	ColorBlue
+	ColorMagenta
+	ColorCyan
+	ColorWhite
+	// Bright black
+	ColorGray
+)
+
+// Escape sequence that resets the color
+const colorReset = "\x1b[0m"
+
+// escape returns the escape sequence that sets the color, or the empty string for ColorNone
+func (c Color) escape() string {
+	switch {
+	case c >= ColorBlack && c <= ColorWhite:
+		// Foreground colors start from 30 (black)
+		return "\x1b[" + strconv.Itoa(29 + int(c)) + "m"
+	case c == ColorGray:
+		return "\x1b[90m"
+	default:
+		return ""
+	}
+}
+
+// defaultColor returns the color of the level used if no color was set by SetLevelColor,
+// custom levels use colors of the nearest built-in levels
+func defaultColor(level Level) Color {
+	switch {
+	case level.exits(), level >= LevelError:
+		return ColorRed
+	case level >= LevelWarn:
+		return ColorYellow
+	case level >= LevelInfo:
+		return ColorNone
+	default:
+		return ColorGray
+	}
+}
+
+//nolint:gochecknoglobals // Reports whether the writer is a terminal, replaced by tests
+var isTerminal = func(w io.Writer) bool {
+	fd, ok := w.(File)
+	if !ok {
+		return false
+	}
+
+	fi, err := fd.Stat()
+	if err != nil || fi.Mode() & os.ModeCharDevice == 0 {
+		return false
+	}
+
+	// The null device is a character device too
+	nullFi, err := os.Stat(os.DevNull)
+
+	return err != nil || !os.SameFile(fi, nullFi)
+}
+
+// SetColors calls [SetColors] on the l object.
+func (l *Logger) SetColors(v bool) {
+	l.execute(func() {
+		l.colors = v
+	})
+}
+
+// SetLevelColor calls [SetLevelColor] on the l object.
+func (l *Logger) SetLevelColor(level Level, color Color) {
+	l.execute(func() {
+		if l.levelColors == nil {
+			l.levelColors = map[Level]Color{}
+		}
+		l.levelColors[level] = color
+	})
+}
+
+// colorTag returns the level tag wrapped by escape sequences of the color of the level, if colors
+// are enabled and the log target is a terminal, otherwise the tag is returned as is
+func (l *Logger) colorTag(level Level, tag string) string {
+	if !l.colors || !l.colorTerm || l.outFormat != FormatText {
+		return tag
+	}
+
+	color, ok := l.levelColors[level]
+	if !ok {
+		color = defaultColor(level)
+	}
+
+	// Spaces that separate the tag or pad it to the column (see SetLevelWidth) are not colored
+	name := strings.TrimRight(tag, " ")
+	if name == "" || color == ColorNone {
+		return tag
+	}
+
+	return color.escape() + name + colorReset + tag[len(name):]
+}
+
+// SetColors enables (v == true) or disables coloring of level tags written to the log target by ANSI
+// escape sequences. Tags are colored only in the [FormatText] format and only if the log target is
+// a terminal (e.g. [OpenWriter] is called with os.Stdout that is not redirected), additional writers
+// (see [AddWriter]), the stderr mirror and files are never colored. Colors of levels can be changed
+// by [SetLevelColor]. SetColors must be called after opening the log, the default is disabled.
+func SetColors(v bool) {
+	logger.SetColors(v)
+}
+
+// SetLevelColor sets the color of tags of the level, used when colors are enabled by [SetColors].
+// By default debug tags are gray, warnings are yellow, errors and fatal messages are red, informational
+// messages have no tags in the default severity style (see [SetSeverityStyle]) and are not colored.
+// Custom levels (see [RegisterLevel]) get colors of the nearest built-in levels. [ColorNone] disables
+// coloring of the level. SetLevelColor must be called after opening the log.
+func SetLevelColor(level Level, color Color) {
+	logger.SetLevelColor(level, color)
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"compress/gzip"
+	"strings"
+	"time"
+)
+
+const (
+	// Suffix of log file names that are compressed on the fly
+	gzipSuffix	=	".gz"
+
+	// How often the writer goroutine flushes compressed data to the log file
+	compressFlushInterval	=	time.Second
+)
+
+// gzipFile is the log file compressed on the fly
+type gzipFile struct {
+	gz		*gzip.Writer
+	fd		File
+	dirty	bool	// there are unflushed data
+}
+
+func newGzipFile(fd File) *gzipFile {
+	return &gzipFile{gz: gzip.NewWriter(fd), fd: fd}
+}
+
+func (g *gzipFile) Write(p []byte) (int, error) {
+	g.dirty = true
+	return g.gz.Write(p)
+}
+
+// Flush writes buffered compressed data to the file
+func (g *gzipFile) Flush() error {
+	if !g.dirty {
+		return nil
+	}
+
+	g.dirty = false
+	return g.gz.Flush()
+}
+
+func (g *gzipFile) Sync() error {
+	if err := g.Flush(); err != nil {
+		return err
+	}
+
+	// Files of custom file systems may not support syncing
+	if s, ok := g.fd.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+
+	return nil
+}
+
+func (g *gzipFile) Close() error {
+	// Write the gzip footer
+	err := g.gz.Close()
+
+	// The file has to be closed anyway
+	if cErr := g.fd.Close(); err == nil {
+		err = cErr
+	}
+
+	return err
+}
+
+// SetCompressLive calls [SetCompressLive] on the l object.
+func (l *Logger) SetCompressLive(v bool) {
+	l.compressLive = v
+}
+
+// compressed returns true if the log file has to be compressed on the fly
+func (l *Logger) compressed() bool {
+	return l.opener == nil && l.logName != DefaultLog &&
+		(l.compressLive || strings.HasSuffix(l.logName, gzipSuffix))
+}
+
+// flushTarget writes data buffered by the compressed log file
+func (l *Logger) flushTarget() {
+	if g, ok := l.target.(*gzipFile); ok {
+		if err := g.Flush(); err != nil {
+			l.handleError(NewFilePathError(l.logName, "cannot flush compressed log file: %w", err))
+		}
+	}
+}
+
+// SetCompressLive enables (v == true) or disables compression of the log file on the fly
+// using gzip. Compression is also enabled for log files with the .gz suffix regardless of
+// this setting.
+//
+// NOTE: SetCompressLive must be called before opening the log, use [Options].CompressLive
+// to enable compression for the package logger.
+//
+// Compressed data are flushed to the file by the writer goroutine every second, by [Barrier]
+// and on closing, so in case of a crash only the last second of messages can be lost.
+// In the synchronous mode (see [Logger.SetSync]) data are flushed only by [Barrier] and on closing.
+// Each opening of the file appends a new gzip member to it, the result is still readable
+// by gzip tools (e.g. zcat), but such files cannot be followed line-by-line by tail -f.
+func SetCompressLive(v bool) {
+	logger.SetCompressLive(v)
+}
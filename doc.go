@@ -5,13 +5,20 @@ Package key features are:
 
  * Process identifier (PID) in log messages, optional
  * Log file reopening function to support logs rotation
+ * Age-based log file rotation and on-the-fly gzip compression of log files
+ * Writing to Unix domain sockets (datagram and stream) with reconnection
+ * Writing to the systemd journal using the native protocol (Linux only), alone or in addition to a file
+ * Additional writers that receive all messages written to the log
  * Support for setting statistics functions
+ * Structured fields (key=value pairs) added by child loggers, see [With]
+ * Optional JSON output format, one object per line, see [SetOutputFormat]
  * Support for configuration with the flags of the standard [log] package
  * Debug function to write messages to the log file only when debug mode is enabled
  * By default, timestamps are disabled, to avoid duplicating timestamps when working
    under the supervisor (systemd and so on)
  * Error and Fatal messages are duplicated in the stderr
  * Concurrency safe using goroutines + channels
+ * Optional synchronous mode without the writer goroutine for short-lived programs
 
 # Basic usage
 
@@ -42,9 +49,13 @@ Package key features are:
 
 # Important notes
 
- * Writing messages into the log before calling [Open] will cause a panic
+ * Writing messages into the log before calling [Open] will cause a panic, except debug messages
+   when the level set by [SetInitialLevel] is above [LevelDebug], unless messages are buffered
+   by [SetPreOpenBuffer]
  * [SetFlags] must be called after calling [Open], otherwise it will cause a panic
  * [Close] must be called before exiting the progam to avoid loss of the last log messages.
+ * All messages are written by a single goroutine, so the order of error messages
+   duplicated to stderr is always the same as their order in the log.
 
 [log]: https://pkg.go.dev/log
 
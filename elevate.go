@@ -0,0 +1,80 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//nolint:gochecknoglobals // Schedules reverting of elevated levels, replaced by tests
+var afterFunc = func(d time.Duration, f func()) (stop func() bool) {
+	return time.AfterFunc(d, f).Stop
+}
+
+// elevation is the temporary level set by ElevateLevel
+type elevation struct {
+	prior	Level		// level restored when the elevation expires
+	until	time.Time	// expiration time by the clock of the logger
+	stop	func() bool	// stops the timer of reverting
+}
+
+// ElevateLevel calls [ElevateLevel] on the l object.
+func (l *Logger) ElevateLevel(to Level, d time.Duration) {
+	l.elevMu.Lock()
+	defer l.elevMu.Unlock()
+
+	prior := Level(atomic.LoadInt32(&l.level))
+	if l.elevated != nil {
+		// The level before the first elevation is restored
+		prior = l.elevated.prior
+		l.elevated.stop()
+	}
+
+	e := &elevation{prior: prior, until: l.now().Add(d)}
+	e.stop = afterFunc(d, func() { l.revertLevel(e) })
+	l.elevated = e
+
+	l.storeLevel(to)
+}
+
+// revertLevel restores the level changed by the elevation, unless it was replaced by SetLevel
+// or by another elevation. Timers do not follow the clock of the logger (see SetClock),
+// so the elevation is extended until the clock reaches the expiration time.
+func (l *Logger) revertLevel(e *elevation) {
+	l.elevMu.Lock()
+	defer l.elevMu.Unlock()
+
+	if l.elevated != e {
+		// The elevation was cancelled or replaced
+		return
+	}
+
+	if left := e.until.Sub(l.now()); left > 0 {
+		e.stop = afterFunc(left, func() { l.revertLevel(e) })
+		return
+	}
+
+	l.elevated = nil
+	l.storeLevel(e.prior)
+}
+
+// cancelElevation stops reverting of the elevated level, elevMu must be locked
+func (l *Logger) cancelElevation() {
+	if l.elevated != nil {
+		l.elevated.stop()
+		l.elevated = nil
+	}
+}
+
+// ElevateLevel temporarily sets the level (see [SetLevel]) to the level to for the duration d, then
+// restores the level set before, e.g. to write debug messages for 5 minutes on request of the admin
+// endpoint without the risk of leaving debug logging on forever:
+//
+//	ElevateLevel(LevelDebug, 5 * time.Minute)
+//
+// Calling ElevateLevel again before the level is restored sets the new level and restarts the timer,
+// the level set before the first call is restored. Calling [SetLevel] (or [SetDebug]) cancels restoring,
+// the level set by it is kept. The level is restored by the timer, but not before the time returned by
+// the clock (see [SetClock]) reaches the end of the duration.
+func ElevateLevel(to Level, d time.Duration) {
+	logger.ElevateLevel(to, d)
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"strings"
+)
+
+// emitWriter passes formatted messages to the callback set by OpenFunc
+type emitWriter func(level Level, msg string)
+
+func (ew emitWriter) Write(p []byte) (int, error) {
+	return ew.WriteLevel(LevelInfo, p)
+}
+
+func (ew emitWriter) WriteLevel(level Level, p []byte) (int, error) {
+	ew(level, strings.TrimSuffix(string(p), "\n"))
+
+	return len(p), nil
+}
+
+// OpenFunc calls [OpenFunc] on the l object.
+func (l *Logger) OpenFunc(emit func(level Level, msg string), prefix string, flags int) error {
+	return l.OpenWriter(emitWriter(emit), prefix, flags)
+}
+
+// OpenFunc opens the log that passes each formatted message to the emit callback instead of writing it,
+// e.g. to forward messages to the logging pipeline of the host application of a plugin. The msg argument
+// is the line of the message as it would be written to the file (with the prefix, the level tag and fields,
+// see [SetOutputFormat]) without the trailing newline. Lines written without the level, such as the run
+// separator (see [SetRunSeparator]), are passed with [LevelInfo]. The meaning of prefix and flags is
+// the same as for [Open]. Emit is called by the writer goroutine, one call at a time, so it does not need
+// to be safe for concurrent use, but it should not block. [Reopen] does nothing and [Barrier] only waits
+// for queued messages, as there is nothing to sync. [Close] stops the writer goroutine.
+func OpenFunc(emit func(level Level, msg string), prefix string, flags int) error {
+	logger = newDefaultLogger()
+	return logger.OpenFunc(emit, prefix, flags)
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// Entry is a log message with its attributes, it is used by log targets
+// that write messages in a structured form instead of text lines.
+type Entry struct {
+	Level	Level
+	Time	time.Time
+	Message	string
+	Fields	[]Field
+}
+
+// entryWriter is implemented by log targets that write entries instead of formatted lines
+type entryWriter interface {
+	writeEntry(l *Logger, e *Entry) error
+}
+
+// newEntry returns the entry of the message with the formatted text
+func newEntry(msg *logMsg, text string) *Entry {
+	fields := msg.fields
+	if msg.component != "" {
+		// Write the component as the first field
+		fields = append([]Field{{Key: ComponentKey, Value: msg.component}}, fields...)
+	}
+
+	if len(msg.extra) != 0 {
+		fields = append(fields[:len(fields):len(fields)], msg.extra...)
+	}
+
+	if msg.caller != "" {
+		// The calling function is the last field
+		fields = append(fields[:len(fields):len(fields)], Field{Key: FuncKey, Value: msg.caller})
+	}
+
+	if len(msg.stack) != 0 {
+		fields = append(fields[:len(fields):len(fields)], Field{Key: StackKey, Value: msg.stack})
+	}
+
+	return &Entry{Level: msg.level, Time: msg.time, Message: text, Fields: fields}
+}
+
+// SetEntryProcessor calls [SetEntryProcessor] on the l object.
+func (l *Logger) SetEntryProcessor(fn func(*Entry)) {
+	l.execute(func() {
+		l.entryProc = fn
+	})
+}
+
+// processEntry passes the message to the entry processor set by SetEntryProcessor
+// and applies changes of the entry to the message, it returns the new text
+func (l *Logger) processEntry(msg *logMsg, text string) string {
+	if l.entryProc == nil {
+		return text
+	}
+
+	// Fields of the logger are shared with other messages, so the processor gets the copy
+	e := &Entry{
+		Level:		msg.level,
+		Time:		msg.time,
+		Message:	text,
+		Fields:		append([]Field(nil), msg.fields...),
+	}
+	l.entryProc(e)
+
+	if e.Level.valid() {
+		msg.level = e.Level
+	} else {
+		l.handleError(fmt.Errorf("entry processor set unknown level %d, level %v is kept", int32(e.Level), msg.level))
+	}
+	msg.time = e.Time
+	msg.fields = e.Fields
+
+	return e.Message
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"fmt"
+)
+
+// ErrorKey is the key of the field with the error passed to [Errf] and [Warnf].
+const ErrorKey = "error"
+
+// Errf calls [Errf] on the l object.
+func (l *Logger) Errf(err error, format string, v ...any) {
+	msg := errMsg(LevelError, err, format, v)
+	l.writeEvent(msg)
+
+	// Call statistic function if was set
+	if l.errEventStat != nil {
+		l.errEventStat(msg.format, msg.args...)
+	}
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
+
+// Warnf calls [Warnf] on the l object.
+func (l *Logger) Warnf(err error, format string, v ...any) {
+	msg := errMsg(LevelWarn, err, format, v)
+	l.writeEvent(msg)
+
+	// Call statistic function if was set
+	if l.wrnEventStat != nil {
+		l.wrnEventStat(msg.format, msg.args...)
+	}
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
+
+// errMsg returns the message with the error appended to the text and added as the field of structured entries
+func errMsg(level Level, err error, format string, v []any) *logMsg {
+	msg := &logMsg{level: level, format: format, args: v}
+	if err == nil {
+		return msg
+	}
+
+	// Do not modify arguments of the caller
+	msg.format += ": %v"
+	msg.args = append(v[:len(v):len(v)], err)
+	msg.extra = []Field{{Key: ErrorKey, Value: err}}
+
+	return msg
+}
+
+// Errf writes the error message like [Err], with err appended to the text as ": <err>", e.g.
+// Errf(err, "cannot process %s", id) writes "<ERR> cannot process 42: file not found". Structured
+// entries (e.g. the [FormatJSON] format, see [SetOutputFormat], and subscribers, see [Subscribe])
+// also get err as the field with the [ErrorKey] key, in the text format it is not duplicated by
+// the field. If err is nil, neither the suffix nor the field is added. The error statistic function
+// (see [SetStatFuncs]) receives the message with the suffix.
+func Errf(err error, format string, v ...any) {
+	logger.Errf(err, format, v...)
+}
+
+// Warnf works like [Errf], but writes the warning message like [Warn].
+func Warnf(err error, format string, v ...any) {
+	logger.Warnf(err, format, v...)
+}
@@ -1,6 +1,25 @@
 package log
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBadFormat is passed (wrapped) to the error handler when the format of the message
+// does not match its arguments, see [SetStrictFormat].
+var ErrBadFormat = errors.New("format of log message does not match arguments")
+
+// ErrDrainTimeout is returned (wrapped) by Close when queued messages are not written
+// within the drain timeout, see [SetDrainTimeout].
+var ErrDrainTimeout = errors.New("log is closed before all queued messages are written")
+
+// ErrBadTemplate is passed (wrapped) to the error handler when the template
+// has placeholders without values, see [Tmpl].
+var ErrBadTemplate = errors.New("log message template has placeholders without values")
+
+// ErrFailover is passed (wrapped) to the error handler when messages start to be rerouted
+// to the fallback logger, see [SetFallback].
+var ErrFailover = errors.New("log target failed, messages are rerouted to the fallback logger")
 
 type OpError struct {
 	err error
@@ -12,10 +31,20 @@ func (e *OpError) Error() string {
 type FileError struct {
 	OpError
 	fileErr	error
+	path	string
 }
 func (ef *FileError) Unwrap() error {
 	return ef.fileErr
 }
+// Path returns the path of the file (or socket) that caused the error,
+// it is empty if the path is unknown (e.g. for writers provided by the caller).
+func (ef *FileError) Path() string {
+	return ef.path
+}
 func NewFileError(format string, err error) error {
-	return &FileError{OpError{fmt.Errorf(format, err)}, err}
+	return NewFilePathError("", format, err)
+}
+// NewFilePathError works like NewFileError, but also keeps the path of the file that caused the error.
+func NewFilePathError(path, format string, err error) error {
+	return &FileError{OpError{fmt.Errorf(format, err)}, err, path}
 }
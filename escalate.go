@@ -0,0 +1,56 @@
+package log
+
+import (
+	"regexp"
+)
+
+// escalationRule raises the level of messages with the text matching the pattern
+type escalationRule struct {
+	pattern	*regexp.Regexp
+	to		Level
+}
+
+// AddEscalationRule calls [AddEscalationRule] on the l object.
+func (l *Logger) AddEscalationRule(pattern *regexp.Regexp, to Level) {
+	l.execute(func() {
+		l.escalations = append(l.escalations, escalationRule{pattern: pattern, to: to})
+	})
+}
+
+// escalate raises the level of the message with the formatted text according to the first matching
+// rule, the statistic function of the new level is called for escalated messages
+func (l *Logger) escalate(msg *logMsg, text string) {
+	for _, rule := range l.escalations {
+		if !rule.pattern.MatchString(text) {
+			continue
+		}
+
+		if rule.to > msg.level {
+			msg.level = rule.to
+			l.callStat(msg.level, msg.format, msg.args...)
+		}
+
+		// The first matching rule wins
+		return
+	}
+}
+
+// AddEscalationRule adds the rule that raises the level of messages with the text (without the prefix
+// and fields) matching the pattern to the to level, e.g. to write messages about exhausted memory
+// as errors regardless of the level used by the code that wrote them. Rules are checked in the order
+// of adding, the first matching rule wins, rules with levels lower than the level of the message
+// do not change it. The escalated message gets the tag of the new level and all effects of it:
+// the stderr mirror of errors (see [SetStderrMirror]), the statistic function of the level, called by
+// the writer goroutine in addition to the statistic function of the original level, and so on.
+// Raw messages (see [Raw]) are not checked. The level of the log (see [SetLevel]) is checked
+// before escalation, so dropped messages are never escalated. AddEscalationRule must be called
+// after opening the log.
+//
+// NOTE: Escalation to [LevelFatal] (or to the custom level that exits, see [RegisterLevel])
+// terminates the program after writing the message, even if it was written by the innocent Info
+// of the third-party code. Patterns that are too broad or messages that include user input (e.g. request
+// paths) can turn into a remote kill switch, so prefer escalation to [LevelError] unless the pattern
+// is strict and the termination is really the only option.
+func AddEscalationRule(pattern *regexp.Regexp, to Level) {
+	logger.AddEscalationRule(pattern, to)
+}
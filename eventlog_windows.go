@@ -0,0 +1,259 @@
+//go:build windows
+
+package log
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Functions of the Windows API used to write to the Event Log and to install sources. They are called
+// directly instead of using golang.org/x/sys/windows/svc/eventlog and golang.org/x/sys/windows/registry,
+// because the module has no dependencies and only a few functions are required.
+//
+//nolint:gochecknoglobals // loaded on demand
+var (
+	advapi32					= syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSourceW	= advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource	= advapi32.NewProc("DeregisterEventSource")
+	procReportEventW			= advapi32.NewProc("ReportEventW")
+	procRegCreateKeyExW			= advapi32.NewProc("RegCreateKeyExW")
+	procRegSetValueExW			= advapi32.NewProc("RegSetValueExW")
+	procRegDeleteKeyW			= advapi32.NewProc("RegDeleteKeyW")
+)
+
+// Types of Event Log entries
+const (
+	eventlogError		= 0x0001
+	eventlogWarning		= 0x0002
+	eventlogInformation	= 0x0004
+)
+
+const (
+	// ID of events, the message file of EventCreate.exe shows the text of such events as is
+	eventlogEventID		= 1
+	// Registry key of sources of the Application log and the message file used by installed sources
+	eventlogSourcesKey	= `SYSTEM\CurrentControlSet\Services\EventLog\Application\`
+	eventlogMessageFile	= `%SystemRoot%\System32\EventCreate.exe`
+)
+
+// ErrEventSourceExists is returned (wrapped by [FileError]) by [InstallEventSource] when the source is already installed.
+var ErrEventSourceExists = errors.New("event source is already installed")
+
+// eventLog writes messages to the Windows Event Log
+type eventLog struct {
+	handle		uintptr
+	source		string
+	minLevel	Level
+}
+
+// OpenEventLog opens the log on the Application log of the Windows Event Log, messages are written
+// as events of the source (see [InstallEventSource]). The source is used as the prefix of the log.
+// Levels of messages are mapped to types of events: warnings to Warning, errors and fatal messages to Error,
+// other levels to Information. Fields of messages (see [With]) are written after the text in the key=value
+// form. The meaning of flags is the same as for [Open], PID is added to events by the Event Log itself.
+func OpenEventLog(source string, flags int) error {
+	logger = newDefaultLogger()
+	return logger.OpenEventLog(source, flags)
+}
+
+// OpenEventLog calls [OpenEventLog] on the l object.
+func (l *Logger) OpenEventLog(source string, flags int) error {
+	l.opener = func() (io.Writer, error) {
+		return registerEventSource(source, LevelDebug)
+	}
+
+	return l.open(source, source, flags | NoPID)
+}
+
+// AddEventLog adds the Windows Event Log as an additional writer of the log (see [AddWriter]), so messages
+// of levels minLevel and above are written both to the log target (e.g. the file opened by [Open]) and to
+// the Event Log, e.g. AddEventLog(LevelWarn) to see warnings and errors of the service in the Event Viewer.
+// Events are written in the same way as by the log opened by [OpenEventLog], the prefix of the log is used
+// as the source. The Event Log is closed by [Close]. AddEventLog must be called after opening the log.
+func AddEventLog(minLevel Level) error {
+	return logger.AddEventLog(minLevel)
+}
+
+// AddEventLog calls [AddEventLog] on the l object.
+func (l *Logger) AddEventLog(minLevel Level) error {
+	el, err := registerEventSource(l.origPrefix, minLevel)
+	if err != nil {
+		return err
+	}
+
+	l.addSink(&sink{w: el, name: l.origPrefix, owned: true})
+
+	return nil
+}
+
+func registerEventSource(source string, minLevel Level) (*eventLog, error) {
+	name, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, NewFilePathError(source, "invalid name of event source: %w", err)
+	}
+
+	handle, _, err := procRegisterEventSourceW.Call(0, uintptr(unsafe.Pointer(name)))
+	if handle == 0 {
+		return nil, NewFilePathError(source, "cannot register event source: %w", err)
+	}
+
+	return &eventLog{handle: handle, source: source, minLevel: minLevel}, nil
+}
+
+func (el *eventLog) Write(p []byte) (int, error) {
+	return el.WriteLevel(LevelInfo, p)
+}
+
+func (el *eventLog) WriteLevel(level Level, p []byte) (int, error) {
+	if err := el.report(level, strings.TrimSuffix(string(p), "\n")); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (el *eventLog) writeEntry(l *Logger, e *Entry) error {
+	return el.report(e.Level, e.Message + l.formatFields(e.Fields))
+}
+
+// report writes the event with the text, messages below the minimal level are dropped
+func (el *eventLog) report(level Level, text string) error {
+	if level < el.minLevel {
+		return nil
+	}
+
+	etype := eventlogInformation
+	switch {
+	case level >= LevelError:
+		etype = eventlogError
+	case level >= LevelWarn:
+		etype = eventlogWarning
+	}
+
+	msg, err := syscall.UTF16PtrFromString(strings.ReplaceAll(text, "\x00", ""))
+	if err != nil {
+		return err
+	}
+	strs := []*uint16{msg}
+
+	ok, _, err := procReportEventW.Call(el.handle, uintptr(etype), 0, eventlogEventID, 0,
+		uintptr(len(strs)), 0, uintptr(unsafe.Pointer(&strs[0])), 0)
+	if ok == 0 {
+		return err
+	}
+
+	return nil
+}
+
+func (el *eventLog) Close() error {
+	if ok, _, err := procDeregisterEventSource.Call(el.handle); ok == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// InstallEventSource registers the source of events in the Application log, so the Event Viewer shows
+// texts of events written by [OpenEventLog] and [AddEventLog] without complaints about missing descriptions.
+// The source uses the message file of EventCreate.exe, that is available on all Windows versions.
+// Installation requires administrative privileges, so it is usually performed by the installer of
+// the service or by its "install" command rather than on each start. If the source is already
+// installed, the [FileError] wrapping [ErrEventSourceExists] is returned.
+func InstallEventSource(source string) error {
+	key, existed, err := createRegKey(eventlogSourcesKey + source)
+	if err != nil {
+		return err
+	}
+	defer syscall.RegCloseKey(key)	//nolint:errcheck // nothing to do with the error of closing
+
+	if existed {
+		return NewFilePathError(source, "cannot install event source: %w", ErrEventSourceExists)
+	}
+
+	if err = setRegString(key, "EventMessageFile", eventlogMessageFile); err == nil {
+		err = setRegDWORD(key, "TypesSupported", eventlogError | eventlogWarning | eventlogInformation)
+	}
+	if err != nil {
+		// Do not leave the half-installed source, the next attempt would report it as already installed
+		_ = deleteRegKey(eventlogSourcesKey + source)
+
+		return NewFilePathError(source, "cannot install event source: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveEventSource removes the source of events installed by [InstallEventSource].
+func RemoveEventSource(source string) error {
+	if err := deleteRegKey(eventlogSourcesKey + source); err != nil {
+		return NewFilePathError(source, "cannot remove event source: %w", err)
+	}
+
+	return nil
+}
+
+// createRegKey creates or opens the key in HKEY_LOCAL_MACHINE, existed is true if the key was not created
+func createRegKey(path string) (key syscall.Handle, existed bool, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, false, NewFilePathError(path, "invalid registry key: %w", err)
+	}
+
+	const regOpenedExistingKey = 2
+
+	var disposition uint32
+	rc, _, _ := procRegCreateKeyExW.Call(uintptr(syscall.HKEY_LOCAL_MACHINE), uintptr(unsafe.Pointer(p)), 0, 0, 0,
+		syscall.KEY_WRITE, 0, uintptr(unsafe.Pointer(&key)), uintptr(unsafe.Pointer(&disposition)))
+	if rc != 0 {
+		return 0, false, NewFilePathError(path, "cannot create registry key: %w", syscall.Errno(rc))
+	}
+
+	return key, disposition == regOpenedExistingKey, nil
+}
+
+// deleteRegKey deletes the key without subkeys from HKEY_LOCAL_MACHINE
+func deleteRegKey(path string) error {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	if rc, _, _ := procRegDeleteKeyW.Call(uintptr(syscall.HKEY_LOCAL_MACHINE), uintptr(unsafe.Pointer(p))); rc != 0 {
+		return syscall.Errno(rc)
+	}
+
+	return nil
+}
+
+func setRegString(key syscall.Handle, name, value string) error {
+	data, err := syscall.UTF16FromString(value)
+	if err != nil {
+		return err
+	}
+
+	return regSetValue(key, name, syscall.REG_EXPAND_SZ, unsafe.Pointer(&data[0]), len(data) * 2)	//nolint:gomnd // UTF-16 characters
+}
+
+func setRegDWORD(key syscall.Handle, name string, value uint32) error {
+	return regSetValue(key, name, syscall.REG_DWORD, unsafe.Pointer(&value), int(unsafe.Sizeof(value)))
+}
+
+// regSetValue sets values of registry keys, replaced by tests to check failed installations
+var regSetValue = setRegValue	//nolint:gochecknoglobals // replaced by tests
+
+func setRegValue(key syscall.Handle, name string, vtype uint32, data unsafe.Pointer, size int) error {
+	n, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	if rc, _, _ := procRegSetValueExW.Call(uintptr(key), uintptr(unsafe.Pointer(n)), 0, uintptr(vtype), uintptr(data), uintptr(size)); rc != 0 {
+		return syscall.Errno(rc)
+	}
+
+	return nil
+}
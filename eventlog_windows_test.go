@@ -0,0 +1,59 @@
+//go:build windows
+
+package log
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func TestEventLog(t *testing.T) {
+	// Writing to sources that are not installed is allowed, the Event Viewer only complains about descriptions
+	if err := OpenEventLog(stubApp, NoFlags); err != nil {
+		t.Errorf("cannot open log on the event log: %v", err)
+		t.FailNow()
+	}
+
+	With("user", "root").Warn(stubLogFormat, 0, `WARNING`)
+	Err(stubLogFormat, 1, `ERROR`)
+	Raw(LevelInfo, []byte(`raw message`))
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the event log: %v", err)
+	}
+}
+
+func TestInstallEventSource(t *testing.T) {
+	source := stubApp + "-" + strconv.Itoa(os.Getpid())
+
+	if err := InstallEventSource(source); errors.Is(err, os.ErrPermission) {
+		t.Skip("installation of event sources requires administrative privileges")
+	} else if err != nil {
+		t.Errorf("cannot install event source %q: %v", source, err)
+		t.FailNow()
+	}
+
+	if err := InstallEventSource(source); !errors.Is(err, ErrEventSourceExists) {
+		t.Errorf("want ErrEventSourceExists, got %v", err)
+	}
+	if err := RemoveEventSource(source); err != nil {
+		t.Errorf("cannot remove event source %q: %v", source, err)
+	}
+
+	// The failed installation must not leave the registry key
+	defer func(orig func(syscall.Handle, string, uint32, unsafe.Pointer, int) error) { regSetValue = orig }(regSetValue)
+	regSetValue = func(syscall.Handle, string, uint32, unsafe.Pointer, int) error {
+		return syscall.ERROR_ACCESS_DENIED
+	}
+
+	if err := InstallEventSource(source); !errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+		t.Errorf("want ERROR_ACCESS_DENIED, got %v", err)
+	}
+	if err := RemoveEventSource(source); !errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		t.Errorf("want the key of event source %q removed after the failed installation, got %v", source, err)
+	}
+}
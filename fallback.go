@@ -0,0 +1,170 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// SetFallbackFile calls [SetFallbackFile] on the l object.
+func (l *Logger) SetFallbackFile(path string) {
+	l.fallbackName = path
+}
+
+// SetFallback calls [SetFallback] on the l object.
+func (l *Logger) SetFallback(fb *Logger) {
+	if fb != nil && fb.logCore == l.logCore {
+		// Messages cannot be rerouted to the same log target
+		fb = nil
+	}
+
+	l.fallbackLog = fb
+}
+
+// ReplayFallback calls [ReplayFallback] on the l object.
+func (l *Logger) ReplayFallback() error {
+	var err error
+
+	// Replay messages from the writer goroutine to keep the order of messages
+	l.execute(func() {
+		err = l.replayFallback()
+	})
+
+	return err
+}
+
+// failover passes the line that cannot be written to the log target to the fallback logger,
+// if it is set and opened, otherwise the line is kept in the fallback file
+func (l *Logger) failover(level Level, line []byte) {
+	if l.fallbackLog == nil || l.fallbackLog.closed {
+		l.writeFallback(line)
+		return
+	}
+
+	if !l.failedOver {
+		// Report switching only, subsequent messages are rerouted silently
+		l.failedOver = true
+		l.handleError(NewFilePathError(l.logName, "%w", ErrFailover))
+	}
+
+	l.fallbackLog.Raw(level, line)
+}
+
+// writeFailedOver writes the line to the fallback logger if the log target has failed before,
+// it returns false if the line has to be written to the log target
+func (l *Logger) writeFailedOver(level Level, line []byte) bool {
+	if !l.failedOver || l.fallbackLog == nil || l.fallbackLog.closed {
+		return false
+	}
+
+	l.fallbackLog.Raw(level, line)
+
+	return true
+}
+
+func (l *Logger) writeFallback(line []byte) {
+	if l.fallbackName == "" {
+		// Fallback is not used, message is lost
+		return
+	}
+
+	// Is fallback file not opened yet or its name was changed?
+	if l.fallbackFd == nil || l.fallbackOpened != l.fallbackName {
+		if err := l.closeFallback(); err != nil {
+			l.handleError(err)
+		}
+
+		fd, err := l.fileSystem().OpenFile(l.fallbackName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, defaultPermMode)
+		if err != nil {
+			l.handleError(NewFilePathError(l.fallbackName, "cannot open fallback file: %w", err))
+			return
+		}
+
+		l.fallbackFd, l.fallbackOpened = fd, l.fallbackName
+	}
+
+	if _, err := l.fallbackFd.Write(line); err != nil {
+		l.handleError(NewFilePathError(l.fallbackName, "cannot write message to fallback file: %w", err))
+	}
+}
+
+func (l *Logger) closeFallback() error {
+	if l.fallbackFd == nil {
+		// Nothing to close
+		return nil
+	}
+
+	fd := l.fallbackFd
+	l.fallbackFd = nil
+
+	if err := fd.Close(); err != nil {
+		return NewFilePathError(l.fallbackOpened, "cannot close fallback file: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Logger) replayFallback() error {
+	if l.fallbackName == "" {
+		// Fallback is not used, nothing to replay
+		return nil
+	}
+
+	// Close the fallback file before reading it
+	if err := l.closeFallback(); err != nil {
+		return err
+	}
+
+	data, err := l.fileSystem().ReadFile(l.fallbackName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// No messages were written to the fallback file
+			return nil
+		}
+
+		return NewFilePathError(l.fallbackName, "cannot read fallback file: %w", err)
+	}
+
+	for len(data) != 0 {
+		// Get the next line including the newline
+		n := bytes.IndexByte(data, '\n') + 1
+		if n == 0 {
+			n = len(data)
+		}
+
+		// Levels of messages are not kept in the fallback file
+		if err = l.writeTarget(LevelInfo, data[:n]); err != nil {
+			// Keep messages that were not replayed in the fallback file
+			if err := l.rewriteFallback(data); err != nil {
+				l.handleError(NewFilePathError(l.fallbackName, "cannot update fallback file: %w", err))
+			}
+
+			return NewFilePathError(l.logName, "cannot replay messages from fallback file: %w", err)
+		}
+
+		data = data[n:]
+	}
+
+	// All messages were replayed
+	if err = l.fileSystem().Remove(l.fallbackName); err != nil {
+		return NewFilePathError(l.fallbackName, "cannot remove replayed fallback file: %w", err)
+	}
+
+	return nil
+}
+
+// rewriteFallback replaces the content of the fallback file by data, like os.WriteFile does
+func (l *Logger) rewriteFallback(data []byte) error {
+	fd, err := l.fileSystem().OpenFile(l.fallbackName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaultPermMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = fd.Write(data)
+	if cErr := fd.Close(); err == nil {
+		err = cErr
+	}
+
+	return err
+}
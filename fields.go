@@ -0,0 +1,301 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeFormat is the default layout used to write time.Time values of fields, see [SetTimeFormat].
+const DefaultTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Key used for arguments of With that cannot be used as keys
+const badKey = "!BADKEY"
+
+// ComponentKey is the key of the field with the name of the component, see [Component].
+const ComponentKey = "component"
+
+// TruncatedKey is the key of the field that marks loggers with dropped fields, see [SetMaxFields].
+const TruncatedKey = "fields_truncated"
+
+// Field is a structured key-value pair, added to log messages by [Logger.With].
+// Fields are written after the message text in the key=value form.
+type Field struct {
+	Key		string
+	Value	any
+}
+
+// DurationFormat defines how time.Duration values of fields are written, see [SetDurationFormat].
+type DurationFormat uint8
+
+// Supported formats of time.Duration values
+const (
+	// Human-readable string such as 123ms or 1m30s (default)
+	DurationString	=	DurationFormat(iota)
+	// Integer number of nanoseconds
+	DurationNanos
+)
+
+// Any returns a field with an arbitrary value.
+func Any(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Dur returns a field with the d duration value, written according to the format set by [SetDurationFormat].
+func Dur(key string, d time.Duration) Field {
+	return Field{Key: key, Value: d}
+}
+
+// Time returns a field with the t time value, written according to the layout set by [SetTimeFormat].
+func Time(key string, t time.Time) Field {
+	return Field{Key: key, Value: t}
+}
+
+// Int returns a field with the integer value. Values from 0 to 1023 (e.g. HTTP status codes)
+// are taken from the cache of preallocated values, so making such fields does not allocate.
+func Int(key string, v int) Field {
+	if v >= 0 && v < len(intValues) {
+		return Field{Key: key, Value: intValues[v]}
+	}
+
+	return Field{Key: key, Value: v}
+}
+
+// Str returns a field with the string value. Values registered by [InternStrings] are taken from
+// the table of preallocated values, so making such fields does not allocate.
+func Str(key, s string) Field {
+	if table, _ := internedValues.Load().(map[string]any); table != nil {
+		if v, ok := table[s]; ok {
+			return Field{Key: key, Value: v}
+		}
+	}
+
+	return Field{Key: key, Value: s}
+}
+
+// Preallocated values of fields made by Int
+var intValues = func() (values [1024]any) {
+	for i := range values {
+		values[i] = i
+	}
+
+	return values
+}()
+
+// Table of preallocated values of fields made by Str, the map is replaced on each update
+var (
+	internedValues	atomic.Value
+	internMu		sync.Mutex
+)
+
+// InternStrings registers commonly used values of string fields (e.g. HTTP methods), values of fields made
+// by [Str] are looked up in the table of registered values to avoid allocations. It is intended to be
+// called during initialization, each call copies the table.
+func InternStrings(values ...string) {
+	internMu.Lock()
+	defer internMu.Unlock()
+
+	old, _ := internedValues.Load().(map[string]any)
+	table := make(map[string]any, len(old) + len(values))
+	for k, v := range old {
+		table[k] = v
+	}
+	for _, v := range values {
+		table[v] = v
+	}
+
+	internedValues.Store(table)
+}
+
+// WithFields calls [WithFields] on the l object.
+func (l *Logger) WithFields(fields ...Field) *Logger {
+	all := make([]Field, len(l.fields), len(l.fields) + len(fields))
+	copy(all, l.fields)
+
+	return &Logger{logCore: l.logCore, fields: l.truncateFields(append(all, fields...)), component: l.component}
+}
+
+// With calls [With] on the l object.
+func (l *Logger) With(args ...any) *Logger {
+	fields := make([]Field, len(l.fields), len(l.fields) + len(args))
+	copy(fields, l.fields)
+
+	for len(args) != 0 {
+		switch arg := args[0].(type) {
+		case Field:
+			fields = append(fields, arg)
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				// Key without value
+				fields = append(fields, Field{Key: badKey, Value: arg})
+				args = nil
+			} else {
+				fields = append(fields, Field{Key: arg, Value: args[1]})
+				args = args[2:]
+			}
+		default:
+			fields = append(fields, Field{Key: badKey, Value: arg})
+			args = args[1:]
+		}
+	}
+
+	return &Logger{logCore: l.logCore, fields: l.truncateFields(fields), component: l.component}
+}
+
+// truncateFields drops the oldest fields exceeding the limit set by SetMaxFields
+func (l *Logger) truncateFields(fields []Field) []Field {
+	limit := l.maxFields
+	if limit <= 0 {
+		// No limit
+		return fields
+	}
+
+	truncated := len(fields) != 0 && fields[0].Key == TruncatedKey
+	if truncated {
+		// The marker does not count toward the limit
+		fields = fields[1:]
+	}
+
+	if len(fields) <= limit && !truncated {
+		return fields
+	}
+
+	if len(fields) > limit {
+		fields = fields[len(fields) - limit:]
+	}
+
+	// The marker replaces dropped fields
+	return append([]Field{{Key: TruncatedKey, Value: true}}, fields...)
+}
+
+// SetMaxFields calls [SetMaxFields] on the l object.
+func (l *Logger) SetMaxFields(n int) {
+	l.maxFields = n
+}
+
+// SetFieldOrder calls [SetFieldOrder] on the l object.
+func (l *Logger) SetFieldOrder(keys []string) {
+	// Keys are copied to allow the caller to reuse the slice
+	order := append([]string(nil), keys...)
+	ranks := fieldRanks(order)
+
+	l.execute(func() {
+		l.fieldOrder, l.fieldRanks = order, ranks
+	})
+}
+
+// fieldRanks maps keys to their positions in the order, nil means the default order
+func fieldRanks(keys []string) map[string]int {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ranks := make(map[string]int, len(keys))
+	for i, key := range keys {
+		// The first position of the repeated key is used
+		if _, ok := ranks[key]; !ok {
+			ranks[key] = i
+		}
+	}
+
+	return ranks
+}
+
+// fieldRank returns the position of the key in the order set by SetFieldOrder, unlisted keys follow listed ones
+func (l *Logger) fieldRank(key string) int {
+	if rank, ok := l.fieldRanks[key]; ok {
+		return rank
+	}
+
+	return len(l.fieldOrder)
+}
+
+// orderFields returns fields sorted in the order set by SetFieldOrder, fields are not modified
+func (l *Logger) orderFields(fields []Field) []Field {
+	if l.fieldRanks == nil || len(fields) < 2 {
+		return fields
+	}
+
+	sorted := append([]Field(nil), fields...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return l.fieldRank(sorted[i].Key) < l.fieldRank(sorted[j].Key)
+	})
+
+	return sorted
+}
+
+// Component calls [Component] on the l object.
+func (l *Logger) Component(name string) *Logger {
+	return &Logger{logCore: l.logCore, fields: l.fields, component: name}
+}
+
+// componentTag returns the text representation of the component name
+func componentTag(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return "[" + name + "] "
+}
+
+// SetDurationFormat calls [SetDurationFormat] on the l object.
+func (l *Logger) SetDurationFormat(df DurationFormat) {
+	// Formats are used by the writer goroutine
+	l.execute(func() {
+		l.durFormat = df
+	})
+}
+
+// SetTimeFormat calls [SetTimeFormat] on the l object.
+func (l *Logger) SetTimeFormat(layout string) {
+	l.execute(func() {
+		l.timeFormat = layout
+	})
+}
+
+func (l *Logger) formatFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	for _, f := range l.orderFields(fields) {
+		sb.WriteByte(' ')
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		sb.WriteString(quoteValue(l.fieldValue(f.Value)))
+	}
+
+	return sb.String()
+}
+
+func (l *Logger) fieldValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Duration:
+		if l.durFormat == DurationNanos {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return v.String()
+	case time.Time:
+		return v.Format(l.timeFormat)
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func quoteValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " =\"\t\r\n") {
+		return strconv.Quote(v)
+	}
+
+	return v
+}
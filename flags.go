@@ -0,0 +1,84 @@
+package log
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// flagName is the name of a single flag used by ParseFlags and FlagNames
+type flagName struct {
+	name	string
+	flag	int
+}
+
+// Names of single flags in the order used by FlagNames
+//
+//nolint:gochecknoglobals // constant table
+var flagNames = []flagName{
+	{"date", log.Ldate},
+	{"time", log.Ltime},
+	{"microseconds", log.Lmicroseconds},
+	{"longfile", log.Llongfile},
+	{"shortfile", log.Lshortfile},
+	{"utc", log.LUTC},
+	{"msgprefix", log.Lmsgprefix},
+	{"nopid", NoPID},
+}
+
+// Names of flags combinations, accepted only by ParseFlags
+//
+//nolint:gochecknoglobals // constant table
+var flagAliases = []flagName{
+	{"noflags", NoFlags},
+	{"stdflags", log.LstdFlags},
+}
+
+// ParseFlags returns the bit combination of flags specified by names, that can be passed to [Open]
+// and [SetFlags], e.g. ParseFlags([]string{"date", "time", "utc", "nopid"}). Names are case-insensitive:
+// "nopid" and "noflags" for NoPID and NoFlags; "date", "time", "microseconds", "longfile", "shortfile",
+// "utc", "msgprefix" and "stdflags" for the corresponding flags of the standard [log] package.
+// Unknown names cause an error, that lists valid names.
+func ParseFlags(names []string) (int, error) {
+	flags := NoFlags
+
+NextName:
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		for _, fn := range append(flagNames, flagAliases...) {
+			if fn.name == name {
+				flags |= fn.flag
+				continue NextName
+			}
+		}
+
+		return NoFlags, fmt.Errorf("unknown log flag %q, valid flags: %s", name, strings.Join(validFlagNames(), ", "))
+	}
+
+	return flags, nil
+}
+
+// FlagNames returns names of flags set in flags, in the form accepted by [ParseFlags],
+// so ParseFlags(FlagNames(flags)) returns the same flags. Bits that do not correspond
+// to any known flag are ignored. Nil is returned for NoFlags.
+func FlagNames(flags int) []string {
+	var names []string
+
+	for _, fn := range flagNames {
+		if flags & fn.flag != 0 {
+			names = append(names, fn.name)
+		}
+	}
+
+	return names
+}
+
+func validFlagNames() []string {
+	names := make([]string, 0, len(flagNames) + len(flagAliases))
+	for _, fn := range append(flagNames, flagAliases...) {
+		names = append(names, fn.name)
+	}
+
+	return names
+}
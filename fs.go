@@ -0,0 +1,130 @@
+package log
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sync/atomic"
+)
+
+// File is the file opened by [FS], the log writes messages to it and closes it by [Close].
+// Stat is used to check whether the file is new (see [SetFileHeader]) and whether it is a regular file.
+// If the file implements the Sync method (like *os.File), it is used to flush data (see [SetSyncOnReopen]).
+type File interface {
+	io.Writer
+	io.Closer
+	Stat() (fs.FileInfo, error)
+}
+
+// TempFile is the temporary file created by [FS], it is used to spill messages (see [SpillToDisk]):
+// messages are written to the file, then the file is read from the beginning and truncated.
+type TempFile interface {
+	File
+	io.Reader
+	io.Seeker
+	Truncate(size int64) error
+	Name() string
+}
+
+// FS is the file system used to open, rotate and remove files of the log, see [SetFS].
+// The meaning of arguments and results of methods is the same as for functions of the [os] package
+// with the same names (Lstat is used only to check whether the file exists).
+type FS interface {
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	ReadFile(name string) ([]byte, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	Lstat(name string) (fs.FileInfo, error)
+	CreateTemp(dir, pattern string) (TempFile, error)
+}
+
+// OSFS is the [FS] of the operating system, it is used by default.
+type OSFS struct{}
+
+// OpenFile calls [os.OpenFile].
+func (OSFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	fd, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		// Do not return the typed nil as the interface
+		return nil, err
+	}
+
+	return fd, nil
+}
+
+// ReadFile calls [os.ReadFile].
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// Rename calls [os.Rename].
+func (OSFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Remove calls [os.Remove].
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Lstat calls [os.Lstat].
+func (OSFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// CreateTemp calls [os.CreateTemp].
+func (OSFS) CreateTemp(dir, pattern string) (TempFile, error) {
+	fd, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		// Do not return the typed nil as the interface
+		return nil, err
+	}
+
+	return fd, nil
+}
+
+// fsHolder wraps FS values to store different implementations in the same atomic.Value
+type fsHolder struct {
+	fsys	FS
+}
+
+// File system of loggers created by subsequent calls of Open functions, see SetFS
+var defaultFS atomic.Value
+
+// SetFS calls [SetFS] on the l object.
+//
+// NOTE: unlike the package function, it sets the file system of the l object only,
+// it has to be called before opening the log or before calling [Logger.Reopen].
+func (l *Logger) SetFS(fsys FS) {
+	l.fsys = fsys
+}
+
+// fileSystem returns the file system used to open files of the logger
+func (l *Logger) fileSystem() FS {
+	if l.fsys == nil {
+		return OSFS{}
+	}
+
+	return l.fsys
+}
+
+// loadDefaultFS returns the file system set by SetFS, nil means OSFS
+func loadDefaultFS() FS {
+	if h, ok := defaultFS.Load().(fsHolder); ok {
+		return h.fsys
+	}
+
+	return nil
+}
+
+// SetFS sets the file system used by loggers created by subsequent calls of Open functions and [NewLogger],
+// e.g. to keep logs of tests in memory. All files of the log are opened, renamed and removed through the file
+// system: the log file (including [Reopen] and rotation, see [SetRotationAge]), the access log (see
+// [SetAccessLogOutput]), leveled files (see [SetLeveledFiles]), the fallback file (see [SetFallbackFile])
+// and the spool file (see [SpillToDisk]). Only [Validate] and checks whether targets are terminals or
+// the null device use the [os] package. Like [SetDefaultFlags], it can be called before [Open]. The nil
+// value restores [OSFS], as [Reset] does. To use the file system with the single log, use the FS field
+// of [Options] or [Logger.SetFS].
+func SetFS(fsys FS) {
+	defaultFS.Store(fsHolder{fsys})
+}
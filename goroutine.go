@@ -0,0 +1,47 @@
+package log
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// GoroutineKey is the key of the field with the goroutine ID, see [SetGoroutineID].
+const GoroutineKey = "gid"
+
+// Size of the buffer enough for the first line of the goroutine stack
+const goroutineHdrSize = 64
+
+// SetGoroutineID calls [SetGoroutineID] on the l object.
+func (l *Logger) SetGoroutineID(v bool) {
+	l.goroutineID = v
+}
+
+// goroutineID returns the ID of the calling goroutine parsed from the header of its stack trace
+// looks like "goroutine 123 [running]:", it returns 0 if the ID cannot be parsed
+func goroutineID() uint64 {
+	var buf [goroutineHdrSize]byte
+	hdr := buf[:runtime.Stack(buf[:], false)]
+
+	hdr = bytes.TrimPrefix(hdr, []byte("goroutine "))
+	if i := bytes.IndexByte(hdr, ' '); i > 0 {
+		hdr = hdr[:i]
+	}
+
+	id, err := strconv.ParseUint(string(hdr), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+// SetGoroutineID enables (v == true) or disables (default) adding of the ID of the goroutine that
+// called the logging function to messages as the field with the [GoroutineKey] key, e.g.
+// "app: message gid=42". It is intended for debugging of concurrency issues only: the Go runtime
+// does not provide goroutine IDs officially, so the ID is parsed from the stack trace header of
+// the calling goroutine for each message, that is relatively expensive. IDs are reused after
+// goroutines exit and must not be used for anything except correlating log lines.
+func SetGoroutineID(v bool) {
+	logger.SetGoroutineID(v)
+}
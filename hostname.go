@@ -0,0 +1,63 @@
+package log
+
+import (
+	"os"
+	"sync"
+)
+
+//nolint:gochecknoglobals // The hostname is resolved once per process
+var (
+	hostOnce	sync.Once
+	hostName	string
+)
+
+// SetHostname calls [SetHostname] on the l object.
+func (l *Logger) SetHostname(v bool) {
+	// The hostname is used by the writer goroutine in the JSON format
+	l.execute(func() {
+		l.withHost = v
+	})
+}
+
+// SetHostnameValue calls [SetHostnameValue] on the l object.
+func (l *Logger) SetHostnameValue(name string) {
+	l.execute(func() {
+		l.hostValue = name
+	})
+}
+
+// hostname returns the hostname written to the log, or an empty string if it is disabled
+func (l *Logger) hostname() string {
+	if !l.withHost {
+		return ""
+	}
+
+	if l.hostValue != "" {
+		return l.hostValue
+	}
+
+	hostOnce.Do(func() {
+		// The hostname is not written if it cannot be resolved
+		hostName, _ = os.Hostname()
+	})
+
+	return hostName
+}
+
+// SetHostname enables (v == true) or disables (default) writing of the hostname before the prefix
+// of each line of the text log, e.g. "myhost app[1234]: message", to correlate aggregated logs
+// of many machines. The hostname is resolved by os.Hostname once and cached, use [SetHostnameValue]
+// to write another name, e.g. in containers where os.Hostname returns a meaningless ID.
+// Targets that add the hostname themselves (e.g. journald) are not affected.
+// Like the prefix separator, the setting is applied by the next call of [SetFlags], [SetPrefix]
+// or [Reopen], use [Options].Hostname to set it when opening the log. In the JSON format
+// (see [SetOutputFormat]) the hostname is written as the "host" key immediately.
+func SetHostname(v bool) {
+	logger.SetHostname(v)
+}
+
+// SetHostnameValue sets the name written by [SetHostname] instead of the name returned by os.Hostname,
+// an empty name restores the default.
+func SetHostnameValue(name string) {
+	logger.SetHostnameValue(name)
+}
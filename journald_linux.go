@@ -0,0 +1,165 @@
+//go:build linux
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"strings"
+)
+
+//nolint:gochecknoglobals // Path to the socket of the journald native protocol, changed only by tests
+var journaldSocket = "/run/systemd/journal/socket"
+
+// ErrNoJournald is returned (wrapped by [FileError]) when the journald socket does not exist.
+var ErrNoJournald = errors.New("journald socket not found, systemd journal is not available")
+
+// journald writes messages to the systemd journal using the native protocol
+type journald struct {
+	conn		net.Conn
+	identifier	string
+	buf			bytes.Buffer
+}
+
+// OpenJournald opens the log on the systemd journal, messages are written using the journald native
+// protocol. The prefix is used as the SYSLOG_IDENTIFIER field, levels of messages are mapped to
+// the PRIORITY field. Fields of messages (see [With]) are written as journal fields: names are
+// uppercased, characters that are not allowed are replaced by underscores and leading underscores
+// are removed, because such names are reserved for trusted fields set by journald itself.
+// If the journald socket does not exist, the [FileError] wrapping [ErrNoJournald] is returned.
+func OpenJournald(prefix string) error {
+	logger = newDefaultLogger()
+	return logger.OpenJournald(prefix)
+}
+
+// OpenJournald calls [OpenJournald] on the l object.
+func (l *Logger) OpenJournald(prefix string) error {
+	l.opener = func() (io.Writer, error) {
+		return dialJournald(prefix)
+	}
+
+	// PID is added to journal entries by journald
+	return l.open(journaldSocket, prefix, NoPID)
+}
+
+// AddJournald adds the systemd journal as an additional writer of the log (see [AddWriter]), so messages
+// are written both to the log target (e.g. the file opened by [Open]) and to the journal. The journal
+// receives messages in the same way as the log opened by [OpenJournald]: with the PRIORITY field and
+// fields of messages as journal fields, while text targets get formatted lines. The prefix of the log
+// is used as the SYSLOG_IDENTIFIER field. The connection to journald is closed by [Close].
+// AddJournald must be called after opening the log.
+func AddJournald() error {
+	return logger.AddJournald()
+}
+
+// AddJournald calls [AddJournald] on the l object.
+func (l *Logger) AddJournald() error {
+	j, err := dialJournald(l.origPrefix)
+	if err != nil {
+		return err
+	}
+
+	l.addSink(&sink{w: j, name: journaldSocket, owned: true})
+
+	return nil
+}
+
+func dialJournald(identifier string) (*journald, error) {
+	conn, err := net.Dial("unixgram", journaldSocket)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = ErrNoJournald
+		}
+		return nil, NewFilePathError(journaldSocket, "cannot connect to journald: %w", err)
+	}
+
+	return &journald{conn: conn, identifier: identifier}, nil
+}
+
+func (j *journald) Write(p []byte) (int, error) {
+	return j.WriteLevel(LevelInfo, p)
+}
+
+func (j *journald) WriteLevel(level Level, p []byte) (int, error) {
+	j.buf.Reset()
+	j.appendHeader(level)
+	j.appendField("MESSAGE", string(bytes.TrimSuffix(p, []byte{'\n'})))
+
+	if _, err := j.conn.Write(j.buf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (j *journald) Close() error {
+	return j.conn.Close()
+}
+
+func (j *journald) writeEntry(l *Logger, e *Entry) error {
+	j.buf.Reset()
+	j.appendHeader(e.Level)
+	j.appendField("MESSAGE", e.Message)
+
+	for _, f := range e.Fields {
+		if name := journaldFieldName(f.Key); name != "" {
+			j.appendField(name, l.fieldValue(f.Value))
+		}
+	}
+
+	_, err := j.conn.Write(j.buf.Bytes())
+
+	return err
+}
+
+func (j *journald) appendHeader(level Level) {
+	j.appendField("PRIORITY", string('0' + byte(level.syslogSeverity())))
+	if j.identifier != "" {
+		j.appendField("SYSLOG_IDENTIFIER", j.identifier)
+	}
+}
+
+func (j *journald) appendField(name, value string) {
+	if !strings.Contains(value, "\n") {
+		// Simple FIELD=value form
+		j.buf.WriteString(name)
+		j.buf.WriteByte('=')
+		j.buf.WriteString(value)
+		j.buf.WriteByte('\n')
+
+		return
+	}
+
+	// Binary form for multi-line values: name, newline, 64-bit little-endian size, value, newline
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+
+	j.buf.WriteString(name)
+	j.buf.WriteByte('\n')
+	j.buf.Write(size[:])
+	j.buf.WriteString(value)
+	j.buf.WriteByte('\n')
+}
+
+// journaldFieldName converts the field key to the valid journal field name
+func journaldFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			name[i] = '_'
+		}
+	}
+
+	// Names starting with underscores are reserved for trusted fields
+	key = strings.TrimLeft(string(name), "_")
+	if key != "" && key[0] >= '0' && key[0] <= '9' {
+		// Names cannot start with digits
+		key = "F_" + key
+	}
+
+	return key
+}
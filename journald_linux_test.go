@@ -0,0 +1,127 @@
+//go:build linux
+
+package log
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournald(t *testing.T) {
+	defer func(orig string) { journaldSocket = orig }(journaldSocket)
+	journaldSocket = filepath.Join(tempDir(), "journal.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		t.Errorf("cannot listen on datagram socket %q: %v", journaldSocket, err)
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	if err = OpenJournald(stubApp); err != nil {
+		t.Errorf("cannot open log on journald: %v", err)
+		t.FailNow()
+	}
+
+	With("user id", "John Doe", "_trusted", 1, "1st", true, "trace", "a\nb").Warn(stubLogFormat, 0, `WARNING`)
+	Raw(LevelError, []byte(`raw message`))
+
+	if err = Close(); err != nil {
+		t.Errorf("cannot close log on journald: %v", err)
+	}
+
+	want := []string{
+		"PRIORITY=4\n" +
+			"SYSLOG_IDENTIFIER=" + stubApp + "\n" +
+			"MESSAGE=Test #0 - WARNING log message\n" +
+			"USER_ID=John Doe\n" +
+			"TRUSTED=1\n" +
+			"F_1ST=true\n" +
+			"TRACE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+		"PRIORITY=3\n" +
+			"SYSLOG_IDENTIFIER=" + stubApp + "\n" +
+			"MESSAGE=raw message\n",
+	}
+
+	buf := make([]byte, 4096)	//nolint:gomnd // enough to read a test message
+	for i, w := range want {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Errorf("[%d] cannot read message from socket %q: %v", i, journaldSocket, err)
+			t.FailNow()
+		}
+
+		if got := string(buf[:n]); got != w {
+			t.Errorf("[%d] want %q, got %q", i, w, got)
+		}
+	}
+}
+
+func TestAddJournald(t *testing.T) {
+	defer func(orig string) { journaldSocket = orig }(journaldSocket)
+	journaldSocket = filepath.Join(tempDir(), "journal.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		t.Errorf("cannot listen on datagram socket %q: %v", journaldSocket, err)
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	buf := &strings.Builder{}
+	if err = OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	if err = AddJournald(); err != nil {
+		t.Errorf("cannot add journald: %v", err)
+		t.FailNow()
+	}
+
+	With("user", "root").Warn(stubLogFormat, 0, `WARNING`)
+
+	if err = Close(); err != nil {
+		t.Errorf("cannot close log: %v", err)
+	}
+
+	if want := stubApp + `: <WRN> Test #0 - WARNING log message user=root` + "\n"; buf.String() != want {
+		t.Errorf("want %q in the text log, got %q", want, buf.String())
+	}
+
+	want := "PRIORITY=4\n" +
+		"SYSLOG_IDENTIFIER=" + stubApp + "\n" +
+		"MESSAGE=Test #0 - WARNING log message\n" +
+		"USER=root\n"
+
+	msg := make([]byte, 4096)	//nolint:gomnd // enough to read a test message
+	n, err := conn.Read(msg)
+	if err != nil {
+		t.Errorf("cannot read message from socket %q: %v", journaldSocket, err)
+		t.FailNow()
+	}
+	if got := string(msg[:n]); got != want {
+		t.Errorf("want %q in the journal, got %q", want, got)
+	}
+}
+
+func TestJournaldNotExist(t *testing.T) {
+	defer func(orig string) { journaldSocket = orig }(journaldSocket)
+	journaldSocket = filepath.Join(tempDir(), "no-such-journal.sock")
+
+	err := OpenJournald(stubApp)
+	if err == nil {
+		t.Errorf("anormal situation - log opened on non-existing journald socket %q", journaldSocket)
+		if err = Close(); err != nil {
+			panic("Cannot close log opened on non-existing journald socket: " + err.Error())
+		}
+		return
+	}
+
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) || !errors.Is(err, ErrNoJournald) {
+		t.Errorf("failed OpenJournald() error is %v (%T), want FileError wrapping %v", err, err, ErrNoJournald)
+	}
+}
@@ -0,0 +1,231 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OutputFormat defines the format of lines written to the log, see [SetOutputFormat].
+type OutputFormat int
+
+// Supported output formats
+const (
+	// Text lines: prefix, level tag, message and fields in the key=value form (default)
+	FormatText	=	OutputFormat(iota)
+	// JSON objects, one per line (NDJSON)
+	FormatJSON
+)
+
+// FieldTypes defines how values of fields are written in the JSON format, see [SetFieldTypes].
+type FieldTypes int
+
+// Supported field types modes
+const (
+	// Numbers and booleans are written as JSON numbers and booleans (default)
+	FieldsNative	=	FieldTypes(iota)
+	// All values are written as JSON strings, formatted like in the text log
+	FieldsAllStrings
+)
+
+// Keys of attributes of messages in the JSON format
+const (
+	jsonSchemaKey	=	"schema"
+	jsonTimeKey		=	"time"
+	jsonLevelKey	=	"level"
+	jsonAppKey		=	"app"
+	jsonHostKey		=	"host"
+	jsonPIDKey		=	"pid"
+	jsonVersionKey	=	"version"
+	jsonMsgKey		=	"msg"
+)
+
+// SetOutputFormat calls [SetOutputFormat] on the l object.
+func (l *Logger) SetOutputFormat(f OutputFormat) {
+	// The format is used by the writer goroutine
+	l.execute(func() {
+		l.outFormat = f
+	})
+}
+
+// SetFieldTypes calls [SetFieldTypes] on the l object.
+func (l *Logger) SetFieldTypes(ft FieldTypes) {
+	l.execute(func() {
+		l.fieldTypes = ft
+	})
+}
+
+// render formats the line of the message in the output format, full is the text of the text format
+// that includes the level tag and fields
+func (l *Logger) render(msg *logMsg, text, full string) []byte {
+	return l.renderFormat(l.outFormat, msg, text, full)
+}
+
+// renderFormat works like render, but formats the line in the format f
+func (l *Logger) renderFormat(f OutputFormat, msg *logMsg, text, full string) []byte {
+	if f == FormatJSON {
+		return l.jsonLine(newEntry(msg, text))
+	}
+
+	return l.formatLine(msg, full)
+}
+
+// Format implements the [Formatter] interface, so output formats can be used with [AddFormattedWriter].
+// In this case lines are formatted with settings of the logger, the same way as lines of the log target.
+// Called directly, Format formats the entry with default settings and without the prefix.
+func (f OutputFormat) Format(e *Entry) []byte {
+	l := NewLogger()
+	if f == FormatJSON {
+		return l.jsonLine(e)
+	}
+
+	return []byte(e.Time.Format(l.timeFormat) + " " + l.levelTag(e.Level) + e.Message + l.formatFields(e.Fields) + "\n")
+}
+
+// jsonAttr is the key and the value of the JSON object, attributes of messages (time, level, etc.)
+// are written regardless of the field types mode
+type jsonAttr struct {
+	key		string
+	value	any
+	attr	bool
+}
+
+// jsonLine formats the entry as the JSON object terminated by the newline
+func (l *Logger) jsonLine(e *Entry) []byte {
+	// Attributes of the message followed by fields, in the default order
+	attrs := l.jsonAttrs[:0]
+	if l.schemaVer != "" {
+		attrs = append(attrs, jsonAttr{key: jsonSchemaKey, value: l.schemaVer, attr: true})
+	}
+	attrs = append(attrs,
+		jsonAttr{key: jsonTimeKey, value: e.Time.Format(l.timeFormat), attr: true},
+		jsonAttr{key: jsonLevelKey, value: e.Level.String(), attr: true})
+	if l.origPrefix != "" {
+		attrs = append(attrs, jsonAttr{key: jsonAppKey, value: l.origPrefix, attr: true})
+	}
+	if host := l.hostname(); host != "" {
+		attrs = append(attrs, jsonAttr{key: jsonHostKey, value: host, attr: true})
+	}
+	if l.logFlags & NoPID == 0 {
+		attrs = append(attrs, jsonAttr{key: jsonPIDKey, value: os.Getpid(), attr: true})
+	}
+	if l.version != "" {
+		attrs = append(attrs, jsonAttr{key: jsonVersionKey, value: l.version, attr: true})
+	}
+	attrs = append(attrs, jsonAttr{key: jsonMsgKey, value: e.Message, attr: true})
+	for _, f := range e.Fields {
+		attrs = append(attrs, jsonAttr{key: f.Key, value: f.Value})
+	}
+	l.jsonAttrs = attrs
+
+	if l.fieldRanks != nil {
+		sort.SliceStable(attrs, func(i, j int) bool {
+			return l.fieldRank(attrs[i].key) < l.fieldRank(attrs[j].key)
+		})
+	}
+
+	// Writing to the buffer cannot fail
+	l.line.Reset()
+
+	l.line.WriteByte('{')
+	for i, a := range attrs {
+		l.jsonKey(a.key, i == 0)
+		switch {
+		case !a.attr:
+			l.jsonValue(a.value)
+		case a.key == jsonPIDKey:
+			l.line.WriteString(strconv.Itoa(a.value.(int)))
+		default:
+			l.jsonString(a.value.(string))
+		}
+	}
+	l.line.WriteString("}\n")
+
+	// Do not keep values of the message
+	for i := range attrs {
+		attrs[i].value = nil
+	}
+
+	return l.line.Bytes()
+}
+
+func (l *Logger) jsonKey(key string, first bool) {
+	if !first {
+		l.line.WriteByte(',')
+	}
+	l.jsonString(key)
+	l.line.WriteByte(':')
+}
+
+func (l *Logger) jsonString(s string) {
+	// Encoding of strings cannot fail
+	data, _ := json.Marshal(s)
+	l.line.Write(data)
+}
+
+// jsonValue writes the value of the field according to the field types mode
+func (l *Logger) jsonValue(v any) {
+	if list, ok := v.([]string); ok {
+		// Lists of strings, e.g. frames of stack traces, are written as arrays in all modes
+		l.line.WriteByte('[')
+		for i, s := range list {
+			if i != 0 {
+				l.line.WriteByte(',')
+			}
+			l.jsonString(s)
+		}
+		l.line.WriteByte(']')
+		return
+	}
+
+	if l.fieldTypes == FieldsAllStrings {
+		l.jsonString(l.fieldValue(v))
+		return
+	}
+
+	switch v := v.(type) {
+	case nil:
+		l.line.WriteString("null")
+	case bool:
+		l.line.WriteString(strconv.FormatBool(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		l.line.WriteString(l.fieldValue(v))
+	case float32, float64:
+		if data, err := json.Marshal(v); err == nil {
+			l.line.Write(data)
+		} else {
+			// NaN and infinities are not valid JSON numbers
+			l.jsonString(l.fieldValue(v))
+		}
+	case time.Duration:
+		if l.durFormat == DurationNanos {
+			l.line.WriteString(strconv.FormatInt(int64(v), 10))
+		} else {
+			l.jsonString(v.String())
+		}
+	default:
+		l.jsonString(l.fieldValue(v))
+	}
+}
+
+// SetOutputFormat sets the format of lines written to the log target, additional writers (see [AddWriter])
+// and the stderr mirror. In the [FormatJSON] format each message is written as the JSON object on its own line
+// with the "schema" (if set, see [SetSchemaVersion]), "time" (formatted according to [SetTimeFormat]), "level",
+// "app" (the prefix, if not empty), "host" (if enabled, see [SetHostname]), "pid" (unless NoPID is set),
+// "version" (if set, see [SetVersion]) and "msg" keys followed by fields, including the component and the calling function, if any. Flags of the standard
+// log package do not affect JSON objects. Targets that receive structured entries (e.g. journald) are not
+// affected. Default is [FormatText].
+func SetOutputFormat(f OutputFormat) {
+	logger.SetOutputFormat(f)
+}
+
+// SetFieldTypes sets how values of fields are written in the JSON format (see [SetOutputFormat]):
+// [FieldsNative] (default) writes numbers and booleans as JSON numbers and booleans (durations too, if
+// they are written as nanoseconds, see [SetDurationFormat]), [FieldsAllStrings] writes all values
+// as strings for systems with strict schemas. Other values are always written as strings. The text
+// format is not affected.
+func SetFieldTypes(ft FieldTypes) {
+	logger.SetFieldTypes(ft)
+}
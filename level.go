@@ -0,0 +1,207 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Level is the severity level of the log message.
+type Level int32
+
+// Supported levels of messages, from the lowest to the highest severity
+const (
+	LevelDebug	=	Level(iota)
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// SeverityStyle defines how levels of messages are represented in the text log, see [SetSeverityStyle].
+type SeverityStyle int
+
+// Supported severity styles
+const (
+	// Word tags: <D>, <WRN>, <ERR> and <FATAL>, no tag for information messages (default)
+	SeverityWords	=	SeverityStyle(iota)
+	// Numeric syslog severity in square brackets, e.g. [3] for errors, for all levels
+	SeverityNumeric
+	// Syslog priority value of the user-level facility in angle brackets, e.g. <11> for errors
+	SeveritySyslog
+	// Single characters without brackets: D, I, W, E and F, for all levels, see SetCompactLevels
+	SeverityCompact
+)
+
+// Syslog facility of user-level messages, used by SeveritySyslog
+const syslogFacilityUser = 1
+
+// Tag of informational messages of the SeverityWords style in the fixed-width column, see SetLevelWidth
+const infoColumnTag = "<INF>"
+
+// customLevel describes the level registered by RegisterLevel
+type customLevel struct {
+	name	string
+	exits	bool
+}
+
+//nolint:gochecknoglobals // Registry of custom levels, see RegisterLevel
+var (
+	customLevelsMu	sync.RWMutex
+	customLevels	= map[Level]customLevel{}
+)
+
+// RegisterLevel registers the custom level with the name and the severity and returns it. The level
+// can be used with [Msg], [Writer], [SetLevel] and other functions that accept levels. Levels are
+// ordered by severities, built-in levels have severities from 0 ([LevelDebug]) to 4 ([LevelFatal]),
+// so e.g. the "EMERGENCY" level above Fatal has severity 5 or higher and the "TRACE" level below Debug
+// has a negative severity. Custom levels are filtered like built-in ones, their tag in the text log is
+// the uppercased name in angle brackets (e.g. <EMERGENCY>), statistic functions of the nearest built-in
+// level below are called for them, but not above the error level (see [SetStatFuncs]). If exits is
+// true, messages of the level are written regardless of the minimal level and the program terminates
+// after writing them, as for Fatal.
+// RegisterLevel panics if the severity is already used by the built-in or registered level,
+// so it is intended to be called at initialization, e.g. in the package var block.
+func RegisterLevel(name string, severity int, exits bool) Level {
+	lvl := Level(severity)
+
+	customLevelsMu.Lock()
+	defer customLevelsMu.Unlock()
+
+	if _, ok := customLevels[lvl]; ok || (lvl >= LevelDebug && lvl <= LevelFatal) {
+		panic(fmt.Sprintf("log: severity %d of level %q is already used by level %s", severity, name, lvl))
+	}
+
+	customLevels[lvl] = customLevel{name: strings.ToUpper(name), exits: exits}
+
+	return lvl
+}
+
+// custom returns the description of the registered level, ok is false for unknown levels
+func (lvl Level) custom() (customLevel, bool) {
+	customLevelsMu.RLock()
+	defer customLevelsMu.RUnlock()
+
+	cl, ok := customLevels[lvl]
+
+	return cl, ok
+}
+
+// valid returns true for built-in levels and levels registered by RegisterLevel
+func (lvl Level) valid() bool {
+	if lvl >= LevelDebug && lvl <= LevelFatal {
+		return true
+	}
+
+	_, ok := lvl.custom()
+
+	return ok
+}
+
+// exits returns true if the program terminates after writing a message of the level
+func (lvl Level) exits() bool {
+	if lvl == LevelFatal {
+		return true
+	}
+
+	cl, ok := lvl.custom()
+
+	return ok && cl.exits
+}
+
+// statLevel returns the built-in level whose statistic function is called for messages of the level
+func (lvl Level) statLevel() Level {
+	switch {
+	case lvl > LevelFatal:
+		return LevelError
+	case lvl < LevelDebug:
+		return LevelDebug
+	default:
+		return lvl
+	}
+}
+
+// LevelWriter is the interface implemented by log targets that need to know the severity level
+// of each written message, e.g. to colorize or route messages. If the log target (see [OpenWriter])
+// implements LevelWriter, its WriteLevel method is called instead of Write.
+//
+// WriteLevel is called exactly once per message, the p argument contains the already
+// formatted message (including prefix and level tag) terminated by the newline.
+type LevelWriter interface {
+	WriteLevel(level Level, p []byte) (n int, err error)
+}
+
+// String returns the name of the level.
+func (lvl Level) String() string {
+	switch lvl {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARNING"
+	case LevelError:
+		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
+	default:
+		if cl, ok := lvl.custom(); ok {
+			return cl.name
+		}
+		return fmt.Sprintf("Level(%d)", int32(lvl))
+	}
+}
+
+// tag returns the level tag of the style added before the message text
+func (lvl Level) tag(style SeverityStyle) string {
+	switch style {
+	case SeverityNumeric:
+		return "[" + strconv.Itoa(lvl.syslogSeverity()) + "] "
+	case SeveritySyslog:
+		return "<" + strconv.Itoa(syslogFacilityUser * 8 + lvl.syslogSeverity()) + "> "	//nolint:gomnd // RFC 5424 PRI
+	case SeverityCompact:
+		// The first letter of the name, e.g. W for warnings
+		if name := lvl.String(); name != "" {
+			return name[:1] + " "
+		}
+		return ""
+	default:
+		return lvl.wordTag()
+	}
+}
+
+// wordTag returns the level tag of the SeverityWords style
+func (lvl Level) wordTag() string {
+	switch lvl {
+	case LevelDebug:
+		return "<D> "
+	case LevelWarn:
+		return "<WRN> "
+	case LevelError:
+		return "<ERR> "
+	case LevelFatal:
+		return "<FATAL> "
+	case LevelInfo:
+		// No tag for informational messages
+		return ""
+	default:
+		return "<" + lvl.String() + "> "
+	}
+}
+
+// syslogSeverity maps levels to syslog severities (RFC 5424)
+func (lvl Level) syslogSeverity() int {
+	switch {
+	case lvl >= LevelFatal:
+		return 2	//nolint:gomnd // LOG_CRIT
+	case lvl >= LevelError:
+		return 3	//nolint:gomnd // LOG_ERR
+	case lvl >= LevelWarn:
+		return 4	//nolint:gomnd // LOG_WARNING
+	case lvl >= LevelInfo:
+		return 6	//nolint:gomnd // LOG_INFO
+	default:
+		return 7	//nolint:gomnd // LOG_DEBUG
+	}
+}
@@ -0,0 +1,222 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// leveledFile is the additional writer that receives messages of the level and above, see SetLeveledFiles
+type leveledFile struct {
+	path		string
+	minLevel	Level
+	fd			File	// nil if the file cannot be opened again by Reopen
+}
+
+func (lf *leveledFile) Write(p []byte) (int, error) {
+	if lf.fd == nil {
+		return 0, os.ErrClosed
+	}
+
+	return lf.fd.Write(p)
+}
+
+func (lf *leveledFile) WriteLevel(level Level, p []byte) (int, error) {
+	if level < lf.minLevel {
+		// Messages of lower levels are written to other files
+		return len(p), nil
+	}
+
+	return lf.Write(p)
+}
+
+func (lf *leveledFile) Close() error {
+	if lf.fd == nil {
+		// Nothing to close
+		return nil
+	}
+
+	fd := lf.fd
+	lf.fd = nil
+
+	return fd.Close()
+}
+
+// FileErrors contains errors of several files, e.g. returned by [SetLeveledFiles] as the reason
+// of [FileError], each error is [FileError] with the path of the file.
+type FileErrors []error
+
+func (fe FileErrors) Error() string {
+	msgs := make([]string, 0, len(fe))
+	for _, err := range fe {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns errors of all files, so they can be checked by errors.Is and errors.As.
+func (fe FileErrors) Unwrap() []error {
+	return fe
+}
+
+// Is reports whether an error of any file matches the target, for versions of Go
+// which do not support unwrapping of multiple errors.
+func (fe FileErrors) Is(target error) bool {
+	for _, err := range fe {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetLeveledFiles calls [SetLeveledFiles] on the l object.
+func (l *Logger) SetLeveledFiles(files map[Level]string) error {
+	// Open files in order of levels, so errors are reported in the same order
+	levels := make([]Level, 0, len(files))
+	for level := range files {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+	var err error
+
+	l.execute(func() {
+		leveled := make([]*leveledFile, 0, len(levels))
+		var errs FileErrors
+
+		for _, level := range levels {
+			lf := &leveledFile{path: files[level], minLevel: level}
+			if oErr := l.openLeveled(lf); oErr != nil {
+				errs = append(errs, oErr)
+				continue
+			}
+			leveled = append(leveled, lf)
+		}
+
+		if errs != nil {
+			// Keep the previous configuration, close files opened so far
+			for _, lf := range leveled {
+				_ = lf.Close()
+			}
+			err = NewFileError("cannot open leveled log files: %w", errs)
+			return
+		}
+
+		err = l.closeLeveled()
+
+		l.leveled = leveled
+		for _, lf := range leveled {
+			l.sinks = append(l.sinks, &sink{w: lf, name: lf.path, owned: true})
+		}
+	})
+
+	return err
+}
+
+// openLeveled opens the file of the leveled file writer
+func (l *Logger) openLeveled(lf *leveledFile) error {
+	fd, err := l.fileSystem().OpenFile(lf.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, defaultPermMode)
+	if err != nil {
+		return NewFilePathError(lf.path, "cannot open leveled log file: %w", err)
+	}
+
+	lf.fd = fd
+
+	return nil
+}
+
+// closeLeveled closes files set by SetLeveledFiles and removes them from additional writers
+func (l *Logger) closeLeveled() error {
+	if l.leveled == nil {
+		// Nothing to close
+		return nil
+	}
+
+	sinks := l.sinks[:0]
+	for _, s := range l.sinks {
+		if _, ok := s.w.(*leveledFile); !ok {
+			sinks = append(sinks, s)
+		}
+	}
+	l.sinks = sinks
+
+	var err error
+	for _, lf := range l.leveled {
+		if cErr := lf.Close(); cErr != nil && err == nil {
+			err = NewFilePathError(lf.path, "cannot close leveled log file: %w", cErr)
+		}
+	}
+
+	l.leveled = nil
+
+	return err
+}
+
+// reopenLeveled closes files set by SetLeveledFiles and opens them again, e.g. after renaming by logrotate
+func (l *Logger) reopenLeveled() error {
+	var err error
+
+	for _, lf := range l.leveled {
+		if cErr := lf.Close(); cErr != nil && err == nil {
+			err = NewFilePathError(lf.path, "cannot close leveled log file: %w", cErr)
+		}
+		// Writing to the file that cannot be opened fails, errors are passed to the error handler
+		if oErr := l.openLeveled(lf); oErr != nil && err == nil {
+			err = oErr
+		}
+	}
+
+	return err
+}
+
+// rotateLeveled renames files set by SetLeveledFiles in the same way as the log file, then opens them again
+func (l *Logger) rotateLeveled(date time.Time) error {
+	var err error
+
+	for _, lf := range l.leveled {
+		backup, bErr := l.backupName(lf.path, date)
+		if bErr != nil {
+			if err == nil {
+				err = bErr
+			}
+			continue
+		}
+
+		if cErr := lf.Close(); cErr != nil && err == nil {
+			err = NewFilePathError(lf.path, "cannot close leveled log file: %w", cErr)
+		}
+		if rErr := l.fileSystem().Rename(lf.path, backup); rErr != nil && err == nil {
+			err = NewFilePathError(lf.path, "cannot rename rotated leveled log file: %w", rErr)
+		}
+		if oErr := l.openLeveled(lf); oErr != nil && err == nil {
+			err = oErr
+		}
+	}
+
+	return err
+}
+
+// SetLeveledFiles opens files that receive messages of levels and above, in addition to the log target,
+// e.g. the common layout of the debug log with all messages, the application log and the error log:
+//
+//	err := SetLeveledFiles(map[Level]string{
+//		LevelDebug: "debug.log",
+//		LevelInfo:  "app.log",
+//		LevelError: "errors.log",
+//	})
+//
+// Files are written by the writer goroutine like additional writers (see [AddWriter]), in the same form
+// as the log, the level of the log (see [SetLevel]) and other filters are applied before. Files are reopened
+// by [Reopen], rotated together with the log file (see [SetRotationAge] and [RotateNow]) and closed by [Close].
+//
+// Files set by the previous call are closed, the empty map closes all of them. If any of files cannot be
+// opened, files set before are kept and [FileError] is returned, its reason is [FileErrors] with errors of
+// all files that cannot be opened. SetLeveledFiles must be called after opening the log.
+func SetLeveledFiles(files map[Level]string) error {
+	return logger.SetLeveledFiles(files)
+}
@@ -1,11 +1,22 @@
 package log
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
 // Exported constants:
 const (
 	// Default log target - empty line means that the default
 	// logger from the standart log package will be used
 	DefaultLog	=	""
 
+	// Default separator between the prefix and the log message
+	DefaultPrefixSeparator	=	": "
+
 	//
 	// Supported flags
 	//
@@ -26,6 +37,24 @@ const (
 // which it is associated with.
 type StatFunc func(format string, args ...any)
 
+// FormattedStatFunc defines the interface for statistics functions that get
+// the message already formatted by the logger, see [SetStatFuncsFormatted].
+type FormattedStatFunc func(msg string)
+
+// Rotator is the interface implemented by log targets that are able to rotate themselves,
+// e.g. writers provided by external log rotation libraries. If the writer passed to [OpenWriter]
+// implements Rotator, [Reopen] calls its Rotate method instead of reopening the log.
+// Rotate is called when no messages are being written, so it does not need
+// to be synchronized with Write.
+type Rotator interface {
+	Rotate() error
+}
+
+// ErrorHandler defines the interface for handlers of errors which occur while
+// writing messages to the log target. Such errors cannot be returned to
+// the caller of logging functions, so they are passed to the handler.
+type ErrorHandler func(err error)
+
 //
 // Default logger object
 //
@@ -33,17 +62,92 @@ type StatFunc func(format string, args ...any)
 //nolint:gochecknoglobals // Pointer to the default logger
 var logger *Logger
 
+// DebugBuild enables the D and Debug functions of the package. Setting it to false (e.g. in the init
+// function of production builds) makes these functions to return immediately, without checking the level.
+//
+//nolint:gochecknoglobals // Intended to be changed by applications
+var DebugBuild = true
+
+//nolint:gochecknoglobals // Levels of the default logger, accessed atomically
+var (
+	// Level of the default logger, it is checked by D and Debug without access to the logger,
+	// so these functions can be called even before Open
+	pkgLevel	=	int32(LevelInfo)
+	// Initial level of loggers, see SetInitialLevel
+	initLevel	=	int32(LevelInfo)
+)
+
+//nolint:gochecknoglobals // Flags used by Open functions instead of NoFlags, accessed atomically
+var defaultFlags = int32(NoFlags)
+
 // Open opens the log file to write messages with the application prefix.
 // If DefaultLog (empty string) is used as the file, the output is written
 // to the standard log module's Writer (usual - stderr). The value of the flags field
 // can be a bit combination of NoFlags, NoPID and flags of standard log package.
+// If flags is NoFlags, the flags set by [SetDefaultFlags] are used.
 //
-// NOTE: writing messages into the log before calling Open will cause a panic.
+// NOTE: writing messages into the log before calling Open will cause a panic, unless
+// [SetPreOpenBuffer] is used.
 func Open(file, prefix string, flags int) error {
-	logger = NewLogger()
+	opts := DefaultOptions()
+	opts.File, opts.Prefix, opts.Flags = file, prefix, flags
+
+	return OpenWithOptions(opts)
+}
+
+// MustOpen works like [Open], but panics if the log cannot be opened. The panic value is
+// an error that wraps the error returned by Open (usually [*FileError]). It returns the package
+// logger, so it can be used to initialize the log in main() or in a variable declaration.
+func MustOpen(file, prefix string, flags int) *Logger {
+	if err := Open(file, prefix, flags); err != nil {
+		panic(fmt.Errorf("cannot open log %q: %w", file, err))
+	}
+
+	return logger
+}
+
+// OpenWithOptions opens the log with the target and settings specified by opts. All settings are applied
+// before the writer goroutine is started, so there is no need to call setters after opening the log.
+// See [Options] and [DefaultOptions] for details.
+func OpenWithOptions(opts Options) error {
+	logger = newDefaultLogger()
+
+	// Synchronize the level checked by package functions
+	atomic.StoreInt32(&pkgLevel, int32(opts.Level))
+
+	return logger.OpenWithOptions(opts)
+}
+
+// OpenSync works like [Open], but opens the log in the synchronous mode, without starting
+// the writer goroutine. See [Logger.SetSync] for details.
+func OpenSync(file, prefix string, flags int) error {
+	logger = newDefaultLogger()
+	logger.SetSync(true)
+
 	return logger.Open(file, prefix, flags)
 }
 
+// OpenWriter opens the log on the w writer to write messages with the application prefix.
+// The meaning of prefix and flags is the same as for [Open]. If w implements the [LevelWriter]
+// interface, its WriteLevel method is used to write messages. The writer is owned by the caller,
+// so it is not closed by [Close].
+func OpenWriter(w io.Writer, prefix string, flags int) error {
+	logger = newDefaultLogger()
+	return logger.OpenWriter(w, prefix, flags)
+}
+
+// SetOutput replaces the target of the opened log by the w writer, the prefix, flags and all
+// settings of the log are kept. The replacement is performed by the writer goroutine after
+// writing of all queued messages. The old target is synced (see [SetSyncOnReopen]) and closed
+// if it is owned by the log, i.e. it is the file opened by [Open] or the connection opened by
+// functions like [OpenSyslog]. Writers passed to [OpenWriter] and SetOutput are owned by
+// the caller, so they are never closed by the log, also [Reopen] keeps writing to w.
+// Returns [ErrLogClosed] if the log is already closed, otherwise errors of syncing or closing
+// the old target.
+func SetOutput(w io.Writer) error {
+	return logger.SetOutput(w)
+}
+
 // Flags returns the set of flags
 func Flags() int {
 	return logger.Flags()
@@ -56,10 +160,405 @@ func SetFlags(flags int) error {
 	return logger.SetFlags(flags)
 }
 
+// SetFileHeader sets the function that returns the header written as the first line of new log files,
+// e.g. names of columns or the version of the format to make log files self-describing. The header is
+// written when the log file opened by [Open], [Reopen] or rotation (see [SetRotationAge]) is empty,
+// so existing files with data never get it. The newline is added to the header if missing, the empty
+// header is not written. Non-file targets are not affected. Use [Options].FileHeader to write
+// the header to the file created by opening the log.
+func SetFileHeader(fn func() string) {
+	logger.SetFileHeader(fn)
+}
+
+// SetRunSeparator sets the line written to the log target right after opening the log (but not
+// by [Reopen]), to see where runs of the program begin when the same file is appended across restarts.
+// The {time} and {pid} placeholders are replaced by the current time (formatted according to
+// [SetTimeFormat], the clock set by [SetClock] is used) and the PID, as in templates of [Tmpl], e.g.
+//
+//	opts := log.DefaultOptions()
+//	opts.RunSeparator = "======== new run {time} pid={pid} ========"
+//
+// The line is written as is, without the prefix. Default is empty (no separator). Use
+// [Options].RunSeparator to write the separator when opening the package logger.
+func SetRunSeparator(sep string) {
+	logger.SetRunSeparator(sep)
+}
+
+// SetPrefix sets a new prefix of messages (usually the application name), the PID is added
+// to it as before, if enabled. The log is reopened (see [Reopen]) to apply the prefix.
+//
+// NOTE: SetPrefix must be called after calling Open, otherwise it will cause a panic.
+func SetPrefix(prefix string) error {
+	return logger.SetPrefix(prefix)
+}
+
+// SetPrefixSeparator sets the separator between the prefix (with PID, if printed) and the log message,
+// by default - [DefaultPrefixSeparator]. An empty separator means a single space, i.e. "app message".
+// The separator is applied by the next call of [SetFlags], [SetPrefix] or [Reopen],
+// use [Options].PrefixSeparator to set it when opening the log.
+func SetPrefixSeparator(sep string) {
+	logger.SetPrefixSeparator(sep)
+}
+
 // SetDebug enables or disables debug mode. If debug mode is disabled (v == false),
 // the debug message functions (D and Debug) do not write data to the log.
+// SetDebug(true) is the same as SetLevel(LevelDebug), SetDebug(false) - SetLevel(LevelInfo).
 func SetDebug(v bool) {
-	logger.SetDebug(v)
+	if v {
+		SetLevel(LevelDebug)
+	} else {
+		SetLevel(LevelInfo)
+	}
+}
+
+// SetLevel sets the minimal level of messages written to the log, messages of lower levels are
+// discarded. Fatal messages are always written. The default level is [LevelInfo], unless changed by
+// [SetInitialLevel].
+func SetLevel(level Level) {
+	atomic.StoreInt32(&pkgLevel, int32(level))
+	logger.SetLevel(level)
+}
+
+// SetInitialLevel sets the level of loggers created by subsequent calls of Open functions
+// and [NewLogger]. Unlike other functions, it can be called before [Open]: in that case
+// the D and Debug functions become cheap no-ops instead of panicking, if the level is above
+// [LevelDebug]. To make debug calls as cheap as possible, call SetInitialLevel as early as
+// possible, e.g. in the init function of the main package.
+func SetInitialLevel(level Level) {
+	atomic.StoreInt32(&initLevel, int32(level))
+	atomic.StoreInt32(&pkgLevel, int32(level))
+}
+
+// SetDefaultFlags sets the flags used by subsequent calls of Open functions (including methods
+// of [Logger]) that are called with NoFlags, e.g. SetDefaultFlags(NoPID) in the init function
+// disables PID for all logs of the application. Explicit flags passed to Open functions are used
+// as is. Like [SetInitialLevel], it can be called before [Open]. [SetFlags] is not affected.
+func SetDefaultFlags(flags int) {
+	atomic.StoreInt32(&defaultFlags, int32(flags))
+}
+
+// LevelEnabled reports whether messages of the level pass the current level of the log (see [SetLevel]).
+// Unlike [WouldLog], it does not check pausing, so it is a single atomic load and comparison, that is
+// inlined by the compiler. It is intended for very hot paths and safe to call at very high frequency
+// concurrently with SetLevel. Like D and Debug, it can be called before Open.
+func LevelEnabled(level Level) bool {
+	return level >= Level(atomic.LoadInt32(&pkgLevel))
+}
+
+// WouldLog reports whether a message of the level would be written to the log, according to the current
+// level (see [SetLevel]) and pausing (see [Pause]). It allows to skip building expensive messages or fields:
+//
+//	if log.WouldLog(log.LevelDebug) {
+//		log.With("state", dumpState()).D("current state")
+//	}
+//
+// WouldLog is cheap (atomic loads only) and safe to call concurrently with SetLevel.
+// Like D and Debug, it can be called before Open.
+func WouldLog(level Level) bool {
+	if level.exits() {
+		// Fatal messages are always written
+		return true
+	}
+
+	if level < Level(atomic.LoadInt32(&pkgLevel)) {
+		return false
+	}
+
+	// Logger may be not opened yet
+	return logger == nil || atomic.LoadInt32(&logger.paused) == 0
+}
+
+// IsOpen reports whether the log is opened by one of Open functions and not closed yet. Unlike other
+// functions, it can be called before [Open] and after [Reset], so libraries can check whether the application
+// has configured logging and do nothing otherwise, e.g.:
+//
+//	if log.IsOpen() {
+//		log.I("Cache is loaded: %d entries", n)
+//	}
+func IsOpen() bool {
+	return logger.IsOpen()
+}
+
+// SkipEmpty enables or disables skipping of empty messages. If enabled (v == true), messages that
+// are empty after formatting (e.g. I("") or I("%s", "")) are dropped instead of writing lines
+// that contain only the prefix. By default, empty messages are written.
+func SkipEmpty(v bool) {
+	logger.SkipEmpty(v)
+}
+
+// SetMirrorWriter sets the writer that receives duplicates of error and fatal messages. By default (nil)
+// the messages are duplicated by the default logger of the standard [log] package, which writes to stderr.
+// Messages are written to w in the same form as to the log. Messages are not duplicated if w is
+// the log target itself.
+func SetMirrorWriter(w io.Writer) {
+	logger.SetMirrorWriter(w)
+}
+
+// SetConfirmWrites enables (v == true, default) or disables waiting for messages to be written.
+// By default, logging functions return only after the writer goroutine has written the message
+// to the log target. When confirmation is disabled, logging functions return as soon as the writer
+// goroutine receives the message, so the caller does not wait for the (possibly slow) target.
+// Messages are still written in order and are not lost on [Close]. Use [ISync], [ESync] or [Barrier]
+// to wait for particular messages. Fatal messages are always waited for, the program is terminated
+// by the goroutine of the caller after writing. The setting has no effect in the synchronous mode.
+func SetConfirmWrites(v bool) {
+	logger.SetConfirmWrites(v)
+}
+
+// SetSyncOnReopen enables (v == true, default) or disables syncing of the log file (see [os.File.Sync])
+// before closing it by [Reopen] or by rotation (see [SetRotationAge]), so the tail of the old file
+// is not lost if the system crashes during rotation. Disabling it makes reopening faster.
+func SetSyncOnReopen(v bool) {
+	logger.SetSyncOnReopen(v)
+}
+
+// SetSeverityStyle sets how levels of messages are represented in the text log: by word tags like <ERR>
+// ([SeverityWords], default), by numeric syslog severities like [3] ([SeverityNumeric]) or by syslog
+// priorities like <11> ([SeveritySyslog]), e.g. for SIEM tools that expect RFC 5424 severities, or by single
+// characters like E ([SeverityCompact], see [SetCompactLevels]). Numeric and compact styles tag messages
+// of all levels, including information messages. Targets that receive
+// structured entries (e.g. journald) are not affected.
+func SetSeverityStyle(style SeverityStyle) {
+	logger.SetSeverityStyle(style)
+}
+
+// SetLevelWidth renders tags of levels in the text log as the column of n characters, so texts of messages
+// line up: tags are padded by spaces on the right, informational messages get the <INF> tag in the word
+// style (see [SetSeverityStyle]), tags suppressed by [SetLevelTag] become blank columns. Tags longer than
+// n are not cut, so n has to fit the longest tag, e.g. 7 for <FATAL>:
+//
+//	app: <D>     debug message
+//	app: <INF>   informational message
+//	app: <FATAL> fatal message
+//
+// Zero or negative n (default) disables the column: tags have variable widths, informational messages
+// have no tag. Targets that receive structured entries and the JSON format are not affected.
+func SetLevelWidth(n int) {
+	logger.SetLevelWidth(n)
+}
+
+// SetCompactLevels switches tags of levels in the text log to single characters without brackets
+// (v == true), e.g. "app: W disk is almost full", or back to the default word tags (v == false).
+// It is the shortcut for SetSeverityStyle([SeverityCompact]) and SetSeverityStyle([SeverityWords]).
+// Informational messages get the I tag too, custom levels (see [RegisterLevel]) get the first letter
+// of their names. Tags replaced by [SetLevelTag] are kept.
+func SetCompactLevels(v bool) {
+	logger.SetCompactLevels(v)
+}
+
+// SetEntryProcessor sets the function that gets each message as the [Entry] right before formatting
+// and can change it: rewrite the text, add, remove or change fields (only fields added by [With],
+// automatic ones like the component are added later), change the time or the level, e.g. to scrub
+// secrets or to implement policies that [AddEscalationRule] cannot express. The changed level affects
+// the tag, the stderr mirror and termination of the program after fatal messages. If the processor
+// sets the unknown level (neither built-in nor registered by [RegisterLevel]), the original level
+// is kept and the error is passed to the error handler (see [SetErrorHandler]). Raw messages (see [Raw])
+// are not processed. nil removes the processor (default). SetEntryProcessor must be called after opening the log.
+//
+// NOTE: The processor is called by the writer goroutine for each message, so it must be fast: all
+// messages wait for it. It must not write to the same log.
+func SetEntryProcessor(fn func(*Entry)) {
+	logger.SetEntryProcessor(fn)
+}
+
+// SetLevelTag replaces the tag of the level in the text log by the tag, regardless of the severity style
+// (see [SetSeverityStyle]), e.g. SetLevelTag(LevelDebug, "<DEBUG>"). The space between the tag and the
+// message is added automatically. An empty tag suppresses the tag of the level completely, e.g. for
+// debug messages that are self-describing: "app: message". Use [ResetLevelTag] to restore the tag
+// of the style. SetLevelTag must be called after opening the log.
+func SetLevelTag(level Level, tag string) {
+	logger.SetLevelTag(level, tag)
+}
+
+// ResetLevelTag restores the default tag of the level, replaced by [SetLevelTag].
+func ResetLevelTag(level Level) {
+	logger.ResetLevelTag(level)
+}
+
+// SetStrictFormat enables (v == true) or disables (default) checking of formatted messages for errors
+// like %!d(string=text), that fmt writes when the format does not match the arguments. When the check
+// is enabled, such message is still written, but also an error that wraps [ErrBadFormat] and contains
+// the format string is passed to the error handler (see [SetErrorHandler]). It is intended to catch
+// logging bugs during testing, the check costs an additional scan of each message.
+func SetStrictFormat(v bool) {
+	logger.SetStrictFormat(v)
+}
+
+// Pause pauses logging, all messages written until [Resume] is called are dropped and
+// counted (see [Dropped]). Unlike [Close], the log file remains open. Fatal messages
+// are written even when logging is paused, so the program termination is always logged.
+func Pause() {
+	logger.Pause()
+}
+
+// Resume resumes logging paused by [Pause].
+func Resume() {
+	logger.Resume()
+}
+
+// Dropped returns the number of messages dropped because logging was paused.
+func Dropped() uint64 {
+	return logger.Dropped()
+}
+
+// SetRotationAge sets the maximum age of the log file. When a message is written to the file whose first
+// message was written more than d ago, the file is renamed by adding the date of the first message to its
+// name (e.g. app.log becomes app-2022-11-08.log, an additional number is added if such file already exists)
+// and the new file is opened. Zero d disables age-based rotation (default). Rotation is supported only
+// for regular log files. Rotation errors are passed to the error handler set by [SetErrorHandler].
+func SetRotationAge(d time.Duration) {
+	logger.SetRotationAge(d)
+}
+
+// SetClock sets the function used by the logger to get the current time, e.g. to check the age of
+// the log file (see [SetRotationAge]). It is intended mostly for tests, nil means time.Now (default).
+func SetClock(clock func() time.Time) {
+	logger.SetClock(clock)
+}
+
+// SetStackDedup enables or disables deduplication of stack traces. If enabled, the trailing
+// multi-line block of a message (everything after the first line, e.g. a stack trace of a recovered
+// panic) is compared with the blocks of recently written messages. The first occurrence of a block
+// is marked by the "(stack #<seq>)" line, repeated identical blocks are replaced by
+// the "(stack identical to #<seq>)" line. Comparison is performed by the writer goroutine.
+func SetStackDedup(v bool) {
+	logger.SetStackDedup(v)
+}
+
+// SetDrainTimeout sets the maximum duration for which [Close] waits for writing of queued messages, e.g.
+// messages spilled to the disk (see [SpillToDisk]) or the message being written to the slow log target.
+// If the timeout is exceeded, Close returns an error that wraps [ErrDrainTimeout] and reports the number
+// of abandoned messages, these messages are dropped (see [Dropped]). Closing of the log target is finished
+// in the background as soon as the current write completes. Zero or negative duration (default) means
+// that Close waits for all messages. [Shutdown] limits the whole closing by its context instead.
+func SetDrainTimeout(d time.Duration) {
+	logger.SetDrainTimeout(d)
+}
+
+// SetErrorHandler sets the handler of errors which occur while writing messages
+// to the log target. If the handler is not set (default), such errors are ignored.
+// See [ErrorHandler] for details.
+func SetErrorHandler(eh ErrorHandler) {
+	logger.SetErrorHandler(eh)
+}
+
+// Errors returns the channel of errors which occur while writing messages, i.e. the same errors that
+// are passed to the error handler (see [SetErrorHandler]): write failures, rotation failures, etc.
+// The channel and the handler can be used together. The channel is buffered, if the receiver
+// does not keep up, the oldest errors are dropped, so writing of messages is never blocked.
+// Errors are sent only after the first call of Errors, all calls return the same channel.
+// The channel is closed by [Close].
+func Errors() <-chan error {
+	return logger.Errors()
+}
+
+// SetWriteRetry makes the writer to retry the failed write of the message to the log target up to attempts
+// times, waiting for the backoff duration before each retry, e.g. to survive transient errors of network
+// targets. The error is passed to the error handler (see [SetErrorHandler]) only if all retries fail,
+// then the reconnect-on-error policy and the fallback file are applied as usual. Messages are written
+// by the single goroutine, so retries keep the order of messages, but all subsequent messages (and
+// callers, if writes are confirmed, see [SetConfirmWrites]) wait for them. Zero or negative attempts
+// (default) disables retries. Targets that may write a part of the message before failing can get
+// the message duplicated.
+func SetWriteRetry(attempts int, backoff time.Duration) {
+	logger.SetWriteRetry(attempts, backoff)
+}
+
+// SetReconnectOnError enables or disables the reconnect-on-error policy. If the policy is enabled,
+// the log target (file or socket) is reopened after a write error and the failed message is written
+// again. The errors of both attempts are passed to the error handler set by [SetErrorHandler].
+func SetReconnectOnError(v bool) {
+	logger.SetReconnectOnError(v)
+}
+
+// SetFallbackFile sets the path of the fallback file. When a message cannot be written to the log
+// target (e.g. the socket peer is unreachable), the formatted message is appended to the fallback file,
+// so it is not lost. Use [ReplayFallback] to write such messages to the log target when it becomes
+// available again. Errors of opening and writing the fallback file are passed to the error handler
+// set by [SetErrorHandler]. An empty path disables the fallback (default).
+func SetFallbackFile(path string) {
+	logger.SetFallbackFile(path)
+}
+
+// SetFallback sets the logger that receives messages when the log target fails, e.g. the logger opened
+// on stderr. When the message cannot be written to the log target (after retries and reconnection, if
+// enabled, see [SetWriteRetry] and [SetReconnectOnError]), the formatted message is passed to the fallback
+// logger as is (see [Raw]), as well as all subsequent messages, without attempts to write them to the log
+// target, until the log target recovers by a successful [Reopen]. The error of writing that caused
+// switching and the error that wraps [ErrFailover] are passed to the error handler (see [SetErrorHandler]),
+// rerouted messages are not reported. Additional writers (see [AddWriter]) still receive all messages.
+// While the fallback logger is set and opened, the fallback file (see [SetFallbackFile]) is not used.
+// The logger cannot be the fallback of itself or of its children, nil disables the fallback (default).
+func SetFallback(fb *Logger) {
+	logger.SetFallback(fb)
+}
+
+// ReplayFallback writes messages kept in the fallback file (see [SetFallbackFile]) to the log target,
+// then removes the fallback file. Messages are replayed by the same goroutine which writes other
+// messages, so they cannot be mixed with concurrently written ones. If the log target fails again,
+// messages that were not replayed remain in the fallback file and the error is returned.
+func ReplayFallback() error {
+	return logger.ReplayFallback()
+}
+
+// With returns a child logger which adds the fields, specified by args, to each written message.
+// The child logger shares the log target and the configuration with its parent. Each argument
+// can be either a [Field] or a key (string) followed by a value. Arguments that cannot be used
+// as keys, including the key without a value, are written with the !BADKEY key.
+func With(args ...any) *Logger {
+	return logger.With(args...)
+}
+
+// WithFields works like [With], but takes fields only. Together with field constructors which do not
+// allocate for common values (see [Int] and [Str]), it keeps the number of allocations of making
+// the child logger fixed (the slice of fields and the logger itself), while each key-value pair
+// of With allocates to convert the value to the interface.
+func WithFields(fields ...Field) *Logger {
+	return logger.WithFields(fields...)
+}
+
+// Component returns a child logger which marks each written message with the name of the component
+// of the program. In the text log the name is written in square brackets right after the prefix,
+// before the level tag (e.g. "app: [db] <WRN> message"), targets that receive structured entries
+// (e.g. journald) get it as the first field with the [ComponentKey] key. The child logger shares
+// the log target and the configuration with its parent, like loggers returned by [With].
+// Calling Component on the child logger replaces the name of the component.
+func Component(name string) *Logger {
+	return logger.Component(name)
+}
+
+// SetMaxFields limits the number of fields of loggers returned by [With] to n, e.g. to bound memory usage
+// and the size of messages when the logger is passed through many layers that add their own fields.
+// When the limit is exceeded, the oldest fields are dropped and the field with the [TruncatedKey] key
+// and the true value is added before the rest of fields, the marker does not count toward the limit.
+// Only fields added by With count, values written automatically (time, level, PID, component, etc.)
+// do not. Zero or negative n (default) means no limit. The limit is applied when child loggers
+// are created, so it does not affect already created loggers.
+func SetMaxFields(n int) {
+	logger.SetMaxFields(n)
+}
+
+// SetFieldOrder sets the order of fields of messages, e.g. for ingestion systems that expect some fields
+// first. Fields with listed keys are written first, in the listed order, other fields follow them in the order
+// they were added (see [With]). In the [FormatJSON] format keys of attributes of messages ("time", "level",
+// "app", "pid" and "msg") can be listed too, by default they are written in this order before fields.
+// In the text format the time, PID and the level tag are parts of the prefix, so only fields are ordered.
+// Entries passed to subscribers (see [Subscribe]) and custom formatters keep the original order. The nil
+// or empty keys restore the default order. SetFieldOrder must be called after opening the log,
+// use the FieldOrder field of [Options] to set the order before.
+func SetFieldOrder(keys []string) {
+	logger.SetFieldOrder(keys)
+}
+
+// SetDurationFormat sets the format of time.Duration values of fields, see [DurationFormat].
+func SetDurationFormat(df DurationFormat) {
+	logger.SetDurationFormat(df)
+}
+
+// SetTimeFormat sets the layout (as used by time.Time.Format) of time.Time values of fields.
+// The default layout is [DefaultTimeFormat].
+func SetTimeFormat(layout string) {
+	logger.SetTimeFormat(layout)
 }
 
 // SetStatFuncs sets the ef (for errors) and ew (for warnings) message statistics handlers.
@@ -68,63 +567,253 @@ func SetStatFuncs(ef, wf StatFunc) {
 	logger.SetStatFuncs(ef, wf)
 }
 
+// SetStatFuncsFormatted sets the ef (for errors) and wf (for warnings) message statistics handlers,
+// that get the formatted message text (without the level tag and fields) instead of the format
+// and arguments, so the message is not formatted again by handlers. Unlike handlers set by
+// [SetStatFuncs], they are called by the writer goroutine when the message is written, so they
+// are not called for dropped messages (e.g. when logging is paused) and must not block.
+// Both kinds of handlers can be set at the same time.
+func SetStatFuncsFormatted(ef, wf FormattedStatFunc) {
+	logger.SetStatFuncsFormatted(ef, wf)
+}
+
+// SetInfoStatFunc sets the inf information message statistics handler, it is called
+// in the same way as the error and warning handlers set by [SetStatFuncs].
+func SetInfoStatFunc(inf StatFunc) {
+	logger.SetInfoStatFunc(inf)
+}
+
 // D is an shortcut for Debug.
 func D(format string, v ...any) {
+	if !DebugBuild || atomic.LoadInt32(&pkgLevel) > int32(LevelDebug) {
+		return
+	}
+	if preOpened(LevelDebug, format, v) {
+		return
+	}
 	logger.D(format, v...)
 }
 // Debug writes a debug message to the log prefixed with <D>,
 // but only if debug mode is enabled (see [SetDebug]).
 func Debug(format string, v ...any) {
+	if !DebugBuild || atomic.LoadInt32(&pkgLevel) > int32(LevelDebug) {
+		return
+	}
+	if preOpened(LevelDebug, format, v) {
+		return
+	}
 	logger.Debug(format, v...)
 }
 
 // I is an shortcut for Info.
 func I(format string, v ...any) {
+	if preOpened(LevelInfo, format, v) {
+		return
+	}
 	logger.I(format, v...)
 }
 // Info writes an information message to the log. The message level prefix is not used.
+// It also calls the information statistics handler, if previously set with the [SetInfoStatFunc] function.
 func Info(format string, v ...any) {
+	if preOpened(LevelInfo, format, v) {
+		return
+	}
 	logger.Info(format, v...)
 }
 
 // W is an shortcut for Warn.
 func W(format string, v ...any) {
+	if preOpened(LevelWarn, format, v) {
+		return
+	}
 	logger.W(format, v...)
 }
 // Warn writes a warning message prefixed with <WRN> to the log.
 // It also calls the warning statistics handler, if previously set with the [SetStatFuncs] function.
 func Warn(format string, v ...any) {
+	if preOpened(LevelWarn, format, v) {
+		return
+	}
 	logger.Warn(format, v...)
 }
 
 // E is an shortcut for Err.
 func E(format string, v ...any) {
+	if preOpened(LevelError, format, v) {
+		return
+	}
 	logger.E(format, v...)
 }
 // Err writes a warning message prefixed with <ERR> to the log. The same message is duplicated to stderr.
+// Messages are duplicated in the same order in which they are written to the log.
 // It also calls the error statistics handler, if previously set with the [SetStatFuncs] function.
 func Err(format string, v ...any) {
+	if preOpened(LevelError, format, v) {
+		return
+	}
 	logger.Err(format, v...)
 }
 
 // F is an shortcut for Fatal.
 func F(format string, v ...any) {
+	if preOpened(LevelFatal, format, v) {
+		return
+	}
 	logger.F(format, v...)
 }
 // Fatal writes a fatal message prefixed with <FATAL> to the log. The same message is duplicated to stderr.
-// Then it causes program termination with the standard function log.Fatalf().
+// After the message is written (and compressed data are flushed, see [SetCompressLive]), the program
+// is terminated by os.Exit(1) or by the function set by [SetExitFunc], called from the goroutine of the caller.
 func Fatal(format string, v ...any) {
+	if preOpened(LevelFatal, format, v) {
+		return
+	}
 	logger.Fatal(format, v...)
 }
 
-// Close closes the log file. Attempts to write to the log file after closing it will cause the goroutine
-// to block, which can lead to a panic when all the goroutines in the program are blocked.
+// SetExitFunc replaces the function that terminates the program after writing fatal messages (see [Fatal]
+// and [RegisterLevel]), os.Exit by default. The function is called from the goroutine of the caller of Fatal
+// with the exit code 1, after the message is written. It is intended for tests of code paths that call Fatal (see
+// [ExpectFatal]) and for the custom shutdown, note that if the function returns, the program continues
+// to run, including the code after the call of Fatal. SetExitFunc must be called after opening the log,
+// nil restores os.Exit.
+func SetExitFunc(fn func(code int)) {
+	logger.SetExitFunc(fn)
+}
+
+// Msg writes the message of the level to the log, it works like the function of the level, e.g.
+// Msg(LevelWarn, ...) is the same as [Warn]. It also accepts custom levels registered by [RegisterLevel].
+func Msg(level Level, format string, v ...any) {
+	if preOpened(level, format, v) {
+		return
+	}
+	logger.Msg(level, format, v...)
+}
+
+// Raw writes b to the log as a single record, verbatim. Only the newline is added if b does not end
+// with it. The prefix (including PID), the level tag and fields are intentionally omitted, so the caller
+// fully controls the format of the record (e.g. a pre-serialized JSON object). The level is used to filter
+// the record (see [SetLevel]) and to pass it to the [LevelWriter] target, the record is not duplicated
+// to stderr. Raw records keep their order with other messages. If level is [LevelFatal], the program
+// is terminated after writing the record.
+func Raw(level Level, b []byte) {
+	if preOpenedRaw(level, b) {
+		return
+	}
+	logger.Raw(level, b)
+}
+
+// ISync writes an information message like [Info], then waits until the message is written and
+// the log file is synced to the storage, like [Barrier] does. It is intended for single messages
+// that must be persisted (e.g. audit events), regardless of [SetConfirmWrites]. Each call costs
+// at least one fsync, i.e. from milliseconds to tens of milliseconds on rotating disks, so it
+// should not be used for regular messages.
+func ISync(format string, v ...any) error {
+	return logger.ISync(format, v...)
+}
+
+// ESync writes an error message like [Err], then waits until the message is persisted,
+// see [ISync] for details.
+func ESync(format string, v ...any) error {
+	return logger.ESync(format, v...)
+}
+
+// Flush works like [Barrier]: it waits until all queued messages are written, flushes compressed
+// data (see [SetCompressLive]) and syncs the log file, but also returns the number of messages written
+// since the previous call of Flush (or opening of the log), e.g. for periodic durability checkpoints
+// of the watchdog. The returned error is the error of syncing, errors of writing messages are passed
+// to the error handler (see [SetErrorHandler]). In the synchronous mode (see [Logger.SetSync])
+// messages are written immediately, so Flush does nothing and returns zero.
+// Flush is safe to call concurrently with logging functions.
+func Flush() (int, error) {
+	return logger.Flush()
+}
+
+// SetFlushThreshold makes the writer goroutine flush compressed data (see [SetCompressLive]) and sync
+// the log file each time count messages are written since the last sync, to bound the number of messages
+// that can be lost on the crash of the system without the cost of syncing each message (see [ISync]).
+// The threshold works in addition to other triggers, whichever fires first: compressed data are also
+// flushed every second, and [Flush], [Barrier] and [ISync] sync the file and restart counting of
+// messages for the threshold, the periodic flush of compressed data does not restart it, because it
+// does not sync the file. Zero or negative count (default) disables the threshold.
+func SetFlushThreshold(count int) {
+	logger.SetFlushThreshold(count)
+}
+
+// Barrier blocks until the writer goroutine has written all messages queued before the call,
+// then it syncs the log file (and the fallback file, if opened) to the storage, without closing it.
+// Barrier is intended to be called before risky operations (e.g. calls into CGO code)
+// that can crash the program, to guarantee that all previously logged messages are persisted.
+// For targets that do not support syncing (such as sockets), only the first guarantee is provided.
+func Barrier() error {
+	return logger.Barrier()
+}
+
+// WriteLatency returns the average and the maximum durations of writing messages to the log target.
+// It helps to notice when the logging itself becomes a bottleneck, e.g. due to a slow disk.
+func WriteLatency() (avg, max time.Duration) {
+	return logger.WriteLatency()
+}
+
+// Close closes the log file and stops the writer goroutine, regardless of the log target type
+// (including [DefaultLog]). Attempts to write to the log file after closing it will cause the goroutine
+// to block, which can lead to a panic when all the goroutines in the program are blocked. In the
+// synchronous mode (see [OpenSync]) such messages are dropped.
 //
 // NOTE: [Close] must be called before exiting the progam to avoid loss of the last log messages.
 func Close() error {
 	return logger.Close()
 }
 
+// Reset closes the log, if it is opened, and returns the package to the initial state, as if no Open
+// function has been called: the default logger with all its settings (e.g. the debug mode and statistic
+// functions) is dropped, the initial level (see [SetInitialLevel]) and default flags (see [SetDefaultFlags])
+// are restored. It is safe to call Reset when the log is not opened, so tests can call it to start with
+// the clean state, e.g. by t.Cleanup(func() { _ = log.Reset() }). The error of closing is returned,
+// the package is reset regardless of it.
+func Reset() error {
+	var err error
+	if logger != nil && !logger.closed {
+		err = logger.Close()
+	}
+
+	logger = nil
+	atomic.StoreInt32(&initLevel, int32(LevelInfo))
+	atomic.StoreInt32(&pkgLevel, int32(LevelInfo))
+	atomic.StoreInt32(&defaultFlags, int32(NoFlags))
+	SetFS(nil)
+	resetPreOpen()
+
+	return err
+}
+
+// Reopen closes and opens the log file again, it is intended to support the log rotation.
+// If the log is written to the socket (see [OpenUnixSocket]), Reopen reconnects to it.
+// If the log is written to the writer that implements [Rotator], Reopen calls its Rotate method.
+// Settings that affect the prefix of messages (e.g. [SetPrefixSeparator]) changed since opening
+// the log are applied by Reopen. The opened file is checked by the zero-byte write, so if the file
+// cannot be written (e.g. it was created by logrotate with the wrong owner) or the file system is
+// read-only, the [FileError] is returned by Reopen instead of errors of writing of subsequent messages.
+// Messages written after the failed Reopen are passed to the error handler, the next successful
+// Reopen continues writing to the file.
 func Reopen() error {
 	return logger.Reopen()
 }
+
+// ReopenContext works like [Reopen], but gives up if ctx is done before the writer goroutine completes
+// the current write (e.g. to the slow disk), so signal handlers that rotate logs do not hang. In this
+// case an error that wraps the error of the context is returned and the log remains opened on the current
+// file, writing of messages continues as soon as the current write completes. Once the writer is stopped,
+// reopening is not interrupted, so the log is never left without the target.
+func ReopenContext(ctx context.Context) error {
+	return logger.ReopenContext(ctx)
+}
+
+func newDefaultLogger() *Logger {
+	l := NewLogger()
+
+	// Synchronize the level checked by package functions with the new logger
+	atomic.StoreInt32(&pkgLevel, atomic.LoadInt32(&l.level))
+
+	return l
+}
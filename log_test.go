@@ -1,15 +1,24 @@
 package log
 
 import (
+	"compress/gzip"
+	"context"
 	"fmt"
 	"testing"
 	"errors"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sort"
+	"sync"
+	"strconv"
 	"io"
+	"regexp"
+	"runtime"
+	"testing/fstest"
+	"time"
 	stdLog "log"
 )
 
@@ -253,6 +262,31 @@ func TestStatFunctions(t *testing.T) {
 	checkStatTestResults(t, wrns, expWrns)
 }
 
+func TestInfoStatFunction(t *testing.T) {
+	if err := Open(os.DevNull, stubApp, NoFlags); err != nil {
+		t.Errorf("cannot open output file %q: %v", os.DevNull, err)
+		t.FailNow()
+	}
+
+	infs := []string{}
+	SetInfoStatFunc(func(format string, args ...any) {
+		infs = append(infs, fmt.Sprintf(format, args...))
+	})
+
+	Info(stubLogFormat, 0, `INFO`)
+	Warn(stubLogFormat, 1, `WARNING`)
+	I(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close test log file %q: %v", os.DevNull, err)
+	}
+
+	checkStatTestResults(t, infs, []string{
+		fmt.Sprintf(stubLogFormat, 0, `INFO`),
+		fmt.Sprintf(stubLogFormat, 2, `INFO`),
+	})
+}
+
 func runStatsTests() ([]string, []string) {
 	// Expected statistic results
 	expErrs, expWrns := []string{}, []string{}
@@ -405,6 +439,10 @@ func TestFailOpen(t *testing.T) {
 		if !errors.Is(err, fs.ErrNotExist) {
 			t.Errorf("failed Open() error is %v, want - %v", err, fs.ErrNotExist)
 		}
+		// The failed file has to be reported
+		if path := err.(*FileError).Path(); path != logFile {
+			t.Errorf("failed Open() error path is %q, want - %q", path, logFile)
+		}
 
 	// Unexpected error
 	default:
@@ -432,6 +470,35 @@ func TestDefaultLog(t *testing.T) {
 	// Ok, tests passed
 }
 
+func TestDefaultLogNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		if err := Open(DefaultLog, stubApp, NoFlags); err != nil {
+			t.Errorf("cannot log on default logger: %v", err)
+			t.FailNow()
+		}
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log on default logger: %v", err)
+		}
+	}
+
+	// Double close must not block
+	if err := Close(); err != &ErrLogClosed {	//nolint:errorlint // Pointer comparison is intended
+		t.Errorf("double Close() returned %v, want - %v", err, &ErrLogClosed)
+	}
+
+	// Writer goroutines may need some time to finish after Close returned
+	for wait := 0; runtime.NumGoroutine() > before && wait < 100; wait++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines leaked: %d before Open/Close, %d after", before, after)
+	}
+}
+
 func TestFailReopenNxFile(t *testing.T) {
 	// Create temporary directory to write test logs
 	logDir := tempDir()
@@ -471,6 +538,10 @@ func TestFailReopenNxFile(t *testing.T) {
 		if !errors.Is(err, fs.ErrNotExist) {
 			t.Errorf("failed Reopen() error is %v, want - %v", err, fs.ErrNotExist)
 		}
+		// The failed file has to be reported
+		if path := err.(*FileError).Path(); path != logger.logName {
+			t.Errorf("failed Reopen() error path is %q, want - %q", path, logger.logName)
+		}
 
 	// Unexpected error
 	default:
@@ -491,14 +562,14 @@ func TestFailReopenCloseErr(t *testing.T) {
 	}
 
 	// Close log bypassing Close function
-	if closer, ok := logger.logger.Writer().(io.Closer); ok {
+	if closer, ok := logger.target.(io.Closer); ok {
 		if err := closer.Close(); err != nil {
 			t.Errorf("cannot close log file %q: %v", logFile, err)
 			t.FailNow()
 		}
 	} else {
 		panic(fmt.Sprintf("logger object contains invalid writter that cannot be closed," +
-			" type: %T", logger.logger.Writer()))
+			" type: %T", logger.target))
 	}
 
 	//nolint:errorlint // Try to reopen closed file
@@ -519,6 +590,10 @@ func TestFailReopenCloseErr(t *testing.T) {
 		if !errors.Is(err, fs.ErrClosed) {
 			t.Errorf("failed Reopen() error is %v, want - %v", err, fs.ErrClosed)
 		}
+		// The failed file has to be reported
+		if path := err.(*FileError).Path(); path != logFile {
+			t.Errorf("failed Reopen() error path is %q, want - %q", path, logFile)
+		}
 
 	// Unexpected error
 	default:
@@ -579,14 +654,14 @@ func TestFailClose(t *testing.T) {
 	}
 
 	// Close log bypassing Close function
-	if closer, ok := logger.logger.Writer().(io.Closer); ok {
+	if closer, ok := logger.target.(io.Closer); ok {
 		if err := closer.Close(); err != nil {
 			t.Errorf("cannot close log file %q: %v", logFile, err)
 			t.FailNow()
 		}
 	} else {
 		panic(fmt.Sprintf("logger object contains invalid writter that cannot be closed," +
-			" type: %T", logger.logger.Writer()))
+			" type: %T", logger.target))
 	}
 
 	//nolint:errorlint // Try to call Close() which believes that
@@ -603,6 +678,10 @@ func TestFailClose(t *testing.T) {
 		if !errors.Is(err, fs.ErrClosed) {
 			t.Errorf("failed Close() error is %v, want - %v", err, fs.ErrClosed)
 		}
+		// The failed file has to be reported
+		if path := err.(*FileError).Path(); path != logFile {
+			t.Errorf("failed Close() error path is %q, want - %q", path, logFile)
+		}
 		// OK
 
 	// Some unexpected error
@@ -612,6 +691,5036 @@ func TestFailClose(t *testing.T) {
 	}
 }
 
+// levelRecorder implements LevelWriter to record written messages with their levels
+type levelRecorder struct {
+	levels	[]Level
+	lines	[]string
+}
+
+func (lr *levelRecorder) Write(p []byte) (int, error) {
+	panic("Write must not be called on the LevelWriter target")
+}
+
+func (lr *levelRecorder) WriteLevel(level Level, p []byte) (int, error) {
+	lr.levels = append(lr.levels, level)
+	lr.lines = append(lr.lines, string(p))
+
+	return len(p), nil
+}
+
+func TestLevelWriter(t *testing.T) {
+	rec := &levelRecorder{}
+
+	if err := OpenWriter(rec, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the level writer: %v", err)
+		t.FailNow()
+	}
+	SetDebug(true)
+
+	Debug(stubLogFormat, 0, `DEBUG`)
+	Info(stubLogFormat, 1, `INFO`)
+	Warn(stubLogFormat, 2, `WARNING`)
+	Err(stubLogFormat, 3, `ERROR ` + errIsOk)
+	Fatal(stubLogFormat, 4, `FATAL ` + errIsOk)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the level writer: %v", err)
+	}
+
+	expLevels := []Level{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+	expLines := []string{
+		stubApp + `: <D> Test #0 - DEBUG log message` + "\n",
+		stubApp + `: Test #1 - INFO log message` + "\n",
+		stubApp + `: <WRN> Test #2 - WARNING log message` + "\n",
+		stubApp + `: <ERR> Test #3 - ERROR ` + errIsOk + ` log message` + "\n",
+		stubApp + `: <FATAL> Test #4 - FATAL ` + errIsOk + ` log message` + "\n",
+	}
+
+	if len(rec.lines) != len(expLines) {
+		t.Errorf("want %d messages, got %d: %#v", len(expLines), len(rec.lines), rec.lines)
+		t.FailNow()
+	}
+
+	for i := range expLines {
+		if rec.levels[i] != expLevels[i] {
+			t.Errorf("[%d] want level %v, got %v", i, expLevels[i], rec.levels[i])
+		}
+		if rec.lines[i] != expLines[i] {
+			t.Errorf("[%d] want %q, got %q", i, expLines[i], rec.lines[i])
+		}
+	}
+}
+
+func TestOpenWriter(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Warn(stubLogFormat, 0, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := stubApp + `: <WRN> Test #0 - WARNING log message` + "\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestErrorsOrder(t *testing.T) {
+	const (
+		writers	=	8
+		msgs	=	50
+	)
+
+	file := filepath.Join(tempDir(), "errors-order.log")
+
+	if err := Open(file, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open test log file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	// Capture the stderr duplicates of error messages
+	mirror := &strings.Builder{}
+	stdLog.SetOutput(mirror)
+	defer stdLog.SetOutput(os.Stderr)
+
+	// Write error messages concurrently
+	done := make(chan any)
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			for i := 0; i < msgs; i++ {
+				Err("writer #%d - message #%d", w, i)
+			}
+			done <- nil
+		}(w)
+	}
+	for w := 0; w < writers; w++ {
+		<-done
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close test log file %q: %v", file, err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("cannot read produced file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	if got, want := mirror.String(), string(data); got != want {
+		t.Errorf("order of messages in stderr differs from the log file:\nstderr:\n%s\nlog:\n%s", got, want)
+	}
+
+	if n := strings.Count(string(data), "\n"); n != writers * msgs {
+		t.Errorf("want %d messages in the log file, got %d", writers * msgs, n)
+	}
+}
+
+func TestSkipEmpty(t *testing.T) {
+	file := filepath.Join(tempDir(), "skip-empty.log")
+
+	if err := Open(file, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open test log file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	SkipEmpty(true)
+	Info("")		// empty format
+	Info("%s", "")	// empty result of formatting
+	Info(stubLogFormat, 0, `INFO`)
+
+	SkipEmpty(false)
+	Info("")
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close test log file %q: %v", file, err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("cannot read produced file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" + stubApp + ": \n"
+	if string(data) != want {
+		t.Errorf("want %q, got %q", want, data)
+	}
+}
+
+func TestPause(t *testing.T) {
+	file := filepath.Join(tempDir(), "pause.log")
+
+	if err := Open(file, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open test log file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	Pause()
+	Info(stubLogFormat, 1, `INFO`)
+	Warn(stubLogFormat, 2, `WARNING`)
+	Fatal(stubLogFormat, 3, `FATAL ` + errIsOk)	// must be written even when paused
+	Resume()
+
+	Info(stubLogFormat, 4, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close test log file %q: %v", file, err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("cannot read produced file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" +
+		stubApp + `: <FATAL> Test #3 - FATAL ` + errIsOk + ` log message` + "\n" +
+		stubApp + `: Test #4 - INFO log message` + "\n"
+	if string(data) != want {
+		t.Errorf("want %q, got %q", want, data)
+	}
+
+	if n := Dropped(); n != 2 {
+		t.Errorf("want 2 dropped messages, got %d", n)
+	}
+}
+
+// flakyWriter fails to write while its fail field is set
+type flakyWriter struct {
+	strings.Builder
+	fail bool
+}
+
+var errFlakyWrite = errors.New("flaky writer failure")
+
+func (fw *flakyWriter) Write(p []byte) (int, error) {
+	if fw.fail {
+		return 0, errFlakyWrite
+	}
+
+	return fw.Builder.Write(p)
+}
+
+func TestErrorsChannel(t *testing.T) {
+	target := &flakyWriter{fail: true}
+
+	if err := OpenWriter(target, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	handled := 0
+	SetErrorHandler(func(err error) {
+		handled++
+	})
+	errs := Errors()
+
+	total := errorsBufSize + 5
+	for i := 0; i < total; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	// The oldest errors are dropped, the channel is closed
+	n := 0
+	for err := range errs {
+		if !errors.Is(err, errFlakyWrite) {
+			t.Errorf("want %v, got %v", errFlakyWrite, err)
+		}
+		n++
+	}
+	if n != errorsBufSize {
+		t.Errorf("want %d errors in the channel, got %d", errorsBufSize, n)
+	}
+	if handled != total {
+		t.Errorf("want %d errors passed to the handler, got %d", total, handled)
+	}
+
+	if _, ok := <-Errors(); ok {
+		t.Errorf("want closed channel of errors after closing the log")
+	}
+}
+
+func TestFallbackLogger(t *testing.T) {
+	target, fbBuf := &flakyWriter{fail: true}, &strings.Builder{}
+
+	fb := NewLogger()
+	if err := fb.OpenWriter(fbBuf, "", NoPID); err != nil {
+		t.Errorf("cannot open fallback log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	if err := OpenWriter(target, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	var handled []error
+	SetErrorHandler(func(err error) {
+		handled = append(handled, err)
+	})
+	SetFallback(fb)
+
+	Info(stubLogFormat, 0, `INFO`)
+	Info(stubLogFormat, 1, `INFO`)
+	// The target is not used until reopening
+	target.fail = false
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	Info(stubLogFormat, 3, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+	if err := fb.Close(); err != nil {
+		t.Errorf("cannot close fallback log on the writer: %v", err)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + "\n"
+	if fbBuf.String() != want {
+		t.Errorf("want %q in the fallback log, got %q", want, fbBuf.String())
+	}
+	if want = stubApp + ": " + fmt.Sprintf(stubLogFormat, 3, `INFO`) + "\n"; target.String() != want {
+		t.Errorf("want %q in the log, got %q", want, target.String())
+	}
+
+	// The error of writing and the failover are reported once
+	if len(handled) != 2 || !errors.Is(handled[0], errFlakyWrite) || !errors.Is(handled[1], ErrFailover) {
+		t.Errorf("want %v and %v errors, got %v", errFlakyWrite, ErrFailover, handled)
+	}
+}
+
+func TestEscalationRules(t *testing.T) {
+	buf, mirror := &strings.Builder{}, &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(mirror)
+
+	errs := 0
+	SetStatFuncs(func(format string, v ...any) {
+		errs++
+	}, nil)
+
+	AddEscalationRule(regexp.MustCompile(`out of memory`), LevelFatal)
+	AddEscalationRule(regexp.MustCompile(`timeout`), LevelError)
+	// Never used, the previous rule matches first
+	AddEscalationRule(regexp.MustCompile(`timeout`), LevelWarn)
+	// Levels are not lowered
+	AddEscalationRule(regexp.MustCompile(`disk`), LevelDebug)
+
+	Info("cannot allocate buffer: out of memory")
+	Info("request timeout")
+	Warn("disk is almost full")
+	Info("request done")
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: <FATAL> cannot allocate buffer: out of memory` + "\n" +
+		stubApp + `: <ERR> request timeout` + "\n" +
+		stubApp + `: <WRN> disk is almost full` + "\n" +
+		stubApp + `: request done` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+
+	// Escalated messages are mirrored and counted as errors
+	want = stubApp + `: <FATAL> cannot allocate buffer: out of memory` + "\n" +
+		stubApp + `: <ERR> request timeout` + "\n"
+	if mirror.String() != want {
+		t.Errorf("want %q in the mirror, got %q", want, mirror.String())
+	}
+	if errs != 1 {
+		t.Errorf("want 1 call of the error statistic function, got %d", errs)
+	}
+}
+
+// failingWriter fails the fails number of writes
+type failingWriter struct {
+	strings.Builder
+	fails int
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if fw.fails > 0 {
+		fw.fails--
+		return 0, errFlakyWrite
+	}
+
+	return fw.Builder.Write(p)
+}
+
+func TestWriteRetry(t *testing.T) {
+	target := &failingWriter{}
+
+	opts := DefaultOptions()
+	opts.Writer = target
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.WriteRetries = 2
+	opts.WriteRetryBackoff = time.Millisecond
+
+	errs := 0
+	opts.ErrorHandler = func(err error) {
+		errs++
+	}
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// Written by the last retry
+	target.fails = 2
+	Info(stubLogFormat, 0, `INFO`)
+	// All retries fail
+	target.fails = 3
+	Info(stubLogFormat, 1, `INFO`)
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + "\n"
+	if target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+	if errs != 1 {
+		t.Errorf("want 1 error passed to the handler, got %d", errs)
+	}
+}
+
+func TestFallbackFile(t *testing.T) {
+	fallback := filepath.Join(tempDir(), "fallback.log")
+	target := &flakyWriter{}
+
+	if err := OpenWriter(target, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// Collect write errors
+	errs := []error{}
+	SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	SetFallbackFile(fallback)
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	// Target becomes unavailable
+	target.fail = true
+	Info(stubLogFormat, 1, `INFO`)
+	Warn(stubLogFormat, 2, `WARNING`)
+
+	// Replay has to fail because the target is still unavailable
+	if err := ReplayFallback(); !errors.Is(err, errFlakyWrite) {
+		t.Errorf("ReplayFallback() returned %v, want - %v", err, errFlakyWrite)
+	}
+
+	// Target becomes available again
+	target.fail = false
+	if err := ReplayFallback(); err != nil {
+		t.Errorf("cannot replay fallback messages: %v", err)
+	}
+	Info(stubLogFormat, 3, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" +
+		stubApp + `: Test #1 - INFO log message` + "\n" +
+		stubApp + `: <WRN> Test #2 - WARNING log message` + "\n" +
+		stubApp + `: Test #3 - INFO log message` + "\n"
+	if target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+
+	// Both failed writes have to be reported
+	if len(errs) != 2 {
+		t.Errorf("want 2 write errors, got %d: %v", len(errs), errs)
+	}
+
+	// Fallback file has to be removed after successful replay
+	if _, err := os.Stat(fallback); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("fallback file %q was not removed after replay: %v", fallback, err)
+	}
+}
+
+func TestBarrier(t *testing.T) {
+	file := filepath.Join(tempDir(), "barrier.log")
+
+	if err := Open(file, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open test log file %q: %v", file, err)
+		t.FailNow()
+	}
+	defer func() {
+		if err := Close(); err != nil {
+			t.Errorf("cannot close test log file %q: %v", file, err)
+		}
+	}()
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	if err := Barrier(); err != nil {
+		t.Errorf("Barrier() failed: %v", err)
+	}
+
+	// The message has to be in the file, which is still open
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("cannot read produced file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	if want := stubApp + `: Test #0 - INFO log message` + "\n"; string(data) != want {
+		t.Errorf("want %q, got %q", want, data)
+	}
+}
+
+func TestSyncMode(t *testing.T) {
+	const (
+		writers	=	4
+		msgs	=	25
+	)
+
+	file := filepath.Join(tempDir(), "sync-mode.log")
+
+	if err := OpenSync(file, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open test log file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen test log file %q: %v", file, err)
+	}
+
+	// Write messages concurrently
+	done := make(chan any)
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			for i := 0; i < msgs; i++ {
+				Warn("writer #%d - message #%d", w, i)
+			}
+			done <- nil
+		}(w)
+	}
+	for w := 0; w < writers; w++ {
+		<-done
+	}
+
+	// The file is read without closing the log, all messages have to be already written
+	Err(stubLogFormat, 1, `ERROR ` + errIsOk)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("cannot read produced file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if n := len(lines); n != writers * msgs + 2 {
+		t.Errorf("want %d messages in the log file, got %d", writers * msgs + 2, n)
+		t.FailNow()
+	}
+
+	if want := stubApp + `: Test #0 - INFO log message`; lines[0] != want {
+		t.Errorf("want %q, got %q", want, lines[0])
+	}
+	if want := stubApp + `: <ERR> Test #1 - ERROR ` + errIsOk + ` log message`; lines[len(lines)-1] != want {
+		t.Errorf("want %q, got %q", want, lines[len(lines)-1])
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close test log file %q: %v", file, err)
+	}
+}
+
+func TestFields(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	at := time.Date(2022, time.November, 8, 14, 50, 53, 123456789, time.UTC)
+	child := With("user", "John Doe", Dur("took", 1500 * time.Millisecond), Time("at", at), 42)
+
+	// Configuration is shared with children
+	SetDebug(true)
+	child.Debug(stubLogFormat, 0, `DEBUG`)
+
+	SetDurationFormat(DurationNanos)
+	SetTimeFormat(time.RFC3339)
+	child.With("attempt", 2).Info(stubLogFormat, 1, `INFO`)
+
+	// Parent writes messages without fields
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: <D> Test #0 - DEBUG log message` +
+			` user="John Doe" took=1.5s at=2022-11-08T14:50:53.123Z !BADKEY=42` + "\n" +
+		stubApp + `: Test #1 - INFO log message` +
+			` user="John Doe" took=1500000000 at=2022-11-08T14:50:53Z !BADKEY=42 attempt=2` + "\n" +
+		stubApp + `: Test #2 - INFO log message` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	InternStrings("GET", "POST")
+	status, method := 404, strings.ToUpper("get")
+
+	// Common values do not allocate, only the slice of fields and the logger do
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = WithFields(Int("status", status), Str("method", method))
+	})
+	if allocs > 2 {
+		t.Errorf("want no more than 2 allocations, got %v", allocs)
+	}
+
+	WithFields(Int("status", status), Str("method", method), Int("size", 1 << 20)).
+		With("attempt", 2).Info(stubLogFormat, 0, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: Test #0 - INFO log message status=404 method=GET size=1048576 attempt=2` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func BenchmarkWith(b *testing.B) {
+	l := NewLogger()
+	status, method := 404, strings.ToUpper("get")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = l.With("status", status, "method", method)
+	}
+}
+
+func BenchmarkWithFields(b *testing.B) {
+	l := NewLogger()
+	InternStrings("GET")
+	status, method := 404, strings.ToUpper("get")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = l.WithFields(Int("status", status), Str("method", method))
+	}
+}
+
+// rotatingWriter implements Rotator, rotation starts a new buffer
+type rotatingWriter struct {
+	bufs []*strings.Builder
+}
+
+func (rw *rotatingWriter) Write(p []byte) (int, error) {
+	if len(rw.bufs) == 0 {
+		rw.bufs = append(rw.bufs, &strings.Builder{})
+	}
+
+	return rw.bufs[len(rw.bufs)-1].Write(p)
+}
+
+func (rw *rotatingWriter) Rotate() error {
+	rw.bufs = append(rw.bufs, &strings.Builder{})
+	return nil
+}
+
+func TestRotator(t *testing.T) {
+	rw := &rotatingWriter{}
+
+	if err := OpenWriter(rw, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log on the rotating writer: %v", err)
+	}
+
+	Info(stubLogFormat, 1, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := []string{
+		stubApp + `: Test #0 - INFO log message` + "\n",
+		stubApp + `: Test #1 - INFO log message` + "\n",
+	}
+	if len(rw.bufs) != len(want) {
+		t.Errorf("want %d rotated parts, got %d", len(want), len(rw.bufs))
+		t.FailNow()
+	}
+	for i, buf := range rw.bufs {
+		if buf.String() != want[i] {
+			t.Errorf("[%d] want %q, got %q", i, want[i], buf.String())
+		}
+	}
+}
+
+// slowWriter sleeps before each write
+type slowWriter struct {
+	strings.Builder
+	delay time.Duration
+}
+
+func (sw *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(sw.delay)
+	return sw.Builder.Write(p)
+}
+
+// blockedWriter blocks writing until the release channel is closed
+type blockedWriter struct {
+	strings.Builder
+	started	chan struct{}
+	release	chan struct{}
+}
+
+func (bw *blockedWriter) Write(p []byte) (int, error) {
+	select {
+	case bw.started <- struct{}{}:
+	default:
+	}
+	<-bw.release
+
+	return bw.Builder.Write(p)
+}
+
+func TestSpillToDisk(t *testing.T) {
+	dir := tempDir()
+	target := &blockedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	opts := DefaultOptions()
+	opts.Writer = target
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.ConfirmWrites = false
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	if err := SpillToDisk(dir); err != nil {
+		t.Errorf("cannot enable spilling to disk: %v", err)
+	}
+
+	// The writer goroutine is blocked by the first message, the rest are spilled
+	const total = 10
+	Info(stubLogFormat, 0, `INFO`)
+	<-target.started
+	for i := 1; i < total; i++ {
+		With("n", i).Info(stubLogFormat, i, `INFO`)
+	}
+
+	spooled, err := filepath.Glob(filepath.Join(dir, "log-spool-*"))
+	if err != nil || len(spooled) != 1 {
+		t.Errorf("want 1 spool file, got %v (err: %v)", spooled, err)
+		t.FailNow()
+	}
+	if fi, err := os.Stat(spooled[0]); err != nil || fi.Size() == 0 {
+		t.Errorf("want non-empty spool file, got %v (err: %v)", fi, err)
+	}
+
+	close(target.release)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n"
+	for i := 1; i < total; i++ {
+		want += stubApp + ": " + fmt.Sprintf(stubLogFormat, i, `INFO`) + " n=" + strconv.Itoa(i) + "\n"
+	}
+	if target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+
+	if _, err := os.Stat(spooled[0]); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want removed spool file, got %v", err)
+	}
+}
+
+func TestSpillOrder(t *testing.T) {
+	mfs := &memFS{files: fstest.MapFS{}}
+	target := &blockedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	opts := DefaultOptions()
+	opts.Writer = target
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.ConfirmWrites = false
+	opts.FS = mfs
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	if err := SpillToDisk(""); err != nil {
+		t.Errorf("cannot enable spilling to disk: %v", err)
+	}
+
+	// The writer goroutine is blocked by the first message, the next ones are spilled
+	Info(stubLogFormat, 0, `INFO`)
+	<-target.started
+	for i := 1; i < 4; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+
+	// The message that cannot be spilled waits for the writer goroutine
+	mfs.tempFail = true
+	go Info(stubLogFormat, 4, `INFO`)
+	for Metrics().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(target.release)
+	for Metrics().Queued != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := ""
+	for i := 0; i < 5; i++ {
+		want += stubApp + ": " + fmt.Sprintf(stubLogFormat, i, `INFO`) + "\n"
+	}
+	if target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+}
+
+func TestSpillRaw(t *testing.T) {
+	target := &blockedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	opts := DefaultOptions()
+	opts.Writer = target
+	opts.ConfirmWrites = false
+	errs := []error{}
+	opts.ErrorHandler = func(err error) { errs = append(errs, err) }
+
+	openWithStubOptions(t, opts)
+	if err := SpillToDisk(tempDir()); err != nil {
+		t.Errorf("cannot enable spilling to disk: %v", err)
+	}
+
+	// The writer goroutine is blocked by the first message, the next ones are spilled
+	Raw(LevelInfo, []byte(`{"raw":0}`))
+	<-target.started
+	Raw(LevelInfo, []byte(`{"raw":1}`))
+	Tmpl(LevelInfo, "user {user} logged in", nil)
+	Raw(LevelInfo, []byte(`{"raw":2}`))
+
+	close(target.release)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := `{"raw":0}` + "\n" + `{"raw":1}` + "\n" +
+		stubApp + `: user {user} logged in` + "\n" + `{"raw":2}` + "\n"
+	if target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrBadTemplate) {
+		t.Errorf("want the ErrBadTemplate warning of the spilled message, got %v", errs)
+	}
+}
+
+func TestSpillConcurrent(t *testing.T) {
+	dir := tempDir()
+	target := &slowWriter{delay: 100 * time.Microsecond}
+
+	opts := DefaultOptions()
+	opts.Writer = target
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.ConfirmWrites = false
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// Messages are spilled while the spool is replaced
+	const writers, total = 4, 50
+	wg := sync.WaitGroup{}
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < total; i++ {
+				With("w", w).Info(stubLogFormat, i, `INFO`)
+			}
+		}(w)
+	}
+	for i := 0; i < 5; i++ {
+		if err := SpillToDisk(dir); err != nil {
+			t.Errorf("cannot enable spilling to disk: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	// Messages of each writer are written in order
+	next := make([]int, writers)
+	for _, line := range strings.Split(strings.TrimSuffix(target.String(), "\n"), "\n") {
+		var i, w int
+		if _, err := fmt.Sscanf(line, stubApp + ": Test #%d - INFO log message w=%d", &i, &w); err != nil {
+			t.Errorf("unexpected line %q: %v", line, err)
+			continue
+		}
+		if i != next[w] {
+			t.Errorf("writer %d: want message #%d, got #%d", w, next[w], i)
+		}
+		next[w] = i + 1
+	}
+	for w, n := range next {
+		if n != total {
+			t.Errorf("writer %d: want %d messages, got %d", w, total, n)
+		}
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	dir := tempDir()
+	target := &blockedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	opts := DefaultOptions()
+	opts.Writer = target
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.ConfirmWrites = false
+	opts.DrainTimeout = 50 * time.Millisecond
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	if err := SpillToDisk(dir); err != nil {
+		t.Errorf("cannot enable spilling to disk: %v", err)
+	}
+
+	// The writer goroutine is blocked by the first message, the rest are spilled
+	const total = 6
+	Info(stubLogFormat, 0, `INFO`)
+	<-target.started
+	for i := 1; i < total; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+
+	// The channel of errors is closed when closing is finished
+	errs := Errors()
+
+	err := Close()
+	if !errors.Is(err, ErrDrainTimeout) || !strings.Contains(err.Error(), strconv.Itoa(total - 1) + " queued messages") {
+		t.Errorf("want %v with %d abandoned messages, got %v", ErrDrainTimeout, total - 1, err)
+	}
+
+	// Closing is finished after the blocked write
+	close(target.release)
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Errorf("closing is not finished after releasing the writer")
+		t.FailNow()
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "log-spool-*")); len(files) != 0 {
+		t.Errorf("want removed spool file, got %v", files)
+	}
+	if want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n"; target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+	if n := Dropped(); n != total - 1 {
+		t.Errorf("want %d dropped messages, got %d", total - 1, n)
+	}
+}
+
+func TestReopenContext(t *testing.T) {
+	target := &blockedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	opts := DefaultOptions()
+	opts.Writer = target
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.ConfirmWrites = false
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// The writer goroutine is blocked by the message
+	Info(stubLogFormat, 0, `INFO`)
+	<-target.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Millisecond)
+	defer cancel()
+	if err := ReopenContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// Writing continues to the same target
+	close(target.release)
+	Info(stubLogFormat, 1, `INFO`)
+	if err := ReopenContext(context.Background()); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + "\n"
+	if target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+}
+
+func TestWriteLatency(t *testing.T) {
+	const delay = 5 * time.Millisecond
+
+	if err := OpenWriter(&slowWriter{delay: delay}, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	if avg, max := WriteLatency(); avg != 0 || max != 0 {
+		t.Errorf("want zero latency before writes, got avg %v, max %v", avg, max)
+	}
+
+	for i := 0; i < 3; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	avg, max := WriteLatency()
+	if avg < delay || max < delay {
+		t.Errorf("want latency not less than %v, got avg %v, max %v", delay, avg, max)
+	}
+	if avg > max {
+		t.Errorf("average latency %v is greater than maximum %v", avg, max)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	// Debug messages before Open must not cause panic
+	logger = nil
+	D(stubLogFormat, 0, `DEBUG`)
+	Debug(stubLogFormat, 0, `DEBUG`)
+
+	file := filepath.Join(tempDir(), "levels.log")
+
+	SetInitialLevel(LevelWarn)
+	defer SetInitialLevel(LevelInfo)
+
+	if err := Open(file, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open test log file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	Debug(stubLogFormat, 1, `DEBUG`)
+	Info(stubLogFormat, 2, `INFO`)
+	Warn(stubLogFormat, 3, `WARNING`)
+
+	SetLevel(LevelDebug)
+	Debug(stubLogFormat, 4, `DEBUG`)
+
+	// Debug functions of the package are disabled
+	DebugBuild = false
+	Debug(stubLogFormat, 5, `DEBUG`)
+	DebugBuild = true
+
+	SetLevel(LevelError)
+	Warn(stubLogFormat, 6, `WARNING`)
+	Fatal(stubLogFormat, 7, `FATAL ` + errIsOk)	// fatal messages are always written
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close test log file %q: %v", file, err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("cannot read produced file %q: %v", file, err)
+		t.FailNow()
+	}
+
+	want := stubApp + `: <WRN> Test #3 - WARNING log message` + "\n" +
+		stubApp + `: <D> Test #4 - DEBUG log message` + "\n" +
+		stubApp + `: <FATAL> Test #7 - FATAL ` + errIsOk + ` log message` + "\n"
+	if string(data) != want {
+		t.Errorf("want %q, got %q", want, data)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	rec := &levelRecorder{}
+
+	if err := OpenWriter(rec, stubApp, NoFlags); err != nil {
+		t.Errorf("cannot open log on the level writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+	Raw(LevelWarn, []byte(`{"event":"login"}`))
+	Raw(LevelDebug, []byte(`{"event":"filtered"}`))	// filtered by the level
+	Raw(LevelError, []byte(`{"event":"logout"}` + "\n"))
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the level writer: %v", err)
+	}
+
+	expLevels := []Level{LevelInfo, LevelWarn, LevelError}
+	expLines := []string{
+		stubApp + `[` + strconv.Itoa(os.Getpid()) + `]: Test #0 - INFO log message` + "\n",
+		`{"event":"login"}` + "\n",
+		`{"event":"logout"}` + "\n",
+	}
+
+	if len(rec.lines) != len(expLines) {
+		t.Errorf("want %d messages, got %d: %#v", len(expLines), len(rec.lines), rec.lines)
+		t.FailNow()
+	}
+
+	for i := range expLines {
+		if rec.levels[i] != expLevels[i] {
+			t.Errorf("[%d] want level %v, got %v", i, expLevels[i], rec.levels[i])
+		}
+		if rec.lines[i] != expLines[i] {
+			t.Errorf("[%d] want %q, got %q", i, expLines[i], rec.lines[i])
+		}
+	}
+}
+
+func TestStackDedup(t *testing.T) {
+	const (
+		stack1 = "goroutine 1 [running]:\nmain.f()\n\t/src/main.go:10"
+		stack2 = "goroutine 1 [running]:\nmain.g()\n\t/src/main.go:20"
+	)
+
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetStackDedup(true)
+	Warn("panic #%d recovered\n%s", 0, stack1)
+	Warn("panic #%d recovered\n%s", 1, stack1)
+	Warn("panic #%d recovered\n%s", 2, stack2)
+	Warn("panic #%d recovered\n%s", 3, stack1)
+	Warn("single line message")
+
+	SetStackDedup(false)
+	Warn("panic #%d recovered\n%s", 4, stack1)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": <WRN> panic #0 recovered\n(stack #1)\n" + stack1 + "\n" +
+		stubApp + ": <WRN> panic #1 recovered\n(stack identical to #1)\n" +
+		stubApp + ": <WRN> panic #2 recovered\n(stack #2)\n" + stack2 + "\n" +
+		stubApp + ": <WRN> panic #3 recovered\n(stack identical to #1)\n" +
+		stubApp + ": <WRN> single line message\n" +
+		stubApp + ": <WRN> panic #4 recovered\n" + stack1 + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	logDir := tempDir()
+	logFile := filepath.Join(logDir, "validate.log")
+
+	opts := DefaultOptions()
+	opts.File = logFile
+	opts.RotationAge = time.Hour
+	opts.FallbackFile = filepath.Join(logDir, "fallback.log")
+	if err := Validate(opts); err != nil {
+		t.Errorf("valid options are rejected: %v", err)
+	}
+	// Validation does not create files
+	if _, err := os.Stat(logFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want not existing log file after validation, got %v", err)
+	}
+
+	opts = DefaultOptions()
+	opts.File = filepath.Join(logDir, "this-dir-does-not-exist", "validate.log")
+	opts.Flags = NoPID | 1 << 20
+	opts.OutputFormat = FormatJSON + 1
+	opts.WriteRetries = -1
+	opts.CompressLive = true
+
+	err := Validate(opts)
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Errorf("want %T, got %v", ve, err)
+		t.FailNow()
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v in %v", fs.ErrNotExist, err)
+	}
+	if len(ve.Errs) != 4 {
+		t.Errorf("want 4 problems, got %d: %v", len(ve.Errs), err)
+	}
+
+	// Rotation requires the log file
+	opts = DefaultOptions()
+	opts.Writer = io.Discard
+	opts.RotationAge = time.Hour
+	if err := Validate(opts); !errors.As(err, &ve) || len(ve.Errs) != 1 {
+		t.Errorf("want 1 problem, got %v", err)
+	}
+
+	// Files of custom file systems are not checked on the disk
+	opts = DefaultOptions()
+	opts.File = "app.log"
+	opts.FallbackFile = filepath.Join(logDir, "this-dir-does-not-exist", "fallback.log")
+	opts.FS = &memFS{files: fstest.MapFS{}}
+	if err := Validate(opts); err != nil {
+		t.Errorf("valid options of the custom file system are rejected: %v", err)
+	}
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	buf := &strings.Builder{}
+
+	wrns := []string{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Level = LevelDebug
+	opts.SkipEmpty = true
+	opts.DurationFormat = DurationNanos
+	opts.WrnStat = func(format string, args ...any) {
+		wrns = append(wrns, fmt.Sprintf(format, args...))
+	}
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log with options: %v", err)
+		t.FailNow()
+	}
+
+	D(stubLogFormat, 0, `DEBUG`)
+	Info("")
+	With(Dur("took", time.Second)).Warn(stubLogFormat, 1, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: <D> Test #0 - DEBUG log message` + "\n" +
+		stubApp + `: <WRN> Test #1 - WARNING log message took=1000000000` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+
+	checkStatTestResults(t, wrns, []string{fmt.Sprintf(stubLogFormat, 1, `WARNING`)})
+}
+
+func TestOpenWithZeroOptions(t *testing.T) {
+	buf := &strings.Builder{}
+
+	exitCode, bursts := 0, 0
+	at := time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC)
+
+	// Zero values of options which are not set keep defaults or disable settings
+	opts := Options{
+		Writer:				buf,
+		Prefix:				stubApp,
+		Flags:				NoPID,
+		PrefixSeparator:	DefaultPrefixSeparator,
+		MaxMessageBytes:	16,
+		LevelTags:			map[Level]string{LevelWarn: "<WARN>"},
+		EntryProcessor:		func(e *Entry) { e.Fields = append(e.Fields, Str("env", "test")) },
+		ErrorBurstQuiet:	time.Minute,
+		ErrorBurstFunc:		func() { bursts++ },
+		ExitFunc:			func(code int) { exitCode = code },
+	}
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log with options: %v", err)
+		t.FailNow()
+	}
+
+	With(Time("at", at)).Warn(stubLogFormat, 0, `WARNING`)
+	Err("failed")
+	Fatal("stopped")
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: <WARN> Test #0 - WARNIN…(truncated 13 bytes) at=2024-05-01T10:20:30.000Z env=test` + "\n" +
+		stubApp + `: <ERR> failed env=test` + "\n" +
+		stubApp + `: <FATAL> stopped env=test` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+	if exitCode != 1 {
+		t.Errorf("want exit code 1, got %d", exitCode)
+	}
+	if bursts != 1 {
+		t.Errorf("want 1 burst of errors, got %d", bursts)
+	}
+}
+
+func TestWouldLog(t *testing.T) {
+	if err := Open(os.DevNull, stubApp, NoFlags); err != nil {
+		t.Errorf("cannot open output file %q: %v", os.DevNull, err)
+		t.FailNow()
+	}
+
+	tests := []struct {
+		set		func()
+		level	Level
+		want	bool
+	}{
+		{func() {}, LevelDebug, false},
+		{func() {}, LevelInfo, true},
+		{func() { SetDebug(true) }, LevelDebug, true},
+		{func() { SetLevel(LevelError) }, LevelWarn, false},
+		{func() {}, LevelError, true},
+		{func() {}, LevelFatal, true},
+		{Pause, LevelError, false},
+		{func() {}, LevelFatal, true},
+		{Resume, LevelError, true},
+	}
+
+	for i, test := range tests {
+		test.set()
+		if got := WouldLog(test.level); got != test.want {
+			t.Errorf("[%d] WouldLog(%v) = %t, want %t", i, test.level, got, test.want)
+		}
+		if got := logger.WouldLog(test.level); got != test.want {
+			t.Errorf("[%d] Logger.WouldLog(%v) = %t, want %t", i, test.level, got, test.want)
+		}
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close test log file %q: %v", os.DevNull, err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	buf, cloneBuf := &strings.Builder{}, &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetLevel(LevelWarn)
+	child := With("user", "root")
+
+	// Hooks and other settings are kept by the clone
+	exits, bursts := 0, 0
+	SetEntryProcessor(func(e *Entry) { e.Fields = append(e.Fields, Str("env", "test")) })
+	SetExitFunc(func(int) { exits++ })
+	SetErrorBurstCallback(time.Minute, func() { bursts++ })
+	AddEscalationRule(regexp.MustCompile(`ESCALATED`), LevelError)
+	SetColors(true)
+	SetLevelColor(LevelWarn, ColorCyan)
+	SetFlushThreshold(3)
+	SetAdaptiveSampling(1000)
+
+	clone := child.Clone()
+	if err := clone.OpenWriter(cloneBuf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open cloned log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// Configuration of the clone is independent from the original logger
+	SetLevel(LevelDebug)
+	SetLevelColor(LevelWarn, ColorRed)
+
+	child.Info(stubLogFormat, 0, `INFO`)
+	clone.Info(stubLogFormat, 0, `INFO`)	// filtered by the cloned level
+	clone.Warn(stubLogFormat, 1, `WARNING`)
+	clone.Warn(stubLogFormat, 2, `ESCALATED ` + errIsOk)
+	clone.Fatal(stubLogFormat, 3, `FATAL ` + errIsOk)
+
+	switch {
+	case !clone.colors || clone.levelColors[LevelWarn] != ColorCyan:
+		t.Errorf("want cyan warnings of the clone, got colors %t, %v", clone.colors, clone.levelColors)
+	case clone.flushThreshold != 3:
+		t.Errorf("want the flush threshold 3 of the clone, got %d", clone.flushThreshold)
+	case clone.adaptive == nil || clone.adaptive == logger.adaptive || clone.adaptive.target != 1000:
+		t.Errorf("want the own adaptive sampler of the clone, got %+v", clone.adaptive)
+	}
+
+	if err := clone.Close(); err != nil {
+		t.Errorf("cannot close cloned log: %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := stubApp + `: Test #0 - INFO log message user=root env=test` + "\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+	want := stubApp + `: <WRN> Test #1 - WARNING log message user=root env=test` + "\n" +
+		stubApp + `: <ERR> Test #2 - ESCALATED ` + errIsOk + ` log message user=root env=test` + "\n" +
+		stubApp + `: <FATAL> Test #3 - FATAL ` + errIsOk + ` log message user=root env=test` + "\n"
+	if cloneBuf.String() != want {
+		t.Errorf("want %q, got %q", want, cloneBuf.String())
+	}
+	if exits != 1 || bursts != 1 {
+		t.Errorf("want 1 exit and 1 burst of errors, got %d and %d", exits, bursts)
+	}
+}
+
+func TestCloneSetters(t *testing.T) {
+	buf, cloneBuf := &strings.Builder{}, &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// The clone is not opened yet, so setters must not wait for its writer goroutine
+	clone := logger.Clone()
+	notBlocked(t, "configuration of the clone", func() {
+		clone.SetVersion("1.2.3")
+		clone.SetLevelTag(LevelInfo, "<I>")
+		clone.SetFieldOrder([]string{"user"})
+	})
+
+	if err := clone.OpenWriter(cloneBuf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open cloned log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+	clone.With("id", 1, "user", "root").Info(stubLogFormat, 0, `INFO`)
+
+	if err := clone.Close(); err != nil {
+		t.Errorf("cannot close cloned log: %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := stubApp + `: Test #0 - INFO log message` + "\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+	if want := stubApp + `: {1.2.3} <I> Test #0 - INFO log message user=root id=1` + "\n"; cloneBuf.String() != want {
+		t.Errorf("want %q, got %q", want, cloneBuf.String())
+	}
+}
+
+// openWithStubOptions opens the log with options, using the stub prefix and no PID
+func openWithStubOptions(t *testing.T, opts Options) {
+	t.Helper()
+
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log with options: %v", err)
+		t.FailNow()
+	}
+}
+
+// checkLogFiles checks contents of log files
+func checkLogFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	for file, want := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Errorf("cannot read log file: %v", err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("%s: want %q, got %q", file, want, string(data))
+		}
+	}
+}
+
+func TestRotationAge(t *testing.T) {
+	dir := tempDir()
+	logFile := filepath.Join(dir, "rotation-age.log")
+	backup := filepath.Join(dir, "rotation-age-2022-11-08.log")
+
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.File = logFile
+	opts.RotationAge = 24 * time.Hour
+	opts.Clock = func() time.Time { return now }
+
+	openWithStubOptions(t, opts)
+
+	Info(stubLogFormat, 0, `INFO`)
+	Barrier()
+
+	// The file is not old enough yet
+	now = now.Add(23 * time.Hour)
+	Info(stubLogFormat, 1, `INFO`)
+	Barrier()
+
+	// The file has to be rotated before this message
+	now = now.Add(time.Hour)
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	checkLogFiles(t, map[string]string{
+		backup:  stubApp + `: Test #0 - INFO log message` + "\n" + stubApp + `: Test #1 - INFO log message` + "\n",
+		logFile: stubApp + `: Test #2 - INFO log message` + "\n",
+	})
+}
+
+func TestReopenRotationAge(t *testing.T) {
+	dir := tempDir()
+	logFile := filepath.Join(dir, "reopen-age.log")
+	renamed := logFile + ".1"
+
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.File = logFile
+	opts.RotationAge = 24 * time.Hour
+	opts.Clock = func() time.Time { return now }
+
+	openWithStubOptions(t, opts)
+
+	Info(stubLogFormat, 0, `INFO`)
+	Barrier()
+
+	// Rotate the file like logrotate does
+	now = now.Add(20 * time.Hour)
+	if err := os.Rename(logFile, renamed); err != nil {
+		t.Errorf("cannot rename log file: %v", err)
+		t.FailNow()
+	}
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log file: %v", err)
+	}
+
+	// The age of the new file is counted from its first write
+	Info(stubLogFormat, 1, `INFO`)
+	Barrier()
+	now = now.Add(6 * time.Hour)
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	checkLogFiles(t, map[string]string{
+		renamed: stubApp + `: Test #0 - INFO log message` + "\n",
+		logFile: stubApp + `: Test #1 - INFO log message` + "\n" + stubApp + `: Test #2 - INFO log message` + "\n",
+	})
+}
+
+func TestPrefixSeparator(t *testing.T) {
+	buf := &strings.Builder{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.PrefixSeparator = " - "
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	// Empty separator is replaced by a single space
+	SetPrefixSeparator("")
+	if err := SetFlags(NoPID); err != nil {
+		t.Errorf("cannot set flags: %v", err)
+	}
+	Info(stubLogFormat, 1, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ` - Test #0 - INFO log message` + "\n" + stubApp + ` Test #1 - INFO log message` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestTraceExtractor(t *testing.T) {
+	type spanKey struct{}
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetTraceExtractor(func(ctx context.Context) (string, string, bool) {
+		span, ok := ctx.Value(spanKey{}).(string)
+		return "t-" + span, "s-" + span, ok
+	})
+
+	InfoCtx(context.Background(), stubLogFormat, 0, `INFO`)
+	WarnCtx(context.WithValue(context.Background(), spanKey{}, "1"), stubLogFormat, 1, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" +
+		stubApp + `: <WRN> Test #1 - WARNING log message trace_id=t-1 span_id=s-1` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestMustOpen(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "this-dir-does-not-exist", "must-open.log")
+
+	defer func() {
+		var fe *FileError
+		err, ok := recover().(error)
+		if !ok || !errors.As(err, &fe) {
+			t.Errorf("want panic with *FileError, got %v", err)
+		}
+	}()
+
+	MustOpen(logFile, stubApp, NoPID)
+
+	t.Errorf("MustOpen did not panic on non-existing directory")
+}
+
+func TestInfoAt(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID | stdLog.Ldate | stdLog.Ltime); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetTimeFormat(time.RFC3339)
+	InfoAt(time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC), stubLogFormat, 0, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := `2022-11-08T10:00:00Z ` + stubApp + `: Test #0 - INFO log message` + "\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+// readCompressedLog returns data of the compressed log file, that may be not closed yet
+func readCompressedLog(t *testing.T, logFile string) string {
+	t.Helper()
+
+	fd, err := os.Open(logFile)
+	if err != nil {
+		t.Errorf("cannot open compressed log: %v", err)
+		return ""
+	}
+	defer fd.Close()
+
+	gz, err := gzip.NewReader(fd)
+	if err != nil {
+		t.Errorf("cannot read compressed log: %v", err)
+		return ""
+	}
+
+	// The gzip footer is not written until closing, ignore unexpected EOF
+	data, err := io.ReadAll(gz)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("cannot read compressed log: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestCompressLive(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "compress-live.log.gz")
+
+	if err := Open(logFile, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+	Barrier()
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n"
+	if got := readCompressedLog(t, logFile); got != want {
+		t.Errorf("after Barrier: want %q, got %q", want, got)
+	}
+
+	Warn(stubLogFormat, 1, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	want += stubApp + `: <WRN> Test #1 - WARNING log message` + "\n"
+	if got := readCompressedLog(t, logFile); got != want {
+		t.Errorf("after Close: want %q, got %q", want, got)
+	}
+}
+
+func TestCompressFatal(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "compress-fatal.log.gz")
+
+	if err := Open(logFile, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	// Compressed data must be written before terminating the program
+	got := ""
+	SetExitFunc(func(int) { got = readCompressedLog(t, logFile) })
+
+	Info(stubLogFormat, 0, `INFO`)
+	Fatal(stubLogFormat, 1, `FATAL ` + errIsOk)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" +
+		stubApp + `: <FATAL> Test #1 - FATAL ` + errIsOk + ` log message` + "\n"
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestComponent(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	db := Component("db").With("table", "users")
+	db.Warn(stubLogFormat, 0, `WARNING`)
+	db.Component("cache").Info(stubLogFormat, 1, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: [db] <WRN> Test #0 - WARNING log message table=users` + "\n" +
+		stubApp + `: [cache] Test #1 - INFO log message table=users` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestStrictFormat(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	errs := []error{}
+	SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	SetStrictFormat(true)
+
+	badFormat := "Test #%d - %d"
+	Info(stubLogFormat, 0, `INFO`)
+	Info(badFormat, 1, "not-a-number")
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if len(errs) != 1 {
+		t.Errorf("want 1 error, got %d: %v", len(errs), errs)
+		t.FailNow()
+	}
+	if !errors.Is(errs[0], ErrBadFormat) || !strings.Contains(errs[0].Error(), strconv.Quote(badFormat)) {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+}
+
+func TestMirrorWriter(t *testing.T) {
+	buf, mirror := &strings.Builder{}, &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetMirrorWriter(mirror)
+
+	Info(stubLogFormat, 0, `INFO`)
+	Err(stubLogFormat, 1, `ERROR ` + errIsOk)
+	Warn(stubLogFormat, 2, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := stubApp + `: <ERR> Test #1 - ERROR ` + errIsOk + ` log message` + "\n"; mirror.String() != want {
+		t.Errorf("mirror: want %q, got %q", want, mirror.String())
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("want 3 lines in the log, got %d: %q", n, buf.String())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	buf := &strings.Builder{}
+
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Clock = func() time.Time { return now }
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetRateLimit(LevelWarn, 2, time.Second)
+	SetRateLimit(LevelFatal, 1, time.Second)	// ignored
+
+	for i := 0; i < 5; i++ {
+		Warn(stubLogFormat, i, `WARNING`)
+		Info(stubLogFormat, i, `INFO`)
+	}
+
+	// One token is returned to the bucket
+	now = now.Add(time.Second / 2)
+	Warn(stubLogFormat, 5, `WARNING`)
+	Warn(stubLogFormat, 6, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if n := strings.Count(buf.String(), "<WRN>"); n != 3 {
+		t.Errorf("want 3 warnings, got %d: %q", n, buf.String())
+	}
+	if n := strings.Count(buf.String(), "INFO"); n != 5 {
+		t.Errorf("want 5 information messages, got %d: %q", n, buf.String())
+	}
+	if n := RateLimited(LevelWarn); n != 4 {
+		t.Errorf("want 4 rate limited warnings, got %d", n)
+	}
+	if n := RateLimited(LevelInfo); n != 0 {
+		t.Errorf("want no rate limited information messages, got %d", n)
+	}
+}
+
+func TestSamplingKey(t *testing.T) {
+	buf := &strings.Builder{}
+
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Clock = func() time.Time { return now }
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetRateLimit(LevelWarn, 1, time.Second)
+	SetSamplingKey(func(e Entry) string {
+		for _, f := range e.Fields {
+			if f.Key == "user" {
+				return fmt.Sprint(f.Value)
+			}
+		}
+		return ""
+	})
+
+	noisy, quiet := With("user", "noisy"), With("user", "quiet")
+	for i := 0; i < 5; i++ {
+		noisy.Warn(stubLogFormat, i, `WARNING`)
+	}
+	quiet.Warn(stubLogFormat, 5, `WARNING`)
+	Warn(stubLogFormat, 6, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	for _, user := range []string{"user=noisy", "user=quiet"} {
+		if n := strings.Count(buf.String(), user); n != 1 {
+			t.Errorf("want 1 warning with %s, got %d: %q", user, n, buf.String())
+		}
+	}
+	if n := strings.Count(buf.String(), "<WRN>"); n != 3 {
+		t.Errorf("want 3 warnings, got %d: %q", n, buf.String())
+	}
+	if n := RateLimited(LevelWarn); n != 4 {
+		t.Errorf("want 4 rate limited warnings, got %d", n)
+	}
+}
+
+func TestSyncOnReopen(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "sync-on-reopen.log")
+
+	if err := Open(logFile, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log file with syncing: %v", err)
+	}
+
+	SetSyncOnReopen(false)
+	Info(stubLogFormat, 1, `INFO`)
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log file without syncing: %v", err)
+	}
+
+	Info(stubLogFormat, 2, `INFO`)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Errorf("cannot read log file: %v", err)
+		t.FailNow()
+	}
+	if n := strings.Count(string(data), "INFO"); n != 3 {
+		t.Errorf("want 3 messages in the log, got %d: %q", n, string(data))
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	entries, unsubscribe := Subscribe(2)
+	_, unsubscribeSlow := Subscribe(0)	// never read
+
+	With("user", "root").Warn(stubLogFormat, 0, `WARNING`)
+	Info(stubLogFormat, 1, `INFO`)
+	Barrier()
+
+	unsubscribe()
+	unsubscribe()	// must be safe
+	unsubscribeSlow()
+
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	got := []Entry{}
+	for e := range entries {
+		got = append(got, e)
+	}
+
+	if len(got) != 2 {
+		t.Errorf("want 2 entries, got %d: %v", len(got), got)
+		t.FailNow()
+	}
+	if got[0].Level != LevelWarn || got[0].Message != `Test #0 - WARNING log message` ||
+		len(got[0].Fields) != 1 || got[0].Fields[0].Key != "user" {
+		t.Errorf("unexpected first entry: %#v", got[0])
+	}
+	if got[1].Level != LevelInfo || got[1].Message != `Test #1 - INFO log message` {
+		t.Errorf("unexpected second entry: %#v", got[1])
+	}
+	if n := SubscribeDropped(); n != 2 {
+		t.Errorf("want 2 dropped entries, got %d", n)
+	}
+}
+
+func TestDefaultFlags(t *testing.T) {
+	buf := &strings.Builder{}
+
+	SetDefaultFlags(NoPID)
+	defer SetDefaultFlags(NoFlags)
+
+	if err := OpenWriter(buf, stubApp, NoFlags); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	if Flags() & NoPID == 0 {
+		t.Errorf("default flags are not used, flags: %x", Flags())
+	}
+	Info(stubLogFormat, 0, `INFO`)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	// Explicit flags override default flags
+	if err := OpenWriter(buf, stubApp, stdLog.Lshortfile); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	if Flags() & NoPID != 0 {
+		t.Errorf("default flags are used instead of explicit flags, flags: %x", Flags())
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := stubApp + `: Test #0 - INFO log message` + "\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestReset(t *testing.T) {
+	buf := &strings.Builder{}
+
+	// Nothing to close
+	if err := Reset(); err != nil {
+		t.Errorf("cannot reset not opened log: %v", err)
+	}
+
+	SetInitialLevel(LevelDebug)
+	SetDefaultFlags(NoPID)
+	if err := OpenWriter(buf, stubApp, NoFlags); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	D(stubLogFormat, 0, `DEBUG`)
+
+	// The opened log is closed, settings are restored
+	if err := Reset(); err != nil {
+		t.Errorf("cannot reset opened log: %v", err)
+	}
+	if logger != nil {
+		t.Errorf("default logger is kept after reset")
+	}
+	if LevelEnabled(LevelDebug) {
+		t.Errorf("debug level is kept after reset")
+	}
+	// Debug messages are dropped without the logger
+	D(stubLogFormat, 1, `DEBUG`)
+
+	if err := OpenWriter(buf, stubApp, NoFlags); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	if Flags() & NoPID != 0 {
+		t.Errorf("default flags are kept after reset, flags: %x", Flags())
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	// The closed log is not closed again
+	if err := Reset(); err != nil {
+		t.Errorf("cannot reset closed log: %v", err)
+	}
+
+	if want := stubApp + `: <D> Test #0 - DEBUG log message` + "\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	// Never opened
+	if err := Reset(); err != nil {
+		t.Errorf("cannot reset log: %v", err)
+	}
+	if IsOpen() {
+		t.Errorf("log is open before opening")
+	}
+	var nilLogger *Logger
+	if nilLogger.IsOpen() || NewLogger().IsOpen() {
+		t.Errorf("not opened logger is open")
+	}
+
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	if !IsOpen() {
+		t.Errorf("opened log is not open")
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+	if IsOpen() {
+		t.Errorf("closed log is open")
+	}
+}
+
+func TestPreOpenBuffer(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := Reset(); err != nil {
+		t.Errorf("cannot reset log: %v", err)
+	}
+	defer Reset()	//nolint:errcheck // the log is closed by the test
+
+	SetPreOpenBuffer(2)
+	I(stubLogFormat, 0, `INFO`)
+	// Below the initial level, not buffered
+	D(stubLogFormat, 1, `DEBUG`)
+	W(stubLogFormat, 2, `WARNING`)
+	// The buffer is full
+	E(stubLogFormat, 3, `ERROR`)
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	I(stubLogFormat, 4, `INFO`)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" +
+		stubApp + `: <WRN> Test #2 - WARNING log message` + "\n" +
+		stubApp + `: <WRN> 1 messages written before opening the log were dropped, the size of the buffer is too small` + "\n" +
+		stubApp + `: Test #4 - INFO log message` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+
+	// Fatal messages are written to stderr with buffered ones
+	stderr := &strings.Builder{}
+	stdLog.SetOutput(stderr)
+	defer stdLog.SetOutput(os.Stderr)
+	stdLog.SetPrefix("")
+	stdLog.SetFlags(0)
+
+	logger = nil
+	I(stubLogFormat, 5, `INFO`)
+	Raw(LevelInfo, []byte(`{"raw":true}`))
+	F(stubLogFormat, 6, `FATAL`)
+
+	want = `Test #5 - INFO log message` + "\n" + `{"raw":true}` + "\n" + `Test #6 - FATAL log message` + "\n"
+	if stderr.String() != want {
+		t.Errorf("want stderr %q, got %q", want, stderr.String())
+	}
+
+	// Buffering is disabled by Reset, writing before opening panics again
+	if err := Reset(); err != nil {
+		t.Errorf("cannot reset log: %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("writing before opening does not panic without the buffer")
+			}
+		}()
+		I(stubLogFormat, 7, `INFO`)
+	}()
+}
+
+func TestAccessLog(t *testing.T) {
+	logDir := tempDir()
+	accessFile := filepath.Join(logDir, "access.log")
+	rotatedFile := filepath.Join(logDir, "access.log.1")
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// Not configured access log drops records
+	if err := AccessLog(AccessFields{Method: "GET", Path: "/", Status: 200}); err != nil {
+		t.Errorf("cannot write record to not configured access log: %v", err)
+	}
+
+	if err := SetAccessLogOutput(accessFile); err != nil {
+		t.Errorf("cannot set access log output: %v", err)
+		t.FailNow()
+	}
+
+	at := time.Date(2022, time.November, 8, 10, 0, 0, 0, time.UTC)
+	fields := AccessFields{
+		RemoteAddr:	"192.0.2.1",
+		Method:		"GET",
+		Path:		"/index.html",
+		Proto:		"HTTP/1.1",
+		Status:		200,
+		Bytes:		1024,
+		Duration:	1500 * time.Microsecond,
+		UserAgent:	`curl "7.86.0"`,
+		Time:		at,
+	}
+	if err := AccessLog(fields); err != nil {
+		t.Errorf("cannot write record to access log: %v", err)
+	}
+
+	// Rotate the access log like logrotate does
+	if err := os.Rename(accessFile, rotatedFile); err != nil {
+		t.Errorf("cannot rename access log: %v", err)
+		t.FailNow()
+	}
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+
+	fields.User, fields.Bytes = "alice", 0
+	SetAccessLogFormat(AccessCommon)
+	if err := AccessLog(fields); err != nil {
+		t.Errorf("cannot write record to access log: %v", err)
+	}
+	SetAccessLogFormat(AccessCombinedDuration)
+	if err := AccessLog(fields); err != nil {
+		t.Errorf("cannot write record to access log: %v", err)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	for file, want := range map[string]string{
+		rotatedFile:	`192.0.2.1 - - [08/Nov/2022:10:00:00 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "curl \"7.86.0\""` + "\n",
+		accessFile:		`192.0.2.1 - alice [08/Nov/2022:10:00:00 +0000] "GET /index.html HTTP/1.1" 200 -` + "\n" +
+			`192.0.2.1 - alice [08/Nov/2022:10:00:00 +0000] "GET /index.html HTTP/1.1" 200 - "-" "curl \"7.86.0\"" 1500` + "\n",
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Errorf("cannot read access log: %v", err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("want %q in %s, got %q", want, file, data)
+		}
+	}
+
+	// The application log is not affected
+	if buf.Len() != 0 {
+		t.Errorf("want empty application log, got %q", buf.String())
+	}
+}
+
+func TestTaggedWriter(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	http := stdLog.New(TaggedWriter(LevelWarn, "http"), "", 0)
+	http.Printf("request failed")
+
+	w := Writer(LevelInfo)
+	fmt.Fprint(w, "partial ")
+	fmt.Fprint(w, "line\r\nsecond line\n")
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: [http] <WRN> request failed` + "\n" +
+		stubApp + `: partial line` + "\n" +
+		stubApp + `: second line` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestConfirmWrites(t *testing.T) {
+	target := &slowWriter{delay: 10 * time.Millisecond}
+
+	if err := OpenWriter(target, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetConfirmWrites(false)
+
+	Info(stubLogFormat, 0, `INFO`)
+	Info(stubLogFormat, 1, `INFO`)
+	if err := ISync(stubLogFormat, 2, `INFO`); err != nil {
+		t.Errorf("ISync failed: %v", err)
+	}
+
+	// All messages are written when ISync returns
+	if n := strings.Count(target.String(), "\n"); n != 3 {
+		t.Errorf("want 3 written messages after ISync, got %d: %q", n, target.String())
+	}
+
+	Info(stubLogFormat, 3, `INFO`)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	// Not confirmed messages are not lost on closing
+	if n := strings.Count(target.String(), "\n"); n != 4 {
+		t.Errorf("want 4 written messages after Close, got %d: %q", n, target.String())
+	}
+}
+
+func TestFatalNotConfirmed(t *testing.T) {
+	target := &slowWriter{delay: 10 * time.Millisecond}
+
+	if err := OpenWriter(target, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetConfirmWrites(false)
+	SetMirrorWriter(io.Discard)
+
+	exited := false
+	SetExitFunc(func(code int) {
+		exited = true
+	})
+
+	Info(stubLogFormat, 0, `INFO`)
+	Fatal(stubLogFormat, 1, `FATAL ` + errIsOk)
+
+	// Fatal waits for writing and terminates the program from the goroutine of the caller
+	if !exited {
+		t.Errorf("exit function is not called when Fatal returns")
+	}
+	want := stubApp + `: ` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		stubApp + `: <FATAL> ` + fmt.Sprintf(stubLogFormat, 1, `FATAL ` + errIsOk) + "\n"
+	if got := target.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+}
+
+// callerFuncHelper writes messages using different functions of the package
+func callerFuncHelper(l *Logger) {
+	Info(stubLogFormat, 0, `INFO`)
+	I(stubLogFormat, 1, `INFO`)
+	l.Warn(stubLogFormat, 2, `WARNING`)
+	l.With("user", "root").W(stubLogFormat, 3, `WARNING`)
+	InfoCtx(context.Background(), stubLogFormat, 4, `INFO`)
+	InfoAt(time.Now(), stubLogFormat, 5, `INFO`)
+}
+
+func TestCallerFunc(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetCallerFunc(true)
+	callerFuncHelper(logger)
+
+	entries, unsubscribe := Subscribe(1)
+	Info(stubLogFormat, 6, `INFO`)
+	unsubscribe()
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 7 {
+		t.Errorf("want 7 lines, got %d: %q", len(lines), buf.String())
+		t.FailNow()
+	}
+	for i, line := range lines[:6] {
+		if !strings.HasSuffix(line, " (log.callerFuncHelper)") {
+			t.Errorf("line #%d: unexpected caller: %q", i, line)
+		}
+	}
+	if !strings.HasSuffix(lines[6], " (log.TestCallerFunc)") {
+		t.Errorf("unexpected caller: %q", lines[6])
+	}
+
+	e := <-entries
+	if len(e.Fields) != 1 || e.Fields[0].Key != FuncKey || e.Fields[0].Value != pkgFuncPrefix + "TestCallerFunc" {
+		t.Errorf("unexpected fields of entry: %#v", e.Fields)
+	}
+}
+
+func TestRotateNow(t *testing.T) {
+	dir := tempDir()
+	logFile := filepath.Join(dir, "rotate-now.log.gz")
+
+	opts := DefaultOptions()
+	opts.File = logFile
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Clock = func() time.Time { return time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC) }
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	SetConfirmWrites(false)
+	Info(stubLogFormat, 0, `INFO`)
+
+	closedPath, err := RotateNow()
+	if err != nil {
+		t.Errorf("RotateNow failed: %v", err)
+	}
+	if want := filepath.Join(dir, "rotate-now-2022-11-08.log.gz"); closedPath != want {
+		t.Errorf("want rotated file %q, got %q", want, closedPath)
+	}
+
+	Info(stubLogFormat, 1, `INFO`)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	for file, want := range map[string]string{
+		closedPath: stubApp + `: Test #0 - INFO log message` + "\n",
+		logFile:    stubApp + `: Test #1 - INFO log message` + "\n",
+	} {
+		fd, err := os.Open(file)
+		if err != nil {
+			t.Errorf("cannot open log file: %v", err)
+			continue
+		}
+		gz, err := gzip.NewReader(fd)
+		if err != nil {
+			t.Errorf("cannot read compressed log %s: %v", file, err)
+			fd.Close()
+			continue
+		}
+		data, err := io.ReadAll(gz)
+		fd.Close()
+		if err != nil {
+			t.Errorf("cannot read compressed log %s: %v", file, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: want %q, got %q", file, want, string(data))
+		}
+	}
+
+	// Writers cannot be rotated
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	if _, err := RotateNow(); !errors.Is(err, &ErrNotRotatable) {
+		t.Errorf("want ErrNotRotatable, got %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+}
+
+func TestLevelEnabled(t *testing.T) {
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetLevel(LevelWarn)
+	if LevelEnabled(LevelInfo) || !LevelEnabled(LevelWarn) || !LevelEnabled(LevelFatal) {
+		t.Errorf("unexpected result of LevelEnabled for LevelWarn")
+	}
+
+	// Setting the level by the child logger is visible to package functions
+	With("user", "root").SetLevel(LevelDebug)
+	if !LevelEnabled(LevelDebug) {
+		t.Errorf("level set by the child logger is not used by LevelEnabled")
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+}
+
+func TestAddWriter(t *testing.T) {
+	buf, extra := &strings.Builder{}, &levelRecorder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	AddWriter(extra)
+	Warn(stubLogFormat, 0, `WARNING`)
+	Raw(LevelError, []byte(`raw message`))
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: <WRN> Test #0 - WARNING log message` + "\n" + `raw message` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q in the log, got %q", want, buf.String())
+	}
+	if got := strings.Join(extra.lines, ""); got != want {
+		t.Errorf("want %q in the additional writer, got %q", want, got)
+	}
+	if len(extra.levels) != 2 || extra.levels[0] != LevelWarn || extra.levels[1] != LevelError {
+		t.Errorf("unexpected levels in the additional writer: %v", extra.levels)
+	}
+}
+
+// countFormatter formats entries as "LEVEL: message" lines and counts calls
+type countFormatter struct {
+	calls	int
+}
+
+func (cf *countFormatter) Format(e *Entry) []byte {
+	cf.calls++
+	return []byte(e.Level.String() + ": " + e.Message + "\n")
+}
+
+func TestAddFormattedWriter(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	buf, jsonBuf, custom1, custom2 := &strings.Builder{}, &strings.Builder{}, &strings.Builder{}, &levelRecorder{}
+	cf := &countFormatter{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Clock = func() time.Time { return now }
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	AddFormattedWriter(jsonBuf, FormatJSON)
+	AddFormattedWriter(custom1, cf)
+	AddFormattedWriter(custom2, cf)
+	Warn(stubLogFormat, 0, `WARNING`)
+	Raw(LevelError, []byte(`raw message`))
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	msg := fmt.Sprintf(stubLogFormat, 0, `WARNING`)
+	want := stubApp + `: <WRN> ` + msg + "\n" + `raw message` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q in the log, got %q", want, buf.String())
+	}
+
+	want = `{"time":"2022-11-08T10:00:00.000Z","level":"WARNING","app":"` + stubApp + `","msg":"` + msg + `"}` + "\n" + `raw message` + "\n"
+	if jsonBuf.String() != want {
+		t.Errorf("want %q in the JSON writer, got %q", want, jsonBuf.String())
+	}
+
+	want = `WARNING: ` + msg + "\n" + `raw message` + "\n"
+	if custom1.String() != want {
+		t.Errorf("want %q in the custom writer, got %q", want, custom1.String())
+	}
+	if got := strings.Join(custom2.lines, ""); got != want {
+		t.Errorf("want %q in the second custom writer, got %q", want, got)
+	}
+	if len(custom2.levels) != 2 || custom2.levels[0] != LevelWarn || custom2.levels[1] != LevelError {
+		t.Errorf("unexpected levels in the second custom writer: %v", custom2.levels)
+	}
+	// The message is formatted once for both writers
+	if cf.calls != 1 {
+		t.Errorf("want 1 call of the formatter, got %d", cf.calls)
+	}
+}
+
+func TestRecentErrors(t *testing.T) {
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Err(stubLogFormat, 0, `ERROR ` + errIsOk)
+	if got := RecentErrors(1); got != nil {
+		t.Errorf("want no recent errors when disabled, got %q", got)
+	}
+
+	SetRecentErrors(2)
+	for i := 1; i <= 3; i++ {
+		Err(stubLogFormat, i, `ERROR ` + errIsOk)
+		Info(stubLogFormat, i, `INFO`)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := []string{
+		`<ERR> Test #2 - ERROR ` + errIsOk + ` log message`,
+		`<ERR> Test #3 - ERROR ` + errIsOk + ` log message`,
+	}
+	if got := RecentErrors(5); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got := RecentErrors(1); len(got) != 1 || got[0] != want[1] {
+		t.Errorf("want %q, got %q", want[1:], got)
+	}
+}
+
+func TestStatFuncsFormatted(t *testing.T) {
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	errs, wrns := []string{}, []string{}
+	SetStatFuncsFormatted(
+		func(msg string) { errs = append(errs, msg) },
+		func(msg string) { wrns = append(wrns, msg) },
+	)
+
+	Err(stubLogFormat, 0, `ERROR ` + errIsOk)
+	With("user", "root").Warn(stubLogFormat, 1, `WARNING`)
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := `Test #0 - ERROR ` + errIsOk + ` log message`; len(errs) != 1 || errs[0] != want {
+		t.Errorf("want errors [%q], got %q", want, errs)
+	}
+	if want := `Test #1 - WARNING log message`; len(wrns) != 1 || wrns[0] != want {
+		t.Errorf("want warnings [%q], got %q", want, wrns)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	flags, err := ParseFlags([]string{"date", "Time", " utc ", "nopid"})
+	if err != nil {
+		t.Errorf("ParseFlags failed: %v", err)
+	}
+	if want := stdLog.Ldate | stdLog.Ltime | stdLog.LUTC | NoPID; flags != want {
+		t.Errorf("want flags %x, got %x", want, flags)
+	}
+
+	// Round-trip
+	if got, err := ParseFlags(FlagNames(flags)); err != nil || got != flags {
+		t.Errorf("round-trip of %x (%q) failed: %x, %v", flags, FlagNames(flags), got, err)
+	}
+
+	if flags, err = ParseFlags([]string{"stdflags", "noflags"}); err != nil || flags != stdLog.LstdFlags {
+		t.Errorf("want flags %x, got %x, %v", stdLog.LstdFlags, flags, err)
+	}
+
+	if _, err = ParseFlags([]string{"date", "bogus"}); err == nil || !strings.Contains(err.Error(), "nopid") {
+		t.Errorf("want error with valid flags for unknown flag, got %v", err)
+	}
+
+	if names := FlagNames(NoFlags); names != nil {
+		t.Errorf("want no names for NoFlags, got %q", names)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	target := &flakyWriter{}
+
+	if err := OpenWriter(target, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	audits := 0
+	SetAuditStatFunc(func(string, ...any) { audits++ })
+	SetLevel(LevelError)
+	Pause()
+
+	if err := Audit("user %s logged in", "root"); err != nil {
+		t.Errorf("Audit failed: %v", err)
+	}
+
+	// Audit messages are counted as written and flushed
+	if written := Metrics().Written[LevelInfo]; written != 1 {
+		t.Errorf("want 1 written audit message, got %d", written)
+	}
+	if n, err := Flush(); n != 1 || err != nil {
+		t.Errorf("want 1 flushed audit message, got %d (err: %v)", n, err)
+	}
+
+	// Write errors are returned to the caller
+	target.fail = true
+	if err := Audit("user %s logged out", "root"); !errors.Is(err, errFlakyWrite) {
+		t.Errorf("want %v, got %v", errFlakyWrite, err)
+	}
+	target.fail = false
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if err := Audit("user %s logged in", "root"); !errors.Is(err, &ErrLogClosed) {
+		t.Errorf("want ErrLogClosed after Close, got %v", err)
+	}
+
+	if want := stubApp + `: <AUDIT> user root logged in` + "\n"; target.String() != want {
+		t.Errorf("want %q, got %q", want, target.String())
+	}
+	if audits != 2 {
+		t.Errorf("want 2 calls of the audit statistics function, got %d", audits)
+	}
+}
+
+func TestAuditJSON(t *testing.T) {
+	buf := &strings.Builder{}
+	jsonBuf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetOutputFormat(FormatJSON)
+	SetTimeFormat("2006")
+	AddFormattedWriter(jsonBuf, FormatJSON)
+
+	Info(stubLogFormat, 0, `INFO`)
+	if err := With("user", "bob").Audit("user %s logged in", "bob"); err != nil {
+		t.Errorf("Audit failed: %v", err)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	year := time.Now().Format("2006")
+	want := `{"time":"` + year + `","level":"INFO","app":"` + stubApp + `","msg":"` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + `"}` + "\n" +
+		`{"time":"` + year + `","level":"INFO","app":"` + stubApp + `","msg":"user bob logged in","user":"bob","audit":true}` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+	if jsonBuf.String() != want {
+		t.Errorf("additional writer: want %q, got %q", want, jsonBuf.String())
+	}
+}
+
+func TestSeverityStyle(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetSeverityStyle(SeverityNumeric)
+	Info(stubLogFormat, 0, `INFO`)
+	Err(stubLogFormat, 1, `ERROR ` + errIsOk)
+
+	SetSeverityStyle(SeveritySyslog)
+	Warn(stubLogFormat, 2, `WARNING`)
+
+	SetSeverityStyle(SeverityWords)
+	Warn(stubLogFormat, 3, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: [6] Test #0 - INFO log message` + "\n" +
+		stubApp + `: [3] Test #1 - ERROR ` + errIsOk + ` log message` + "\n" +
+		stubApp + `: <12> Test #2 - WARNING log message` + "\n" +
+		stubApp + `: <WRN> Test #3 - WARNING log message` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestCompactLevels(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+
+	SetDebug(true)
+	SetCompactLevels(true)
+	Debug(stubLogFormat, 0, `DEBUG`)
+	Info(stubLogFormat, 1, `INFO`)
+	Warn(stubLogFormat, 2, `WARNING`)
+	Err(stubLogFormat, 3, `ERROR ` + errIsOk)
+	Fatal(stubLogFormat, 4, `FATAL`)
+
+	SetCompactLevels(false)
+	Warn(stubLogFormat, 5, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: D Test #0 - DEBUG log message` + "\n" +
+		stubApp + `: I Test #1 - INFO log message` + "\n" +
+		stubApp + `: W Test #2 - WARNING log message` + "\n" +
+		stubApp + `: E Test #3 - ERROR ` + errIsOk + ` log message` + "\n" +
+		stubApp + `: F Test #4 - FATAL log message` + "\n" +
+		stubApp + `: <WRN> Test #5 - WARNING log message` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestEntryProcessor(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+
+	var handled []error
+	SetErrorHandler(func(err error) {
+		handled = append(handled, err)
+	})
+
+	SetEntryProcessor(func(e *Entry) {
+		// Scrub passwords, mark the message and raise the level
+		fields := e.Fields[:0]
+		for _, f := range e.Fields {
+			if f.Key != "password" {
+				fields = append(fields, f)
+			}
+		}
+		e.Fields = append(fields, Field{Key: "processed", Value: true})
+		e.Message = strings.ToUpper(e.Message)
+		if strings.Contains(e.Message, "INVALID") {
+			e.Level = Level(100)
+		} else {
+			e.Level = LevelWarn
+		}
+	})
+
+	child := With("user", "root", "password", "secret")
+	child.Info(stubLogFormat, 0, `INFO`)
+	Info(stubLogFormat, 1, `INVALID`)
+
+	SetEntryProcessor(nil)
+	child.Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: <WRN> TEST #0 - INFO LOG MESSAGE user=root processed=true` + "\n" +
+		stubApp + `: TEST #1 - INVALID LOG MESSAGE processed=true` + "\n" +
+		// Fields of the logger are not changed by the processor
+		stubApp + `: Test #2 - INFO log message user=root password=secret` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+	if len(handled) != 1 || !strings.Contains(handled[0].Error(), "unknown level 100") {
+		t.Errorf("want the error about the unknown level, got %v", handled)
+	}
+}
+
+// memFS keeps files in memory, files are created by OpenFile
+type memFS struct {
+	files	fstest.MapFS
+	// Opening files for writing or writing to them fails
+	readOnly	bool
+	writeFail	bool
+	// Number of created temporary files and failing of writing to them
+	temps		int
+	tempFail	bool
+}
+
+type memFile struct {
+	fsys	*memFS
+	name	string
+	// Offset of reading
+	off		int64
+	temp	bool
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if m.readOnly && flag & (os.O_WRONLY|os.O_RDWR) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	if _, ok := m.files[name]; !ok || flag & os.O_TRUNC != 0 {
+		if !ok && flag & os.O_CREATE == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		m.files[name] = &fstest.MapFile{Mode: perm}
+	}
+
+	return &memFile{fsys: m, name: name}, nil
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(m.files, name)
+}
+
+func (m *memFS) Rename(oldpath, newpath string) error {
+	f, ok := m.files[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	delete(m.files, oldpath)
+	m.files[newpath] = f
+
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+
+	return nil
+}
+
+func (m *memFS) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Stat(m.files, name)
+}
+
+func (m *memFS) CreateTemp(dir, pattern string) (TempFile, error) {
+	if dir == "" {
+		dir = "tmp"
+	}
+	m.temps++
+	name := path.Join(dir, strings.Replace(pattern, "*", strconv.Itoa(m.temps), 1))
+	m.files[name] = &fstest.MapFile{Mode: 0o600}
+
+	return &memFile{fsys: m, name: name, temp: true}, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.fsys.writeFail || f.temp && f.fsys.tempFail {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+
+	// Data are always appended
+	f.fsys.files[f.name].Data = append(f.fsys.files[f.name].Data, p...)
+	return len(p), nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	data := f.fsys.files[f.name].Data
+	if f.off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, data[f.off:])
+	f.off += int64(n)
+
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(f.fsys.files[f.name].Data))
+	}
+	f.off = offset
+
+	return f.off, nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.fsys.files[f.name].Data = f.fsys.files[f.name].Data[:size]
+	return nil
+}
+
+func (f *memFile) Name() string {
+	return f.name
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return fs.Stat(f.fsys.files, f.name)
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+func TestSetFS(t *testing.T) {
+	mfs := &memFS{files: fstest.MapFS{}}
+
+	// The directory does not exist on the real file system
+	logFile := "go-test-missing/app.log"
+	accessFile := "go-test-missing/access.log"
+
+	SetFS(mfs)
+	defer SetFS(nil)
+
+	if err := Open(logFile, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the in-memory file system: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+	// The header is not written to the reopened file that already has data
+	SetFileHeader(func() string { return "# header" })
+
+	Info(stubLogFormat, 0, `INFO`)
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log on the in-memory file system: %v", err)
+	}
+	Info(stubLogFormat, 1, `INFO`)
+
+	if err := SetAccessLogOutput(accessFile); err != nil {
+		t.Errorf("cannot open access log on the in-memory file system: %v", err)
+	}
+	if err := AccessLog(AccessFields{Method: "GET", Path: "/", Status: 200, Time: time.Unix(0, 0).UTC()}); err != nil {
+		t.Errorf("cannot write access log record: %v", err)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log: %v", err)
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" + stubApp + `: Test #1 - INFO log message` + "\n"
+	if got := string(mfs.files[logFile].Data); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	want = `- - - [01/Jan/1970:00:00:00 +0000] "GET /" 200 - "-" "-"` + "\n"
+	if got := string(mfs.files[accessFile].Data); got != want {
+		t.Errorf("want access log %q, got %q", want, got)
+	}
+	if _, err := os.Stat(filepath.Dir(logFile)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("log files were created on the real file system, error: %v", err)
+	}
+
+	// The file system of options is used instead of the default one
+	opts := DefaultOptions()
+	opts.File, opts.Prefix, opts.Flags = "other.log", stubApp, NoPID
+	opts.FS = mfs
+	opts.FileHeader = func() string { return "# header" }
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log with options: %v", err)
+		t.FailNow()
+	}
+	Info(stubLogFormat, 2, `INFO`)
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log: %v", err)
+	}
+
+	want = "# header\n" + stubApp + `: Test #2 - INFO log message` + "\n"
+	if got := string(mfs.files["other.log"].Data); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestFSFiles(t *testing.T) {
+	mfs := &memFS{files: fstest.MapFS{}}
+
+	opts := DefaultOptions()
+	opts.File, opts.Prefix, opts.Flags = "rotated.log", stubApp, NoPID
+	opts.FS = mfs
+	opts.Clock = func() time.Time { return time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC) }
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the in-memory file system: %v", err)
+		t.FailNow()
+	}
+
+	// Leveled files and the spool file are created on the file system
+	if err := SetLeveledFiles(map[Level]string{LevelError: "errors.log"}); err != nil {
+		t.Errorf("cannot set leveled files: %v", err)
+	}
+	if err := SpillToDisk(""); err != nil {
+		t.Errorf("cannot enable spilling: %v", err)
+	}
+	if _, ok := mfs.files["tmp/log-spool-1"]; !ok {
+		t.Errorf("spool file is not created on the in-memory file system: %v", mfs.files)
+	}
+	Err(stubLogFormat, 0, `ERROR ` + errIsOk)
+
+	// Rotated files are renamed on the file system
+	if closedPath, err := RotateNow(); err != nil || closedPath != "rotated-2022-11-08.log" {
+		t.Errorf("want rotated file %q, got %q, error: %v", "rotated-2022-11-08.log", closedPath, err)
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log: %v", err)
+	}
+
+	errLine := stubApp + `: <ERR> ` + fmt.Sprintf(stubLogFormat, 0, `ERROR ` + errIsOk) + "\n"
+	for file, want := range map[string]string{
+		"rotated-2022-11-08.log":	errLine,
+		"errors-2022-11-08.log":	errLine,
+		"rotated.log":				"",
+		"errors.log":				"",
+	} {
+		if f, ok := mfs.files[file]; !ok || string(f.Data) != want {
+			t.Errorf("%s: want %q, got %v", file, want, f)
+		}
+	}
+	if _, ok := mfs.files["tmp/log-spool-1"]; ok {
+		t.Errorf("spool file is not removed from the in-memory file system")
+	}
+
+	// The fallback file is written, replayed and removed on the file system
+	target := &flakyWriter{fail: true}
+	opts = DefaultOptions()
+	opts.Writer, opts.Prefix, opts.Flags = target, stubApp, NoPID
+	opts.FS = mfs
+	opts.FallbackFile = "fallback.log"
+	opts.ErrorHandler = func(error) {}
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 1, `INFO`)
+	if err := Barrier(); err != nil {
+		t.Errorf("cannot sync log: %v", err)
+	}
+	infoLine := stubApp + `: ` + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n"
+	if f, ok := mfs.files["fallback.log"]; !ok || string(f.Data) != infoLine {
+		t.Errorf("want fallback file %q, got %v", infoLine, f)
+	}
+
+	// Not replayed messages are kept
+	if err := ReplayFallback(); !errors.Is(err, errFlakyWrite) {
+		t.Errorf("want %v, got %v", errFlakyWrite, err)
+	}
+	if f, ok := mfs.files["fallback.log"]; !ok || string(f.Data) != infoLine {
+		t.Errorf("want fallback file %q after failed replay, got %v", infoLine, f)
+	}
+
+	target.fail = false
+	if err := ReplayFallback(); err != nil {
+		t.Errorf("cannot replay fallback messages: %v", err)
+	}
+	if _, ok := mfs.files["fallback.log"]; ok {
+		t.Errorf("fallback file is not removed from the in-memory file system")
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+	if target.String() != infoLine {
+		t.Errorf("want %q, got %q", infoLine, target.String())
+	}
+}
+
+func TestReopenUnwritable(t *testing.T) {
+	mfs := &memFS{files: fstest.MapFS{}}
+
+	opts := DefaultOptions()
+	opts.File, opts.Prefix, opts.Flags = "app.log", stubApp, NoPID
+	opts.FS = mfs
+	errs := []error{}
+	opts.ErrorHandler = func(err error) { errs = append(errs, err) }
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the in-memory file system: %v", err)
+		t.FailNow()
+	}
+	Info(stubLogFormat, 0, `INFO`)
+
+	// The file created by the rotation cannot be written
+	mfs.readOnly = true
+
+	err := Reopen()
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) || fileErr.Path() != "app.log" || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("want the error about the unwritable file, got %v", err)
+	}
+
+	// The file can be opened, but the zero-byte write fails
+	mfs.readOnly, mfs.writeFail = false, true
+	if err := Reopen(); !errors.As(err, &fileErr) || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("want the error of the zero-byte write, got %v", err)
+	}
+
+	// Messages written until the next reopening are reported as not written
+	Info(stubLogFormat, 1, `INFO`)
+	Barrier()
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrClosed) {
+		t.Errorf("want the error of writing to the closed log file, got %v", errs)
+	}
+
+	mfs.writeFail = false
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log: %v", err)
+	}
+
+	want := stubApp + `: Test #0 - INFO log message` + "\n" + stubApp + `: Test #2 - INFO log message` + "\n"
+	if got := string(mfs.files["app.log"].Data); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// The file cannot be opened too
+	mfs.readOnly = true
+	if err := OpenWithOptions(opts); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("want the error about the unwritable file, got %v", err)
+	}
+}
+
+func TestReopenUnwritableFile(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "unwritable.log")
+
+	if err := Open(logFile, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	// The directory created instead of the rotated file cannot be opened for writing by anyone
+	if err := os.Rename(logFile, logFile + ".1"); err != nil {
+		t.Errorf("cannot rename log file: %v", err)
+	}
+	if err := os.Mkdir(logFile, 0o755); err != nil {
+		t.Errorf("cannot create directory: %v", err)
+	}
+	var fileErr *FileError
+	if err := Reopen(); !errors.As(err, &fileErr) || fileErr.Path() != logFile {
+		t.Errorf("want the error about the unwritable file, got %v", err)
+	}
+
+	if err := os.Remove(logFile); err != nil {
+		t.Errorf("cannot remove directory: %v", err)
+	}
+
+	// The read-only file, permissions are not checked for the superuser
+	if os.Geteuid() != 0 {
+		if err := os.WriteFile(logFile, nil, 0o444); err != nil {
+			t.Errorf("cannot create read-only file: %v", err)
+		}
+		if err := Reopen(); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("want the permission error, got %v", err)
+		}
+		if err := os.Remove(logFile); err != nil {
+			t.Errorf("cannot remove read-only file: %v", err)
+		}
+	}
+
+	// The log is usable again when the file can be opened
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log: %v", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	target := &slowWriter{delay: 10 * time.Millisecond}
+
+	if err := OpenWriter(target, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetConfirmWrites(false)
+	Info(stubLogFormat, 0, `INFO`)
+	Info(stubLogFormat, 1, `INFO`)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if err := Shutdown(context.Background()); !errors.Is(err, &ErrLogClosed) {
+		t.Errorf("want ErrLogClosed after shutdown, got %v", err)
+	}
+	if n := strings.Count(target.String(), "\n"); n != 2 {
+		t.Errorf("want 2 written messages, got %d: %q", n, target.String())
+	}
+
+	// Too short deadline
+	l := NewLogger()
+	if err := l.OpenWriter(&slowWriter{delay: 50 * time.Millisecond}, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	l.SetConfirmWrites(false)
+	l.Info(stubLogFormat, 0, `INFO`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := l.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+// tbRecorder records messages logged by the test logger
+type tbRecorder struct {
+	testing.TB
+	logs		[]string
+	errs		[]string
+	cleanups	[]func()
+}
+
+func (tr *tbRecorder) Errorf(format string, args ...any) {
+	tr.errs = append(tr.errs, fmt.Sprintf(format, args...))
+}
+
+func (tr *tbRecorder) Log(args ...any) {
+	tr.logs = append(tr.logs, fmt.Sprint(args...))
+}
+
+func (tr *tbRecorder) Cleanup(fn func()) {
+	tr.cleanups = append(tr.cleanups, fn)
+}
+
+func TestNewTestLogger(t *testing.T) {
+	tr := &tbRecorder{TB: t}
+
+	l := NewTestLogger(tr)
+	l.SetLevel(LevelWarn)
+	l.Info(stubLogFormat, 0, `INFO`)
+	l.Err(stubLogFormat, 1, `ERROR ` + errIsOk)
+
+	want := []string{`<ERR> Test #1 - ERROR ` + errIsOk + ` log message`}
+	if strings.Join(tr.logs, "|") != strings.Join(want, "|") {
+		t.Errorf("want %q, got %q", want, tr.logs)
+	}
+
+	if len(tr.cleanups) != 1 {
+		t.Errorf("want 1 cleanup function, got %d", len(tr.cleanups))
+		t.FailNow()
+	}
+	tr.cleanups[0]()
+	if err := l.Close(); !errors.Is(err, &ErrLogClosed) {
+		t.Errorf("logger is not closed by the cleanup function: %v", err)
+	}
+
+	// Usage with the real test
+	NewTestLogger(t).Info("message from the test logger")
+}
+
+func TestExpectFatal(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+
+	tr := &tbRecorder{TB: t}
+	msg := ExpectFatal(tr, func() {
+		Info(stubLogFormat, 0, `INFO`)
+		Fatal(stubLogFormat, 1, `FATAL`)
+	})
+	if want := fmt.Sprintf(stubLogFormat, 1, `FATAL`); msg != want {
+		t.Errorf("want %q, got %q", want, msg)
+	}
+	if len(tr.errs) != 0 {
+		t.Errorf("want no errors of the test, got %q", tr.errs)
+	}
+
+	// Fatal is not called
+	msg = ExpectFatal(tr, func() {
+		Err(stubLogFormat, 2, `ERROR ` + errIsOk)
+	})
+	if msg != "" || len(tr.errs) != 1 {
+		t.Errorf("want the error of the test and no message, got %q and %q", tr.errs, msg)
+	}
+
+	// Custom exit function gets the exit code
+	exits := 0
+	SetExitFunc(func(code int) {
+		exits += code
+	})
+	Fatal(stubLogFormat, 3, `FATAL`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if exits != 1 {
+		t.Errorf("want exit code 1, got %d", exits)
+	}
+}
+
+func TestStderrMirrorFormat(t *testing.T) {
+	logBuf, stderr := &strings.Builder{}, &strings.Builder{}
+
+	defer stdLog.SetOutput(os.Stderr)
+	stdLog.SetOutput(stderr)
+
+	if err := OpenWriter(logBuf, stubApp, stdLog.Ldate | stdLog.Ltime); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// Configuration of the standard logger does not affect mirrored messages
+	stdLog.SetPrefix("other: ")
+	stdLog.SetFlags(stdLog.Lshortfile)
+	defer stdLog.SetFlags(stdLog.LstdFlags)
+	defer stdLog.SetPrefix("")
+
+	at := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	Component("db").With("k", "v").ErrAt(at, stubLogFormat, 0, `ERROR ` + errIsOk)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if logBuf.String() == "" || stderr.String() != logBuf.String() {
+		t.Errorf("want the same lines in the log and stderr, got %q and %q", logBuf.String(), stderr.String())
+	}
+}
+
+func TestStderrMirror(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "stderr-mirror.log")
+
+	// Redirect stderr to the log file
+	stderr, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Errorf("cannot create log file: %v", err)
+		t.FailNow()
+	}
+	defer stderr.Close()
+
+	defer func(orig *os.File) {
+		os.Stderr = orig
+		stdLog.SetOutput(orig)
+	}(os.Stderr)
+	os.Stderr = stderr
+	stdLog.SetOutput(stderr)
+
+	for _, mode := range []MirrorMode{MirrorAuto, MirrorNever, MirrorAlways} {
+		if err := Open(logFile, stubApp, NoPID); err != nil {
+			t.Errorf("cannot open log file: %v", err)
+			t.FailNow()
+		}
+
+		SetStderrMirror(mode)
+		Err(stubLogFormat, int(mode), `ERROR ` + errIsOk)
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log file: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Errorf("cannot read log file: %v", err)
+		t.FailNow()
+	}
+
+	// Only MirrorAlways duplicates the message to the same file
+	for mode, want := range map[MirrorMode]int{MirrorAuto: 1, MirrorNever: 1, MirrorAlways: 2} {
+		msg := fmt.Sprintf(stubLogFormat, int(mode), `ERROR ` + errIsOk)
+		if n := strings.Count(string(data), msg); n != want {
+			t.Errorf("mode %d: want %d lines with the message, got %d: %q", mode, want, n, string(data))
+		}
+	}
+
+	// Files other than the log target are always mirrored in auto mode
+	if !autoStderrMirror(io.Discard) {
+		t.Errorf("stderr is not mirrored for non-file targets in auto mode")
+	}
+}
+
+func TestTmpl(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	errs := []error{}
+	SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	entries, unsubscribe := Subscribe(1)
+
+	Tmpl(LevelWarn, "user {user} failed after {attempts} attempts {{literal}}", map[string]any{
+		"user":		"root",
+		"attempts":	3,
+	})
+	unsubscribe()
+	Tmpl(LevelInfo, "unknown {placeholder} and {user", map[string]any{"user": "root"})
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + `: <WRN> user root failed after 3 attempts {literal}` + "\n" +
+		stubApp + `: unknown {placeholder} and {user` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+
+	e := <-entries
+	if len(e.Fields) != 2 || e.Fields[0].Key != "attempts" || e.Fields[1].Key != "user" {
+		t.Errorf("unexpected fields of entry: %#v", e.Fields)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], ErrBadTemplate) || !strings.Contains(errs[0].Error(), "placeholder") {
+		t.Errorf("want 1 error about the placeholder, got %v", errs)
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	first, second := &strings.Builder{}, &strings.Builder{}
+
+	if err := OpenWriter(first, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 1, `INFO`)
+	if err := SetOutput(second); err != nil {
+		t.Errorf("cannot set output: %v", err)
+	}
+	Info(stubLogFormat, 2, `INFO`)
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	Info(stubLogFormat, 3, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if err := SetOutput(first); !errors.Is(err, &ErrLogClosed) {
+		t.Errorf("want %v, got %v", &ErrLogClosed, err)
+	}
+
+	for _, tc := range []struct{
+		buf	*strings.Builder
+		want	string
+	}{
+		{first, stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n"},
+		{second, stubApp + ": " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + "\n" +
+			stubApp + ": " + fmt.Sprintf(stubLogFormat, 3, `INFO`) + "\n"},
+	} {
+		if tc.buf.String() != tc.want {
+			t.Errorf("want %q, got %q", tc.want, tc.buf.String())
+		}
+	}
+}
+
+func TestSetOutputFile(t *testing.T) {
+	file := filepath.Join(tempDir(), "set-output.log")
+	buf := &strings.Builder{}
+
+	if err := Open(file, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 1, `INFO`)
+	if err := SetOutput(buf); err != nil {
+		t.Errorf("cannot set output: %v", err)
+	}
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log: %v", err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("cannot read log file: %v", err)
+	}
+
+	if want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n"; string(data) != want {
+		t.Errorf("want %q in the file, got %q", want, data)
+	}
+	if want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + "\n"; buf.String() != want {
+		t.Errorf("want %q in the writer, got %q", want, buf.String())
+	}
+}
+
+func TestOnce(t *testing.T) {
+	buf := &strings.Builder{}
+
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Clock = func() time.Time { return now }
+	opts.SkipEmpty = true
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	// Disabled level does not mark the key as seen
+	Once(LevelDebug, "debug", stubLogFormat, 0, `DEBUG`)
+	SetLevel(LevelDebug)
+	Once(LevelDebug, "debug", stubLogFormat, 1, `DEBUG`)
+
+	for i := 2; i < 5; i++ {
+		Once(LevelWarn, "deprecated", stubLogFormat, i, `WARNING`)
+	}
+
+	SetOnceInterval(time.Minute)
+	now = now.Add(time.Minute)
+	Once(LevelWarn, "deprecated", stubLogFormat, 5, `WARNING`)
+	Once(LevelWarn, "deprecated", stubLogFormat, 6, `WARNING`)
+
+	// The number of remembered keys is bounded
+	for i := 0; i < onceMaxKeys + 10; i++ {
+		Once(LevelInfo, strconv.Itoa(i), "")
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": <D> " + fmt.Sprintf(stubLogFormat, 1, `DEBUG`) + "\n" +
+		stubApp + ": <WRN> " + fmt.Sprintf(stubLogFormat, 2, `WARNING`) + "\n" +
+		stubApp + ": <WRN> " + fmt.Sprintf(stubLogFormat, 5, `WARNING`) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+
+	if n := len(logger.onceSeen); n != onceMaxKeys {
+		t.Errorf("want %d remembered keys, got %d", onceMaxKeys, n)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	buf := &strings.Builder{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.GoroutineID = true
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	// The ID of another goroutine is different
+	done := make(chan uint64)
+	go func() {
+		With("k", "v").Info(stubLogFormat, 1, `INFO`)
+		done <- goroutineID()
+	}()
+	other := <-done
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	gid := goroutineID()
+	if gid == 0 || gid == other {
+		t.Errorf("unexpected goroutine IDs: %d and %d", gid, other)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + " gid=" + strconv.FormatUint(gid, 10) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + " k=v gid=" + strconv.FormatUint(other, 10) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+//nolint:gochecknoglobals // Custom levels are registered once per process
+var (
+	levelEmergency	= RegisterLevel("emergency", 10, false)
+	levelTrace		= RegisterLevel("trace", -1, false)
+)
+
+func TestCustomLevels(t *testing.T) {
+	buf := &strings.Builder{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.StderrMirror = MirrorNever
+
+	errStat := 0
+	opts.ErrStat = func(format string, v ...any) {
+		errStat++
+	}
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Msg(levelTrace, stubLogFormat, 0, `TRACE`)
+	Msg(levelEmergency, stubLogFormat, 1, `EMERGENCY`)
+	Msg(LevelWarn, stubLogFormat, 2, `WARNING`)
+
+	SetLevel(levelTrace)
+	Msg(levelTrace, stubLogFormat, 3, `TRACE`)
+	SetLevel(levelEmergency)
+	Err(stubLogFormat, 4, `ERROR`)
+	Msg(levelEmergency, stubLogFormat, 5, `EMERGENCY`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": <EMERGENCY> " + fmt.Sprintf(stubLogFormat, 1, `EMERGENCY`) + "\n" +
+		stubApp + ": <WRN> " + fmt.Sprintf(stubLogFormat, 2, `WARNING`) + "\n" +
+		stubApp + ": <TRACE> " + fmt.Sprintf(stubLogFormat, 3, `TRACE`) + "\n" +
+		stubApp + ": <EMERGENCY> " + fmt.Sprintf(stubLogFormat, 5, `EMERGENCY`) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+
+	// Emergency messages are counted as errors, including the filtered error message
+	if errStat != 3 {
+		t.Errorf("want 3 calls of the error statistic function, got %d", errStat)
+	}
+
+	if levelEmergency.String() != "EMERGENCY" || levelEmergency <= LevelFatal || levelTrace >= LevelDebug {
+		t.Errorf("unexpected custom levels: %v(%d), %v(%d)", levelEmergency, levelEmergency, levelTrace, levelTrace)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("want panic on registering the level with the used severity")
+		}
+	}()
+	RegisterLevel("critical", int(LevelFatal), true)
+}
+
+func TestTimed(t *testing.T) {
+	buf := &strings.Builder{}
+
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Clock = func() time.Time { return now }
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	func() {
+		defer Timed(LevelWarn, stubLogFormat, 0, `WARNING`)()
+		now = now.Add(1500 * time.Millisecond)
+	}()
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": <WRN> " + fmt.Sprintf(stubLogFormat, 0, `WARNING`) + " started\n" +
+		stubApp + ": <WRN> " + fmt.Sprintf(stubLogFormat, 0, `WARNING`) + " finished elapsed=1.5s\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestMaxMessageBytes(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+
+	for format, want := range map[OutputFormat]string{
+		FormatText:	stubApp + `: abcdefg…(truncated 7 bytes) user=root` + "\n" + stubApp + `: short user=root` + "\n",
+		FormatJSON:	`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"abcdefg…(truncated 7 bytes)","user":"root"}` + "\n" +
+			`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"short","user":"root"}` + "\n",
+	} {
+		buf := &strings.Builder{}
+
+		opts := DefaultOptions()
+		opts.Writer = buf
+		opts.Prefix = stubApp
+		opts.Flags = NoPID
+		opts.Clock = func() time.Time { return now }
+		opts.OutputFormat = format
+
+		if err := OpenWithOptions(opts); err != nil {
+			t.Errorf("cannot open log on the writer: %v", err)
+			t.FailNow()
+		}
+
+		// The two-byte rune crosses the limit, so it is dropped entirely
+		SetMaxMessageBytes(8)
+		With("user", "root").Info("abcdefgé12345")
+		With("user", "root").Info("short")
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log on the writer: %v", err)
+		}
+
+		if buf.String() != want {
+			t.Errorf("want %q, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+
+	for format, want := range map[OutputFormat]string{
+		FormatText:	stubApp + `: default b=1 a=2 c=3` + "\n" +
+			stubApp + `: ordered c=3 a=2 b=1` + "\n" +
+			stubApp + `: restored b=1 a=2 c=3` + "\n",
+		FormatJSON:	`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"default","b":1,"a":2,"c":3}` + "\n" +
+			`{"msg":"ordered","c":3,"level":"INFO","a":2,"time":"2022-11-08T10:00:00.000Z","app":"` + stubApp + `","b":1}` + "\n" +
+			`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"restored","b":1,"a":2,"c":3}` + "\n",
+	} {
+		buf := &strings.Builder{}
+
+		opts := DefaultOptions()
+		opts.Writer = buf
+		opts.Prefix = stubApp
+		opts.Flags = NoPID
+		opts.Clock = func() time.Time { return now }
+		opts.OutputFormat = format
+
+		if err := OpenWithOptions(opts); err != nil {
+			t.Errorf("cannot open log on the writer: %v", err)
+			t.FailNow()
+		}
+
+		child := With("b", 1, "a", 2, "c", 3)
+		child.Info("default")
+		// Keys of attributes are ignored by the text format, the repeated key keeps its first position
+		SetFieldOrder([]string{"msg", "c", "level", "a", "c"})
+		child.Info("ordered")
+		SetFieldOrder(nil)
+		child.Info("restored")
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log on the writer: %v", err)
+		}
+
+		if buf.String() != want {
+			t.Errorf("want %q, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestErrf(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	err := errors.New("file not found")
+
+	for format, want := range map[OutputFormat]string{
+		FormatText:	stubApp + `: <ERR> cannot process 42: file not found user=root` + "\n" +
+			stubApp + `: <WRN> cannot process 43: file not found` + "\n" +
+			stubApp + `: <ERR> cannot process 44` + "\n",
+		FormatJSON:	`{"time":"2022-11-08T10:00:00.000Z","level":"ERROR","app":"` + stubApp + `","msg":"cannot process 42: file not found","user":"root","error":"file not found"}` + "\n" +
+			`{"time":"2022-11-08T10:00:00.000Z","level":"WARNING","app":"` + stubApp + `","msg":"cannot process 43: file not found","error":"file not found"}` + "\n" +
+			`{"time":"2022-11-08T10:00:00.000Z","level":"ERROR","app":"` + stubApp + `","msg":"cannot process 44"}` + "\n",
+	} {
+		buf := &strings.Builder{}
+		var stats []string
+
+		opts := DefaultOptions()
+		opts.Writer = buf
+		opts.Prefix = stubApp
+		opts.Flags = NoPID
+		opts.Clock = func() time.Time { return now }
+		opts.OutputFormat = format
+		opts.MirrorWriter = io.Discard
+		opts.ErrStat = func(format string, args ...any) {
+			stats = append(stats, fmt.Sprintf(format, args...))
+		}
+
+		if err := OpenWithOptions(opts); err != nil {
+			t.Errorf("cannot open log on the writer: %v", err)
+			t.FailNow()
+		}
+
+		With("user", "root").Errf(err, "cannot process %d", 42)
+		Warnf(err, "cannot process %d", 43)
+		Errf(nil, "cannot process %d", 44)
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log on the writer: %v", err)
+		}
+
+		if buf.String() != want {
+			t.Errorf("want %q, got %q", want, buf.String())
+		}
+		if wantStats := []string{"cannot process 42: file not found", "cannot process 44"}; fmt.Sprint(stats) != fmt.Sprint(wantStats) {
+			t.Errorf("want error statistics %q, got %q", wantStats, stats)
+		}
+	}
+}
+
+func TestAdaptiveSampling(t *testing.T) {
+	buf := &strings.Builder{}
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	// The clock is changed by the test, so messages are written by the test goroutine
+	opts.Sync = true
+	opts.Clock = func() time.Time { return now }
+	opts.MirrorWriter = io.Discard
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetAdaptiveSampling(10)
+
+	// The number of messages in each second and the number of messages expected to be written
+	for i, step := range []struct{ sent, written int }{
+		// The rate is not known yet, all messages are written
+		{40, 40},
+		// The rate is 40 per second, each fourth message is written
+		{40, 10},
+		{5, 1},
+		// The rate fell below the target, all messages are written again
+		{5, 5},
+	} {
+		before := strings.Count(buf.String(), "\n")
+		for n := 0; n < step.sent; n++ {
+			Info(stubLogFormat, n, `INFO`)
+		}
+		if i == 1 {
+			// Fatal messages are not sampled and not counted
+			Fatal(stubLogFormat, 0, `FATAL`)
+			before++
+		}
+
+		if written := strings.Count(buf.String(), "\n") - before; written != step.written {
+			t.Errorf("step %d: want %d written messages, got %d", i, step.written, written)
+		}
+
+		now = now.Add(time.Second)
+	}
+
+	if emitted, dropped := AdaptiveSampled(); emitted != 56 || dropped != 34 {
+		t.Errorf("want 56 emitted and 34 dropped messages, got %d and %d", emitted, dropped)
+	}
+
+	// Disabled sampling resets counters
+	SetAdaptiveSampling(0)
+	Info(stubLogFormat, 0, `INFO`)
+	if emitted, dropped := AdaptiveSampled(); emitted != 0 || dropped != 0 {
+		t.Errorf("want reset counters, got %d and %d", emitted, dropped)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+}
+
+func TestOpenFunc(t *testing.T) {
+	type call struct {
+		level	Level
+		msg		string
+	}
+	var calls []call
+
+	if err := OpenFunc(func(level Level, msg string) {
+		calls = append(calls, call{level, msg})
+	}, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the function: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+
+	With("user", "root").Info(stubLogFormat, 0, `INFO`)
+	Warn(stubLogFormat, 1, `WARNING`)
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log on the function: %v", err)
+	}
+	Err(stubLogFormat, 2, `ERROR`)
+	if err := Barrier(); err != nil {
+		t.Errorf("cannot sync log on the function: %v", err)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the function: %v", err)
+	}
+
+	want := []call{
+		{LevelInfo, stubApp + `: Test #0 - INFO log message user=root`},
+		{LevelWarn, stubApp + `: <WRN> Test #1 - WARNING log message`},
+		{LevelError, stubApp + `: <ERR> Test #2 - ERROR log message`},
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("want calls %v, got %v", want, calls)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+	SetRateLimit(LevelWarn, 1, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		Info(stubLogFormat, i, `INFO`)
+		Warn(stubLogFormat, i, `WARNING`)
+	}
+	Err(stubLogFormat, 0, `ERROR ` + errIsOk)
+	Pause()
+	Info(stubLogFormat, 3, `INFO`)
+	Resume()
+	if err := Barrier(); err != nil {
+		t.Errorf("cannot wait for writing of messages: %v", err)
+	}
+
+	m := Metrics()
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := [LevelFatal + 1]uint64{LevelInfo: 3, LevelWarn: 1, LevelError: 1}; m.Written != want {
+		t.Errorf("want written messages %v, got %v", want, m.Written)
+	}
+	if m.Dropped != 1 || m.RateLimited != 2 {
+		t.Errorf("want 1 dropped and 2 rate limited messages, got %d and %d", m.Dropped, m.RateLimited)
+	}
+	// All messages are received by the writer, at least one was passed to it
+	if m.Queued != 0 || m.QueuedPeak < 1 {
+		t.Errorf("want no queued messages and the positive peak, got %d and %d", m.Queued, m.QueuedPeak)
+	}
+	if m.WriteMax < m.WriteAvg || m.WriteMax == 0 {
+		t.Errorf("unexpected write latency: avg %v, max %v", m.WriteAvg, m.WriteMax)
+	}
+}
+
+func TestLevelColor(t *testing.T) {
+	term, plain := &strings.Builder{}, &strings.Builder{}
+
+	// Pretend the builder is the terminal
+	defer func(orig func(io.Writer) bool) { isTerminal = orig }(isTerminal)
+	isTerminal = func(w io.Writer) bool { return w == term }
+
+	SetInitialLevel(LevelDebug)
+	defer SetInitialLevel(LevelInfo)
+
+	if err := OpenWriter(term, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+	AddWriter(plain)
+	SetColors(true)
+
+	D(stubLogFormat, 0, `DEBUG`)
+	Info(stubLogFormat, 1, `INFO`)
+	Warn(stubLogFormat, 2, `WARNING`)
+	Err(stubLogFormat, 3, `ERROR`)
+	SetLevelColor(LevelWarn, ColorMagenta)
+	SetLevelColor(LevelError, ColorNone)
+	Warn(stubLogFormat, 4, `WARNING`)
+	Err(stubLogFormat, 5, `ERROR`)
+	// Padding of the column is not colored
+	SetLevelWidth(6)
+	Warn(stubLogFormat, 6, `WARNING`)
+	SetLevelWidth(0)
+	SetColors(false)
+	Warn(stubLogFormat, 7, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": \x1b[90m<D>\x1b[0m Test #0 - DEBUG log message\n" +
+		stubApp + ": Test #1 - INFO log message\n" +
+		stubApp + ": \x1b[33m<WRN>\x1b[0m Test #2 - WARNING log message\n" +
+		stubApp + ": \x1b[31m<ERR>\x1b[0m Test #3 - ERROR log message\n" +
+		stubApp + ": \x1b[35m<WRN>\x1b[0m Test #4 - WARNING log message\n" +
+		stubApp + ": <ERR> Test #5 - ERROR log message\n" +
+		stubApp + ": \x1b[35m<WRN>\x1b[0m  Test #6 - WARNING log message\n" +
+		stubApp + ": <WRN> Test #7 - WARNING log message\n"
+	if term.String() != want {
+		t.Errorf("want %q, got %q", want, term.String())
+	}
+
+	// Additional writers are not terminals
+	if strings.Contains(plain.String(), "\x1b") || strings.Count(plain.String(), "\n") != 8 {
+		t.Errorf("want 8 plain lines in the additional writer, got %q", plain.String())
+	}
+}
+
+// syncCounter counts syncs and numbers of lines written before each of them
+type syncCounter struct {
+	lines	int
+	synced	[]int
+}
+
+func (sc *syncCounter) Write(p []byte) (int, error) {
+	sc.lines++
+	return len(p), nil
+}
+
+func (sc *syncCounter) Sync() error {
+	sc.synced = append(sc.synced, sc.lines)
+	return nil
+}
+
+func TestFlushThreshold(t *testing.T) {
+	sc := &syncCounter{}
+
+	if err := OpenWriter(sc, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetFlushThreshold(3)
+
+	for i := 0; i < 7; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+	// Barrier syncs the target and restarts counting
+	if err := Barrier(); err != nil {
+		t.Errorf("cannot sync log: %v", err)
+	}
+	for i := 7; i < 10; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+	SetFlushThreshold(0)
+	for i := 10; i < 20; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	if want := []int{3, 6, 7, 10}; fmt.Sprint(sc.synced) != fmt.Sprint(want) {
+		t.Errorf("want syncs after lines %v, got %v", want, sc.synced)
+	}
+}
+
+func TestChange(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+
+	for format, want := range map[OutputFormat]string{
+		FormatText:	stubApp + `: user.email changed: old=a@example.com new=b@example.com` + "\n" +
+			stubApp + `: user.name changed: old="" new="John Doe" admin=true` + "\n" +
+			stubApp + `: user.timeout changed: old=1s new=1m30s` + "\n",
+		FormatJSON:	`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"user.email changed: old=a@example.com new=b@example.com",` +
+				`"entity":"user","field":"email","old":"a@example.com","new":"b@example.com"}` + "\n" +
+			`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"user.name changed: old=\"\" new=\"John Doe\"",` +
+				`"admin":true,"entity":"user","field":"name","old":"","new":"John Doe"}` + "\n" +
+			`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"user.timeout changed: old=1s new=1m30s",` +
+				`"entity":"user","field":"timeout","old":"1s","new":"1m30s"}` + "\n",
+	} {
+		buf := &strings.Builder{}
+
+		opts := DefaultOptions()
+		opts.Writer = buf
+		opts.Prefix = stubApp
+		opts.Flags = NoPID
+		opts.Clock = func() time.Time { return now }
+		opts.OutputFormat = format
+
+		if err := OpenWithOptions(opts); err != nil {
+			t.Errorf("cannot open log on the writer: %v", err)
+			t.FailNow()
+		}
+
+		Change("user", "email", "a@example.com", "b@example.com")
+		With("admin", true).Change("user", "name", "", "John Doe")
+		Change("user", "timeout", time.Second, 90 * time.Second)
+		// Unchanged values are skipped
+		Change("user", "email", "b@example.com", "b@example.com")
+		Change("user", "groups", []string{"wheel"}, []string{"wheel"})
+		Change("user", "manager", nil, nil)
+		// Structs are comparable, but their interface fields can hold not comparable values
+		Change("user", "settings", changeSettings{Value: []string{"a"}}, changeSettings{Value: []string{"a"}})
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log on the writer: %v", err)
+		}
+
+		if buf.String() != want {
+			t.Errorf("want %q, got %q", want, buf.String())
+		}
+	}
+
+	if unchanged(changeSettings{Value: []string{"a"}}, changeSettings{Value: []string{"b"}}) {
+		t.Errorf("different slices in interface fields are reported as unchanged")
+	}
+	if unchanged(1, int64(1)) {
+		t.Errorf("values of different types are reported as unchanged")
+	}
+}
+
+// changeSettings is the comparable struct that can hold not comparable values
+type changeSettings struct {
+	Value	any
+}
+
+func TestStackTrace(t *testing.T) {
+	for _, format := range []OutputFormat{FormatText, FormatJSON} {
+		buf := &strings.Builder{}
+
+		opts := DefaultOptions()
+		opts.Writer = buf
+		opts.Prefix = stubApp
+		opts.Flags = NoPID
+		opts.OutputFormat = format
+
+		if err := OpenWithOptions(opts); err != nil {
+			t.Errorf("cannot open log on the writer: %v", err)
+			t.FailNow()
+		}
+		SetMirrorWriter(io.Discard)
+
+		SetStackTrace(LevelError)
+		Info(stubLogFormat, 0, `INFO`)
+		Err(stubLogFormat, 1, `ERROR ` + errIsOk)
+		SetStackTrace(StackTraceOff)
+		Err(stubLogFormat, 2, `ERROR ` + errIsOk)
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log on the writer: %v", err)
+		}
+
+		// The first frame is the function that wrote the message
+		frame, next := pkgFuncPrefix + "TestStackTrace (", "\n\ttesting.tRunner ("
+		if format == FormatJSON {
+			frame, next = `"` + StackKey + `":["` + frame, `,"testing.tRunner (`
+		}
+
+		// Texts of messages start with the number of the test, stack traces follow them
+		lines := strings.Split(buf.String(), "Test #")[1:]
+		if len(lines) != 3 {
+			t.Errorf("want 3 messages, got %q", buf.String())
+			continue
+		}
+		if strings.Contains(lines[0], frame) || strings.Contains(lines[2], frame) {
+			t.Errorf("want no stack traces below the level or when disabled, got %q", buf.String())
+		}
+		if i := strings.Index(lines[1], frame); i == -1 {
+			t.Errorf("want the stack trace from %q after the message, got %q", frame, lines[1])
+		} else if !strings.Contains(lines[1][i:], "log_test.go:") || !strings.Contains(lines[1][i:], next) {
+			t.Errorf("unexpected frames of the stack trace: %q", lines[1][i:])
+		}
+	}
+}
+
+func TestMaxFields(t *testing.T) {
+	buf := &strings.Builder{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.MaxFields = 2
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	l := With("a", 1, "b", 2)
+	l.Info(stubLogFormat, 0, `INFO`)
+	l = l.With("c", 3)
+	l.Info(stubLogFormat, 1, `INFO`)
+	l.With("d", 4, "e", 5).Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + " a=1 b=2\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + " fields_truncated=true b=2 c=3\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + " fields_truncated=true d=4 e=5\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestHostname(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Skipf("cannot get hostname: %v", err)
+	}
+
+	buf := &strings.Builder{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Hostname = true
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	SetHostnameValue("container")
+	if err := SetFlags(NoPID); err != nil {
+		t.Errorf("cannot set flags: %v", err)
+	}
+	Info(stubLogFormat, 1, `INFO`)
+
+	// The hostname is the attribute of JSON objects
+	SetOutputFormat(FormatJSON)
+	SetTimeFormat("2006")
+	Info(stubLogFormat, 2, `INFO`)
+	SetHostname(false)
+	Info(stubLogFormat, 3, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	year := time.Now().Format("2006")
+	want := host + " " + stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		"container " + stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n" +
+		`{"time":"` + year + `","level":"INFO","app":"` + stubApp + `","host":"container","msg":"` +
+			fmt.Sprintf(stubLogFormat, 2, `INFO`) + `"}` + "\n" +
+		`{"time":"` + year + `","level":"INFO","app":"` + stubApp + `","msg":"` +
+			fmt.Sprintf(stubLogFormat, 3, `INFO`) + `"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestFileHeader(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "header.log")
+
+	opts := DefaultOptions()
+	opts.File = logFile
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.FileHeader = func() string { return "# format: v1" }
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+	// The file is not empty, no header is written
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log file: %v", err)
+	}
+	Info(stubLogFormat, 1, `INFO`)
+
+	backup, err := RotateNow()
+	if err != nil {
+		t.Errorf("cannot rotate log file: %v", err)
+	}
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	for file, want := range map[string]string{
+		backup:		"# format: v1\n" +
+			stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+			stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n",
+		logFile:	"# format: v1\n" +
+			stubApp + ": " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + "\n",
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Errorf("cannot read log file: %v", err)
+		}
+		if string(data) != want {
+			t.Errorf("want %q in %s, got %q", want, file, data)
+		}
+	}
+}
+
+func TestFieldTypes(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	msg := fmt.Sprintf(stubLogFormat, 0, `INFO`)
+
+	for ft, want := range map[FieldTypes]string{
+		FieldsNative:		`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"` + msg + `","count":3,"ok":true,"user":"root"}` + "\n",
+		FieldsAllStrings:	`{"time":"2022-11-08T10:00:00.000Z","level":"INFO","app":"` + stubApp + `","msg":"` + msg + `","count":"3","ok":"true","user":"root"}` + "\n",
+	} {
+		buf := &strings.Builder{}
+
+		opts := DefaultOptions()
+		opts.Writer = buf
+		opts.Prefix = stubApp
+		opts.Flags = NoPID
+		opts.Clock = func() time.Time { return now }
+		opts.OutputFormat = FormatJSON
+		opts.FieldTypes = ft
+
+		if err := OpenWithOptions(opts); err != nil {
+			t.Errorf("cannot open log on the writer: %v", err)
+			t.FailNow()
+		}
+
+		With("count", 3, "ok", true, "user", "root").Info(stubLogFormat, 0, `INFO`)
+
+		if err := Close(); err != nil {
+			t.Errorf("cannot close log on the writer: %v", err)
+		}
+
+		if buf.String() != want {
+			t.Errorf("want %q, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestSetPrefix(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+	if err := SetPrefix("app-v2"); err != nil {
+		t.Errorf("cannot set prefix: %v", err)
+	}
+	Info(stubLogFormat, 1, `INFO`)
+
+	// Reopen keeps the new prefix and applies the separator
+	SetPrefixSeparator(" | ")
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := SetPrefix(""); err != nil {
+		t.Errorf("cannot set prefix: %v", err)
+	}
+	Info(stubLogFormat, 3, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		"app-v2: " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n" +
+		"app-v2 | " + fmt.Sprintf(stubLogFormat, 2, `INFO`) + "\n" +
+		fmt.Sprintf(stubLogFormat, 3, `INFO`) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestFlush(t *testing.T) {
+	logFile := filepath.Join(tempDir(), "flush.log")
+
+	if err := Open(logFile, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+
+	for i := 0; i < 3; i++ {
+		Info(stubLogFormat, i, `INFO`)
+	}
+	Raw(LevelInfo, []byte("raw message"))
+
+	for _, want := range []int{4, 0} {
+		n, err := Flush()
+		if err != nil {
+			t.Errorf("cannot flush log: %v", err)
+		}
+		if n != want {
+			t.Errorf("want %d flushed messages, got %d", want, n)
+		}
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	// Nothing to flush in the synchronous mode
+	if err := OpenSync(logFile, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log file: %v", err)
+		t.FailNow()
+	}
+	Info(stubLogFormat, 4, `INFO`)
+	if n, err := Flush(); n != 0 || err != nil {
+		t.Errorf("want no flushed messages in the synchronous mode, got %d (err: %v)", n, err)
+	}
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+}
+
+func TestRunSeparator(t *testing.T) {
+	buf := &strings.Builder{}
+
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Prefix = stubApp
+	opts.Flags = NoPID
+	opts.Clock = func() time.Time { return now }
+	opts.RunSeparator = "==== new run {time} pid={pid} ===="
+
+	if err := OpenWithOptions(opts); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+	// No separator on reopening
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	Info(stubLogFormat, 1, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := "==== new run 2022-11-08T10:00:00.000Z pid=" + strconv.Itoa(os.Getpid()) + " ====\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestLevelTag(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+
+	SetDebug(true)
+	SetLevelTag(LevelDebug, "")
+	SetLevelTag(LevelInfo, "<INF>")
+	Debug(stubLogFormat, 0, `DEBUG`)
+	Info(stubLogFormat, 1, `INFO`)
+
+	ResetLevelTag(LevelDebug)
+	Debug(stubLogFormat, 2, `DEBUG`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": " + fmt.Sprintf(stubLogFormat, 0, `DEBUG`) + "\n" +
+		stubApp + ": <INF> " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n" +
+		stubApp + ": <D> " + fmt.Sprintf(stubLogFormat, 2, `DEBUG`) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestLevelWidth(t *testing.T) {
+	buf := &strings.Builder{}
+
+	if err := OpenWriter(buf, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+
+	SetDebug(true)
+	SetLevelWidth(7)
+	SetLevelTag(LevelWarn, "")
+	Debug(stubLogFormat, 0, `DEBUG`)
+	Info(stubLogFormat, 1, `INFO`)
+	Warn(stubLogFormat, 2, `WARNING`)
+	Fatal(stubLogFormat, 3, `FATAL`)
+
+	// Tags longer than the width are not cut
+	SetLevelWidth(3)
+	Err(stubLogFormat, 4, `ERROR ` + errIsOk)
+
+	SetLevelWidth(0)
+	Info(stubLogFormat, 5, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	want := stubApp + ": <D>     " + fmt.Sprintf(stubLogFormat, 0, `DEBUG`) + "\n" +
+		stubApp + ": <INF>   " + fmt.Sprintf(stubLogFormat, 1, `INFO`) + "\n" +
+		stubApp + ":         " + fmt.Sprintf(stubLogFormat, 2, `WARNING`) + "\n" +
+		stubApp + ": <FATAL> " + fmt.Sprintf(stubLogFormat, 3, `FATAL`) + "\n" +
+		stubApp + ": <ERR> " + fmt.Sprintf(stubLogFormat, 4, `ERROR ` + errIsOk) + "\n" +
+		stubApp + ": " + fmt.Sprintf(stubLogFormat, 5, `INFO`) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestErrorBurstCallback(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+
+	if err := OpenWriter(io.Discard, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	SetMirrorWriter(io.Discard)
+	SetClock(func() time.Time { return now })
+
+	bursts := 0
+	SetErrorBurstCallback(time.Minute, func() {
+		bursts++
+	})
+
+	for _, step := range []struct {
+		after	time.Duration
+		level	Level
+		want	int
+	}{
+		{0, LevelError, 1},					// the first error
+		{30 * time.Second, LevelError, 1},	// the same burst
+		{40 * time.Second, LevelWarn, 1},	// not an error
+		{10 * time.Second, LevelError, 1},	// still within the quiet period after the previous error
+		{time.Minute, LevelInfo, 1},
+		{0, LevelFatal, 2},					// the quiet period has passed
+	} {
+		now = now.Add(step.after)
+		Msg(step.level, stubLogFormat, 0, step.level.String())
+		if err := Barrier(); err != nil {
+			t.Errorf("cannot sync log: %v", err)
+		}
+		if bursts != step.want {
+			t.Errorf("want %d bursts after %v message, got %d", step.want, step.level, bursts)
+		}
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+}
+
+// notBlocked fails the test if fn does not return in time
+func notBlocked(t *testing.T, name string, fn func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("%s is blocked", name)
+		t.FailNow()
+	}
+}
+
+func TestSettersBeforeOpen(t *testing.T) {
+	for _, tc := range []struct {
+		name	string
+		set		func(l *Logger)
+		write	func(l *Logger)
+		want	string
+	}{
+		{
+			name:	"SetVersion",
+			set:	func(l *Logger) { l.SetVersion("1.4.2") },
+			want:	stubApp + `: {1.4.2} ` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n",
+		},
+		{
+			name:	"SetSchemaVersion",
+			set:	func(l *Logger) { l.SetSchemaVersion("2"); l.SetOutputFormat(FormatJSON); l.SetTimeFormat("2006") },
+			want:	`{"schema":"2","time":"` + time.Now().Format("2006") + `","level":"INFO","app":"` + stubApp +
+				`","msg":"` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + `"}` + "\n",
+		},
+		{
+			name:	"ElevateLevel",
+			set:	func(l *Logger) { l.ElevateLevel(LevelDebug, time.Hour) },
+			write:	func(l *Logger) { l.Debug(stubLogFormat, 0, `DEBUG`) },
+			want:	stubApp + `: <D> ` + fmt.Sprintf(stubLogFormat, 0, `DEBUG`) + "\n",
+		},
+		{
+			name:	"SetLevelTag",
+			set:	func(l *Logger) { l.SetLevelTag(LevelInfo, "<I>") },
+			want:	stubApp + `: <I> ` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n",
+		},
+		{
+			name:	"SetExitFunc",
+			set:	func(l *Logger) { l.SetMirrorWriter(io.Discard); l.SetExitFunc(func(int) {}) },
+			write:	func(l *Logger) { l.Fatal(stubLogFormat, 0, `FATAL`) },
+			want:	stubApp + `: <FATAL> ` + fmt.Sprintf(stubLogFormat, 0, `FATAL`) + "\n",
+		},
+		{
+			name:	"SetEntryProcessor",
+			set:	func(l *Logger) { l.SetEntryProcessor(func(e *Entry) { e.Message = "processed" }) },
+			want:	stubApp + `: processed` + "\n",
+		},
+		{
+			name:	"SetFieldOrder",
+			set:	func(l *Logger) { l.SetFieldOrder([]string{"b", "a"}) },
+			write:	func(l *Logger) { l.With("a", 1, "b", 2).Info(stubLogFormat, 0, `INFO`) },
+			want:	stubApp + `: ` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + ` b=2 a=1` + "\n",
+		},
+	} {
+		for _, syncMode := range []bool{false, true} {
+			buf := &strings.Builder{}
+
+			l := NewLogger()
+			l.SetSync(syncMode)
+			notBlocked(t, tc.name, func() { tc.set(l) })
+
+			if err := l.OpenWriter(buf, stubApp, NoPID); err != nil {
+				t.Errorf("cannot open log on the writer: %v", err)
+				t.FailNow()
+			}
+
+			if tc.write != nil {
+				tc.write(l)
+			} else {
+				l.Info(stubLogFormat, 0, `INFO`)
+			}
+
+			if err := l.Close(); err != nil {
+				t.Errorf("cannot close log on the writer: %v", err)
+			}
+
+			if buf.String() != tc.want {
+				t.Errorf("%s (sync mode %t): want %q, got %q", tc.name, syncMode, tc.want, buf.String())
+			}
+			if n := l.Dropped(); n != 0 {
+				t.Errorf("%s (sync mode %t): %d messages dropped", tc.name, syncMode, n)
+			}
+		}
+	}
+
+	// Thresholds of flushing are applied to the target opened after setting
+	sc := &syncCounter{}
+	l := NewLogger()
+	notBlocked(t, "SetFlushThreshold", func() { l.SetFlushThreshold(2) })
+	if err := l.OpenWriter(sc, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on the writer: %v", err)
+		t.FailNow()
+	}
+	for i := 0; i < 4; i++ {
+		l.Info(stubLogFormat, i, `INFO`)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+	if len(sc.synced) < 2 || sc.synced[0] != 2 || sc.synced[1] != 4 {
+		t.Errorf("want syncs after 2 and 4 lines, got %v", sc.synced)
+	}
+}
+
+func TestSettersWhileLogging(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Writer = io.Discard
+	opts.StderrMirror = MirrorNever
+	openWithStubOptions(t, opts)
+
+	// Settings used by the writer goroutine are changed while messages are written,
+	// the race detector complains about settings that are not changed by the writer goroutine
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			With("took", time.Second, "at", time.Now()).Err(stubLogFormat, i, `ERROR ` + errIsOk)
+		}
+	}()
+
+	for _, set := range []func(){
+		func() { SetSeverityStyle(SeverityCompact) },
+		func() { SetCompactLevels(false) },
+		func() { SetLevelWidth(5) },
+		func() { SetStderrMirror(MirrorNever) },
+		func() { SetDurationFormat(DurationNanos) },
+		func() { SetTimeFormat(time.RFC3339) },
+		func() { SetOutputFormat(FormatJSON) },
+		func() { SetFieldTypes(FieldsAllStrings) },
+		func() { SetStrictFormat(true) },
+		func() { SetMirrorWriter(io.Discard) },
+		func() { SetWriteRetry(1, time.Millisecond) },
+		func() { SetMaxMessageBytes(16) },
+		func() { SetStackTrace(StackTraceOff) },
+	} {
+		for i := 0; i < 10; i++ {
+			set()
+		}
+	}
+	<-done
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+}
+
+func TestLeveledFiles(t *testing.T) {
+	dir := tempDir()
+	debugFile := filepath.Join(dir, "debug.log")
+	appFile := filepath.Join(dir, "app.log")
+	errFile := filepath.Join(dir, "errors.log")
+
+	opts := DefaultOptions()
+	opts.File = filepath.Join(dir, "main.log")
+	opts.Level = LevelDebug
+	opts.Clock = func() time.Time { return time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC) }
+
+	openWithStubOptions(t, opts)
+
+	// Files that cannot be opened are reported all together, nothing is changed
+	err := SetLeveledFiles(map[Level]string{
+		LevelInfo:	appFile,
+		LevelWarn:	filepath.Join(dir, "missing", "warn.log"),
+		LevelError:	filepath.Join(dir, "missing", "errors.log"),
+	})
+	var fe *FileError
+	var errs FileErrors
+	switch {
+	case !errors.As(err, &fe):
+		t.Errorf("want FileError, got %v", err)
+	case !errors.As(err, &errs) || len(errs) != 2:
+		t.Errorf("want errors of 2 files, got %v", err)
+	case !errors.Is(err, fs.ErrNotExist):
+		t.Errorf("want fs.ErrNotExist, got %v", err)
+	}
+
+	if err := SetLeveledFiles(map[Level]string{
+		LevelDebug:	debugFile,
+		LevelInfo:	appFile,
+		LevelError:	errFile,
+	}); err != nil {
+		t.Errorf("cannot set leveled files: %v", err)
+	}
+
+	D(stubLogFormat, 0, `DEBUG`)
+	I(stubLogFormat, 1, `INFO`)
+	Err(stubLogFormat, 2, `ERROR ` + errIsOk)
+
+	// Leveled files are rotated together with the log file
+	if _, err := RotateNow(); err != nil {
+		t.Errorf("RotateNow failed: %v", err)
+	}
+	Err(stubLogFormat, 3, `ERROR ` + errIsOk)
+
+	// Leveled files are reopened together with the log file
+	if err := os.Rename(appFile, appFile + ".1"); err != nil {
+		t.Errorf("cannot rename leveled file: %v", err)
+	}
+	if err := Reopen(); err != nil {
+		t.Errorf("cannot reopen log: %v", err)
+	}
+	I(stubLogFormat, 4, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log file: %v", err)
+	}
+
+	debugLine := stubApp + `: <D> Test #0 - DEBUG log message` + "\n"
+	infoLine := stubApp + `: Test #1 - INFO log message` + "\n"
+	errLine := func(n int) string {
+		return stubApp + `: <ERR> Test #` + strconv.Itoa(n) + ` - ERROR ` + errIsOk + ` log message` + "\n"
+	}
+	checkLogFiles(t, map[string]string{
+		filepath.Join(dir, "debug-2022-11-08.log"):		debugLine + infoLine + errLine(2),
+		filepath.Join(dir, "app-2022-11-08.log"):		infoLine + errLine(2),
+		filepath.Join(dir, "errors-2022-11-08.log"):	errLine(2),
+		debugFile:										errLine(3) + stubApp + `: Test #4 - INFO log message` + "\n",
+		appFile + ".1":									errLine(3),
+		appFile:										stubApp + `: Test #4 - INFO log message` + "\n",
+		errFile:										errLine(3),
+	})
+}
+
+func TestVersion(t *testing.T) {
+	buf := &strings.Builder{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.Version = "1.4.2"
+
+	openWithStubOptions(t, opts)
+
+	Info(stubLogFormat, 0, `INFO`)
+	Component("db").Err(stubLogFormat, 1, `ERROR ` + errIsOk)
+
+	SetOutputFormat(FormatJSON)
+	SetTimeFormat("2006")
+	Info(stubLogFormat, 2, `INFO`)
+
+	// The empty version disables writing of the version
+	SetVersion("")
+	Info(stubLogFormat, 3, `INFO`)
+	SetOutputFormat(FormatText)
+	Info(stubLogFormat, 4, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	year := time.Now().Format("2006")
+	want := stubApp + `: {1.4.2} ` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		stubApp + `: {1.4.2} [db] <ERR> ` + fmt.Sprintf(stubLogFormat, 1, `ERROR ` + errIsOk) + "\n" +
+		`{"time":"` + year + `","level":"INFO","app":"` + stubApp + `","version":"1.4.2","msg":"` +
+			fmt.Sprintf(stubLogFormat, 2, `INFO`) + `"}` + "\n" +
+		`{"time":"` + year + `","level":"INFO","app":"` + stubApp + `","msg":"` +
+			fmt.Sprintf(stubLogFormat, 3, `INFO`) + `"}` + "\n" +
+		stubApp + `: ` + fmt.Sprintf(stubLogFormat, 4, `INFO`) + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestElevateLevel(t *testing.T) {
+	now := time.Date(2022, 11, 8, 10, 0, 0, 0, time.UTC)
+
+	// Timers are fired by the test
+	type timer struct {
+		d	time.Duration
+		f	func()
+	}
+	var timers []timer
+	origAfterFunc := afterFunc
+	defer func() { afterFunc = origAfterFunc }()
+	afterFunc = func(d time.Duration, f func()) func() bool {
+		timers = append(timers, timer{d, f})
+		return func() bool { return true }
+	}
+
+	opts := DefaultOptions()
+	opts.Writer = io.Discard
+	opts.Clock = func() time.Time { return now }
+
+	openWithStubOptions(t, opts)
+
+	ElevateLevel(LevelDebug, 5 * time.Minute)
+	if !LevelEnabled(LevelDebug) {
+		t.Errorf("level is not elevated")
+	}
+
+	// Elevating again restarts the timer, the level set before the first elevation is restored
+	now = now.Add(time.Minute)
+	ElevateLevel(LevelWarn, 5 * time.Minute)
+	if LevelEnabled(LevelInfo) {
+		t.Errorf("level is not changed by the second elevation")
+	}
+	if len(timers) != 2 || timers[1].d != 5 * time.Minute {
+		t.Errorf("want 2 timers of 5m, got %v", timers)
+		t.FailNow()
+	}
+
+	// The timer of the replaced elevation does nothing
+	now = now.Add(4 * time.Minute)
+	timers[0].f()
+	if LevelEnabled(LevelInfo) {
+		t.Errorf("level is restored by the replaced elevation")
+	}
+
+	// The timer fired before the clock reaches the end of the elevation is restarted
+	timers[1].f()
+	if LevelEnabled(LevelInfo) || len(timers) != 3 || timers[2].d != time.Minute {
+		t.Errorf("want the restarted timer of 1m, got %v", timers)
+		t.FailNow()
+	}
+
+	now = now.Add(time.Minute)
+	timers[2].f()
+	if !LevelEnabled(LevelInfo) || LevelEnabled(LevelDebug) {
+		t.Errorf("level is not restored")
+	}
+
+	// SetLevel cancels restoring of the level
+	ElevateLevel(LevelDebug, time.Minute)
+	SetLevel(LevelError)
+	now = now.Add(time.Minute)
+	timers[len(timers)-1].f()
+	if LevelEnabled(LevelWarn) || !LevelEnabled(LevelError) {
+		t.Errorf("level set by SetLevel is not kept")
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	buf := &strings.Builder{}
+
+	opts := DefaultOptions()
+	opts.Writer = buf
+	opts.SchemaVersion = "2"
+
+	openWithStubOptions(t, opts)
+
+	// The text format is not affected
+	Info(stubLogFormat, 0, `INFO`)
+
+	SetOutputFormat(FormatJSON)
+	SetTimeFormat("2006")
+	With("user", "root").Info(stubLogFormat, 1, `INFO`)
+
+	// The empty version omits the key
+	SetSchemaVersion("")
+	Info(stubLogFormat, 2, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on the writer: %v", err)
+	}
+
+	year := time.Now().Format("2006")
+	want := stubApp + `: ` + fmt.Sprintf(stubLogFormat, 0, `INFO`) + "\n" +
+		`{"schema":"2","time":"` + year + `","level":"INFO","app":"` + stubApp + `","msg":"` +
+			fmt.Sprintf(stubLogFormat, 1, `INFO`) + `","user":"root"}` + "\n" +
+		`{"time":"` + year + `","level":"INFO","app":"` + stubApp + `","msg":"` +
+			fmt.Sprintf(stubLogFormat, 2, `INFO`) + `"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
 func TestError(t *testing.T) {
 	const testErr = "test OpError"
 
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MetricsSnapshot is the snapshot of internal counters of the logger, see [Metrics].
+type MetricsSnapshot struct {
+	// Messages passed to the writer goroutine and not received by it yet: current and peak numbers
+	Queued		int64
+	QueuedPeak	int64
+	// Messages spilled to the disk and not written yet (see SpillToDisk): current and peak numbers
+	Spooled		int64
+	SpooledPeak	int64
+
+	// Messages dropped because logging was paused or the log was closed, see Dropped
+	Dropped				uint64
+	// Messages dropped by rate limits of all levels, see RateLimited
+	RateLimited			uint64
+	// Messages dropped by the adaptive sampling, see AdaptiveSampled
+	Sampled				uint64
+	// Entries dropped because subscribers were too slow, see SubscribeDropped
+	SubscribeDropped	uint64
+
+	// Messages written to the log by levels, e.g. Written[LevelError], custom levels are counted
+	// with the built-in level whose statistic function they call (see RegisterLevel)
+	Written	[LevelFatal + 1]uint64
+
+	// Average and maximum durations of writing messages, see WriteLatency
+	WriteAvg	time.Duration
+	WriteMax	time.Duration
+}
+
+// Metrics calls [Metrics] on the l object.
+func (l *Logger) Metrics() MetricsSnapshot {
+	m := MetricsSnapshot{
+		Queued:				atomic.LoadInt64(&l.queued),
+		QueuedPeak:			atomic.LoadInt64(&l.queuedPeak),
+		Spooled:			atomic.LoadInt64(&l.spoolBacklog),
+		SpooledPeak:		atomic.LoadInt64(&l.spoolPeak),
+		Dropped:			atomic.LoadUint64(&l.dropped),
+		Sampled:			atomic.LoadUint64(&l.adaptDropped),
+		SubscribeDropped:	atomic.LoadUint64(&l.subsDropped),
+	}
+
+	for level := range m.Written {
+		m.Written[level] = atomic.LoadUint64(&l.levelWrites[level])
+	}
+
+	l.limitsMu.Lock()
+	for _, rl := range l.limits {
+		m.RateLimited += atomic.LoadUint64(&rl.dropped)
+	}
+	l.limitsMu.Unlock()
+
+	m.WriteAvg, m.WriteMax = l.WriteLatency()
+
+	return m
+}
+
+// queue sends the message to the writer goroutine, counting messages waiting for it
+func (l *Logger) queue(event *logMsg) {
+	l.addQueued(1)
+	l.msgCh<-event
+}
+
+// addQueued changes the number of messages passed to the writer goroutine, keeping the peak number
+func (l *Logger) addQueued(delta int64) {
+	updatePeak(&l.queuedPeak, atomic.AddInt64(&l.queued, delta))
+}
+
+// updatePeak stores v to the peak if it is greater
+func updatePeak(peak *int64, v int64) {
+	for {
+		old := atomic.LoadInt64(peak)
+		if v <= old || atomic.CompareAndSwapInt64(peak, old, v) {
+			return
+		}
+	}
+}
+
+// countWritten counts the message written to the log
+func (l *Logger) countWritten(level Level) {
+	atomic.AddUint64(&l.levelWrites[level.statLevel()], 1)
+}
+
+// Metrics returns the snapshot of internal counters of the log: numbers of messages waiting for
+// the writer goroutine and spilled to the disk (with peak values since opening the log, to right-size
+// buffers, see [SpillToDisk]), numbers of dropped messages by reasons, numbers of written messages
+// by levels and the write latency. Counters are updated atomically, so getting the snapshot does not
+// block writing of messages, but values of different counters may be off by messages written during
+// the call. In the synchronous mode (see [OpenSync]) messages are never queued.
+func Metrics() MetricsSnapshot {
+	return logger.Metrics()
+}
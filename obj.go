@@ -1,49 +1,258 @@
 package log
 
 import (
+	"bytes"
+	"context"
 	"log"
 	"os"
 	"fmt"
 	"io"
 	"errors"
+	"sync"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
 // Private constants
 const (
 	logFlagsAlways	=	log.Lmsgprefix
 	defaultPermMode	=	0o644
+	errorsBufSize	=	64
 )
 
 // ErrLogClosed returned when Close is called on a closed or never opened log-file
 var ErrLogClosed	=	OpError{errors.New("log already closed/not opened yet")}
 
+// ErrNotRotatable returned by RotateNow when the log target is not a regular file
+var ErrNotRotatable	=	OpError{errors.New("log target is not a regular file, it cannot be rotated")}
+
 // Private types
 type logMsg struct {
+	level Level
 	format string
 	args []any
-	fatal bool
+	fields []Field
+	extra []Field	// fields written only to structured entries
+	warning error	// passed to the error handler on writing
+	component string
+	caller string
+	stack []string	// stack trace of the call site, see SetStackTrace
+	time time.Time
+	timeSet bool	// time is set by the caller, not by the logger
+	raw []byte
 	done chan bool
+	// The program has to be terminated by exitFn after writing, set by the writer for callers waiting on done
+	exiting bool
+	exitFn func(code int)
+
+	// Function to execute in the writer goroutine instead of writing the message
+	exec func()
 }
 
 // A Logger represents an active logging object that generates lines of output to file
 // specified by the file parameter of the [Logger.Open] function. Each logging operation
 // makes a single call to the Writer's Write method. A Logger can be used simultaneously
 // from multiple goroutines; it guarantees to serialize access to the log file.
+//
+// Child loggers created by [Logger.With] share the log target and configuration
+// with the parent logger, but add their own fields to messages.
 type Logger struct {
+	*logCore
+
+	// Fields added to each message written by the logger
+	fields	[]Field
+	// Name of the component, see Component
+	component	string
+}
+
+// logCore contains the log target and the configuration shared by the logger and its children
+type logCore struct {
+	// Counter of dropped messages and write latency statistics, accessed atomically,
+	// so they must be the first to be 64-bit aligned on 32-bit platforms
+	dropped		uint64
+	writes		uint64
+	unflushed	int		// messages written since the last Flush, used only by the writer
+	// Number of messages that triggers flushing and the number of messages written since the last sync,
+	// used only by the writer, see SetFlushThreshold
+	flushThreshold	int
+	pendingFlush	int
+	writesTime	int64
+	writesMax	int64
+	// Messages written by levels and numbers of queued messages, see Metrics
+	levelWrites	[LevelFatal + 1]uint64
+	queued		int64
+	queuedPeak	int64
+	spoolPeak	int64
+
 	logger		*log.Logger
 	logName		string
 	origPrefix	string
 	logPrefix	string
+	prefixSep	string
 	logFlags	int
-	debug		bool
+	level		int32	// accessed atomically
 	closed		bool
+	skipEmpty	bool
+	sevStyle	SeverityStyle
+	levelWidth	int
+	levelTags	map[Level]string
+	// Coloring of level tags, whether the log target is a terminal and colors of levels, see SetColors
+	colors		bool
+	colorTerm	bool
+	levelColors	map[Level]Color
+	callerFunc	bool
+	goroutineID	bool
+	// Minimal level of messages with stack traces, accessed atomically by callers, see SetStackTrace
+	stackLevel	int32
+	withHost	bool
+	fileHeader	func() string
+	runSep		string
+	outFormat	OutputFormat
+	fieldTypes	FieldTypes
+	hostValue	string
+	// Version of the program and its tag in text lines, see SetVersion
+	version		string
+	versionTag	string
+	// Version of the schema of JSON objects, see SetSchemaVersion
+	schemaVer	string
+	maxFields	int
+	maxMsgBytes	int
+	// Order of keys of fields and their positions in it, see SetFieldOrder
+	fieldOrder	[]string
+	fieldRanks	map[string]int
+	confirmWrites	bool
+	syncOnReopen	bool
+	strictFormat	bool
+	paused		int32	// accessed atomically
 
 	msgCh		chan *logMsg
 	stpStrCh	chan any
+	// Closed when the writer stopped by the abandoned stopWriterContext is started again
+	restarted	chan struct{}
+
+	// Synchronous mode, messages are written under the mutex without the writer goroutine
+	syncMode	bool
+	mu			sync.Mutex
+
+	// Function to open a non-file log target, nil means the regular file or DefaultLog
+	opener		func() (io.Writer, error)
+	// Log target and buffer to format messages before writing to the target
+	target		io.Writer
+	line		bytes.Buffer
+	// Attributes of the message in the JSON format, reused to not to allocate them for each message
+	jsonAttrs	[]jsonAttr
+
+	// Write errors handling
+	errHandler		ErrorHandler
+	reconnectOnErr	bool
+	retryAttempts	int
+	retryBackoff	time.Duration
+	fallbackName	string
+	fallbackFd		File
+	fallbackOpened	string	// name of the opened fallback file
+	// Fallback logger and whether messages are rerouted to it, see SetFallback
+	fallbackLog		*Logger
+	failedOver		bool
+	// Rules of escalation of levels of messages, see AddEscalationRule
+	escalations		[]escalationRule
+	// Function that terminates the program after fatal messages, nil means os.Exit
+	exitFunc		func(code int)
+	// Function that changes entries before formatting, see SetEntryProcessor
+	entryProc		func(*Entry)
+	// Detection of bursts of errors, see SetErrorBurstCallback
+	burstQuiet		time.Duration
+	burstFn			func()
+	lastErr			time.Time
+	// File system to open files, see SetFS
+	fsys			FS
+	// Access log, see SetAccessLogOutput
+	accessName		string
+	accessFd		File
+	accessFormat	AccessFormat
+
+	// Stack traces deduplication, used only by the writer
+	stacks		*stackDedup
+
+	// Formatting of fields values
+	durFormat	DurationFormat
+	timeFormat	string
+
+	// Age-based rotation, used only by the writer
+	rotAge		time.Duration
+	firstWrite	time.Time
+
+	// Source of the current time, nil means time.Now
+	clock		func() time.Time
+
+	// Source of trace and span IDs for *Ctx functions
+	traceExtractor	TraceExtractor
+
+	// Compress the log file on the fly
+	compressLive	bool
+
+	// Destination of duplicated error messages, nil means the standard logger
+	mirrorWriter	io.Writer
+
+	// Duplication of error messages to stderr, used only by the writer
+	stderrMode			MirrorMode
+	autoMirrorTarget	io.Writer
+	autoMirror			bool
+
+	// Additional writers, used only by the writer goroutine
+	sinks		[]*sink
+	// Files of levels, also included to additional writers, see SetLeveledFiles
+	leveled		[]*leveledFile
+
+	// Temporary level, see ElevateLevel
+	elevMu		sync.Mutex
+	elevated	*elevation
+
+	// Recent error messages, see SetRecentErrors
+	recentMu	sync.Mutex
+	recent		*recentRing
+
+	// Channel of errors, see Errors
+	errsMu		sync.Mutex
+	errsCh		chan error
+	errsClosed	bool
+
+	// Keys of messages written by Once
+	onceMu			sync.Mutex
+	onceSeen		map[string]time.Time
+	onceInterval	time.Duration
+
+	// Spool of messages, see SpillToDisk
+	spoolRef		atomic.Value	// *spool, loaded by callers, see loadSpool
+	spoolBacklog	int64	// number of spilled messages not written yet, accessed atomically
+
+	// Maximum duration of writing of queued messages by Close, see SetDrainTimeout
+	drainTimeout	time.Duration
+	drainDeadline	int64	// deadline of Close in Unix nanoseconds, accessed atomically
+
+	// Rate limits of levels
+	limitsMu	sync.Mutex
+	limits		map[Level]*rateLimit
+	// Function that returns the rate limit bucket of the entry, see SetSamplingKey
+	samplingKey	func(Entry) string
+	// Adaptive sampling, used only by the writer, and its counters accessed atomically, see SetAdaptiveSampling
+	adaptive		*adaptiveSampler
+	adaptEmitted	uint64
+	adaptDropped	uint64
+
+	// Subscribers to written entries
+	subsMu		sync.Mutex
+	subs		map[*subscriber]struct{}
+	subsDropped	uint64	// accessed atomically
 
 	// Statistic functions
 	errEventStat StatFunc
 	wrnEventStat StatFunc
+	infEventStat StatFunc
+	audEventStat StatFunc
+	errFmtStat FormattedStatFunc
+	wrnFmtStat FormattedStatFunc
 }
 
 //nolint:gochecknoglobals // Auxiliary variable to avoid tests termination on Fatal() function
@@ -56,57 +265,291 @@ var govetPrintfStub = false
 func NewLogger() *Logger {
 	// By default print log messages to default logger target
 	return &Logger{
-		logger: log.Default(),
-		closed:	true,
+		logCore: &logCore{
+			logger:		log.Default(),
+			closed:		true,
+			level:		atomic.LoadInt32(&initLevel),
+			prefixSep:	DefaultPrefixSeparator,
+			syncOnReopen:	true,
+			confirmWrites:	true,
+			timeFormat:	DefaultTimeFormat,
+			stackLevel:	int32(StackTraceOff),
+			fsys:		loadDefaultFS(),
+		},
+	}
+}
+
+// Clone returns a new unopened logger with the same configuration as l: level, flags, prefix,
+// fields and other settings. The log target and the writer goroutine are not copied, so the clone
+// has to be opened by one of Open methods, e.g. to write the same messages to a different file.
+// Setters of the clone can be called before opening it, settings are applied to the clone directly.
+// Statistic functions and the error handler are shared by reference, use the corresponding
+// setters of the clone to change them.
+func (l *Logger) Clone() *Logger {
+	c := &logCore{
+		logger:			log.Default(),
+		closed:			true,
+		level:			atomic.LoadInt32(&l.level),
+		origPrefix:		l.origPrefix,
+		logPrefix:		l.logPrefix,
+		prefixSep:		l.prefixSep,
+		logFlags:		l.logFlags,
+		skipEmpty:		l.skipEmpty,
+		sevStyle:		l.sevStyle,
+		levelWidth:		l.levelWidth,
+		colors:			l.colors,
+		callerFunc:		l.callerFunc,
+		goroutineID:	l.goroutineID,
+		stackLevel:		atomic.LoadInt32(&l.stackLevel),
+		withHost:		l.withHost,
+		fileHeader:		l.fileHeader,
+		runSep:			l.runSep,
+		outFormat:		l.outFormat,
+		fieldTypes:		l.fieldTypes,
+		hostValue:		l.hostValue,
+		version:		l.version,
+		versionTag:		l.versionTag,
+		schemaVer:		l.schemaVer,
+		maxFields:		l.maxFields,
+		fieldOrder:		l.fieldOrder,
+		fieldRanks:		l.fieldRanks,
+		maxMsgBytes:	l.maxMsgBytes,
+		confirmWrites:	l.confirmWrites,
+		syncOnReopen:	l.syncOnReopen,
+		strictFormat:	l.strictFormat,
+		syncMode:		l.syncMode,
+		drainTimeout:	l.drainTimeout,
+		flushThreshold:	l.flushThreshold,
+		errHandler:		l.errHandler,
+		reconnectOnErr:	l.reconnectOnErr,
+		retryAttempts:	l.retryAttempts,
+		retryBackoff:	l.retryBackoff,
+		fallbackName:	l.fallbackName,
+		fallbackLog:	l.fallbackLog,
+		durFormat:		l.durFormat,
+		timeFormat:		l.timeFormat,
+		rotAge:			l.rotAge,
+		clock:			l.clock,
+		fsys:			l.fsys,
+		traceExtractor:	l.traceExtractor,
+		samplingKey:	l.samplingKey,
+		entryProc:		l.entryProc,
+		exitFunc:		l.exitFunc,
+		burstQuiet:		l.burstQuiet,
+		burstFn:		l.burstFn,
+		compressLive:	l.compressLive,
+		mirrorWriter:	l.mirrorWriter,
+		stderrMode:		l.stderrMode,
+		errEventStat:	l.errEventStat,
+		wrnEventStat:	l.wrnEventStat,
+		infEventStat:	l.infEventStat,
+		audEventStat:	l.audEventStat,
+		errFmtStat:		l.errFmtStat,
+		wrnFmtStat:		l.wrnFmtStat,
+	}
+
+	// Tags of levels are copied to not to share the map
+	for level, tag := range l.levelTags {
+		if c.levelTags == nil {
+			c.levelTags = map[Level]string{}
+		}
+		c.levelTags[level] = tag
+	}
+	for level, color := range l.levelColors {
+		if c.levelColors == nil {
+			c.levelColors = map[Level]Color{}
+		}
+		c.levelColors[level] = color
+	}
+
+	// Rules are copied to not to share rules added later
+	c.escalations = append([]escalationRule(nil), l.escalations...)
+
+	// The adaptive sampler starts measuring the rate of messages of the clone from scratch
+	if l.adaptive != nil {
+		c.adaptive = newAdaptiveSampler(l.adaptive.target)
+	}
+
+	// Keys seen by Once are not shared
+	l.onceMu.Lock()
+	c.onceInterval = l.onceInterval
+	l.onceMu.Unlock()
+
+	// State of stack traces deduplication is not shared
+	if l.stacks != nil {
+		c.stacks = newStackDedup()
+	}
+
+	// Rate limits are copied with full buckets
+	l.limitsMu.Lock()
+	for level, rl := range l.limits {
+		if c.limits == nil {
+			c.limits = map[Level]*rateLimit{}
+		}
+		c.limits[level] = &rateLimit{n: rl.n, per: rl.per, tokens: float64(rl.n)}
 	}
+	l.limitsMu.Unlock()
+
+	fields := make([]Field, len(l.fields))
+	copy(fields, l.fields)
+
+	return &Logger{logCore: c, fields: fields, component: l.component}
 }
 
 // Open calls [Open] on the l object.
 func (l *Logger) Open(file, prefix string, flags int) error {
+	// Regular file or DefaultLog is used
+	l.opener = nil
+
+	return l.open(file, prefix, flags)
+}
+
+// OpenWriter calls [OpenWriter] on the l object.
+func (l *Logger) OpenWriter(w io.Writer, prefix string, flags int) error {
+	l.opener = func() (io.Writer, error) {
+		return w, nil
+	}
+
+	// Use empty log name to not to close the writer owned by the caller
+	return l.open(DefaultLog, prefix, flags)
+}
+
+// SetOutput calls [SetOutput] on the l object.
+func (l *Logger) SetOutput(w io.Writer) error {
+	// Check for log already closed
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	var syncErr, closeErr error
+
+	// Replace the target from the writer goroutine, after all queued messages are written
+	l.execute(func() {
+		syncErr = l.syncBeforeClose()
+		closeErr = l.closeTarget()
+
+		// The new writer is owned by the caller
+		l.opener = func() (io.Writer, error) {
+			return w, nil
+		}
+		l.logName = DefaultLog
+		l.target = w
+		l.colorTerm = isTerminal(w)
+
+		// Start counting the age of the log from the next write
+		l.firstWrite = time.Time{}
+	})
+
+	if syncErr != nil {
+		return syncErr
+	}
+
+	return closeErr
+}
+
+func (l *Logger) open(file, prefix string, flags int) error {
 	l.logName = file
 
+	// Use default flags if no flags are specified
+	if flags == NoFlags {
+		flags = int(atomic.LoadInt32(&defaultFlags))
+	}
+
 	l.setFlags(prefix, flags)
 
 	if err := l.openLog(); err != nil {
 		return err
 	}
 
+	// Mark the beginning of the new run, messages are not written yet
+	l.writeRunSeparator()
+
+	// In synchronous mode messages are written by callers, no writer goroutine is required
+	if l.syncMode {
+		l.flushPreOpen()
+		return nil
+	}
+
 	// Initiate channel to write logging data from a single point
 	l.msgCh = make(chan *logMsg)
 	// Stop/start channel
 	l.stpStrCh = make(chan interface{})
+
+	// Compressed data have to be flushed periodically
+	var flushCh <-chan time.Time
+	var ticker *time.Ticker
+	if l.compressed() {
+		ticker = time.NewTicker(compressFlushInterval)
+		flushCh = ticker.C
+	}
+
 	go func() {
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
 		for {
 			select {
 			// Wait for messages
 			case msg := <-l.msgCh:
-				if msg.fatal {
-					// XXX This condition is not satisfied only in tests
-					if fatalDoExit {
-						l.logger.Fatalf(msg.format, msg.args...)
-					}
+				l.addQueued(-1)
+				if msg.done != nil {
+					// Callers that wait for writing (e.g. control messages and messages that cannot
+					// be spilled) do not spill anything meanwhile, so their previous spilled messages
+					// are written before. Other messages are received before spilling of the next
+					// messages of their callers, so spilled messages must not be written before them
+					l.drainSpool()
 				}
-
-				// Write message to the log
-				l.logger.Printf(msg.format, msg.args...)
+				l.process(msg)
 
 				// Close the done channel in the message to notify the caller that the message is written
-				close(msg.done)
+				if msg.done != nil {
+					close(msg.done)
+				}
+
+			case <-flushCh:
+				l.flushTarget()
+
+			case <-l.spoolWake():
+				l.drainSpool()
 
 			case <-l.stpStrCh:
+				// Write spilled messages before stopping
+				l.drainSpool()
+
 				// Send signal that stop message was received
 				l.stpStrCh <- nil
 
-				// Wait for start message
-				<-l.stpStrCh
+				// Wait for start or quit message
+				if quit := <-l.stpStrCh; quit != nil {
+					return
+				}
 			}
 		}
 	}()
 
+	// Write messages buffered before opening
+	l.flushPreOpen()
+
 	// No errors
 	return nil
 }
 
+// SetSync enables (v == true) or disables the synchronous mode of the l object. In the synchronous mode,
+// messages are written directly by the goroutines that call logging functions, under the mutex,
+// instead of passing them to the writer goroutine through the channel. All other methods behave
+// the same way in both modes.
+//
+// The synchronous mode is simpler and cheaper for short-lived programs and tests,
+// no goroutine is started, so messages cannot be lost even if [Logger.Close] was not called.
+// The asynchronous (default) mode serializes writes through a single goroutine, that scales better
+// when many goroutines are writing messages concurrently.
+//
+// NOTE: SetSync must be called before opening the log.
+func (l *Logger) SetSync(v bool) {
+	l.syncMode = v
+}
+
 // Flags calls [Flags] on the l object.
 func (l *Logger) Flags() int {
 	return l.logFlags
@@ -120,9 +563,222 @@ func (l *Logger) SetFlags(flags int) error {
 	return l.Reopen()
 }
 
+// SetRunSeparator calls [SetRunSeparator] on the l object.
+func (l *Logger) SetRunSeparator(sep string) {
+	l.runSep = sep
+}
+
+// writeRunSeparator writes the separator line set by SetRunSeparator to the log target
+func (l *Logger) writeRunSeparator() {
+	if l.runSep == "" {
+		return
+	}
+
+	line, _ := l.renderTemplate(l.runSep, map[string]any{
+		"time":	l.now(),
+		"pid":	os.Getpid(),
+	})
+
+	l.output(LevelInfo, []byte(line + "\n"))
+}
+
+// SetPrefix calls [SetPrefix] on the l object.
+//
+// NOTE: SetPrefix must be called after calling l.Open, otherwise it will cause a panic.
+func (l *Logger) SetPrefix(prefix string) error {
+	l.origPrefix = prefix
+	return l.Reopen()
+}
+
+// SetPrefixSeparator calls [SetPrefixSeparator] on the l object.
+func (l *Logger) SetPrefixSeparator(sep string) {
+	l.prefixSep = sep
+}
+
 // SetDebug calls [SetDebug] on the l object.
 func (l *Logger) SetDebug(v bool) {
-	l.debug = v
+	if v {
+		l.SetLevel(LevelDebug)
+	} else {
+		l.SetLevel(LevelInfo)
+	}
+}
+
+// SetLevel calls [SetLevel] on the l object.
+func (l *Logger) SetLevel(level Level) {
+	// The explicitly set level is kept, regardless of the temporary elevation
+	l.elevMu.Lock()
+	defer l.elevMu.Unlock()
+
+	l.cancelElevation()
+	l.storeLevel(level)
+}
+
+// storeLevel sets the level of the logger
+func (l *Logger) storeLevel(level Level) {
+	atomic.StoreInt32(&l.level, int32(level))
+
+	// Keep the level checked by package functions current, if l shares the core with the package logger
+	if logger != nil && l.logCore == logger.logCore {
+		atomic.StoreInt32(&pkgLevel, int32(level))
+	}
+}
+
+// LevelEnabled calls [LevelEnabled] on the l object.
+func (l *Logger) LevelEnabled(level Level) bool {
+	return level >= Level(atomic.LoadInt32(&l.level))
+}
+
+// WouldLog calls [WouldLog] on the l object.
+func (l *Logger) WouldLog(level Level) bool {
+	if level.exits() {
+		// Fatal messages are always written
+		return true
+	}
+
+	return level >= Level(atomic.LoadInt32(&l.level)) && atomic.LoadInt32(&l.paused) == 0
+}
+
+// IsOpen calls [IsOpen] on the l object. It can be called on the nil object, that is never opened.
+func (l *Logger) IsOpen() bool {
+	return l != nil && l.logCore != nil && !l.closed
+}
+
+// SkipEmpty calls [SkipEmpty] on the l object.
+func (l *Logger) SkipEmpty(v bool) {
+	l.skipEmpty = v
+}
+
+// SetMirrorWriter calls [SetMirrorWriter] on the l object.
+func (l *Logger) SetMirrorWriter(w io.Writer) {
+	l.execute(func() {
+		l.mirrorWriter = w
+	})
+}
+
+// SetConfirmWrites calls [SetConfirmWrites] on the l object.
+func (l *Logger) SetConfirmWrites(v bool) {
+	l.confirmWrites = v
+}
+
+// SetSyncOnReopen calls [SetSyncOnReopen] on the l object.
+func (l *Logger) SetSyncOnReopen(v bool) {
+	l.syncOnReopen = v
+}
+
+// SetSeverityStyle calls [SetSeverityStyle] on the l object.
+func (l *Logger) SetSeverityStyle(style SeverityStyle) {
+	// The style is used by the writer goroutine
+	l.execute(func() {
+		l.sevStyle = style
+	})
+}
+
+// SetCompactLevels calls [SetCompactLevels] on the l object.
+func (l *Logger) SetCompactLevels(v bool) {
+	if v {
+		l.SetSeverityStyle(SeverityCompact)
+	} else {
+		l.SetSeverityStyle(SeverityWords)
+	}
+}
+
+// SetLevelTag calls [SetLevelTag] on the l object.
+func (l *Logger) SetLevelTag(level Level, tag string) {
+	// Tags are used by the writer goroutine
+	l.execute(func() {
+		if l.levelTags == nil {
+			l.levelTags = map[Level]string{}
+		}
+		l.levelTags[level] = tag
+	})
+}
+
+// ResetLevelTag calls [ResetLevelTag] on the l object.
+func (l *Logger) ResetLevelTag(level Level) {
+	l.execute(func() {
+		delete(l.levelTags, level)
+	})
+}
+
+// SetLevelWidth calls [SetLevelWidth] on the l object.
+func (l *Logger) SetLevelWidth(n int) {
+	l.execute(func() {
+		l.levelWidth = n
+	})
+}
+
+// levelTag returns the tag of the level added before the message text
+func (l *Logger) levelTag(level Level) string {
+	tag, ok := l.levelTags[level]
+	if !ok {
+		tag = strings.TrimSuffix(level.tag(l.sevStyle), " ")
+		if tag == "" && l.levelWidth > 0 {
+			// Informational messages need the tag in the column too
+			tag = infoColumnTag
+		}
+	}
+
+	if l.levelWidth > 0 {
+		// Pad the tag to the width of the column, suppressed tags become blank columns
+		if pad := l.levelWidth - utf8.RuneCountInString(tag); pad > 0 {
+			tag += strings.Repeat(" ", pad)
+		}
+
+		return tag + " "
+	}
+
+	if tag == "" {
+		// The tag is suppressed, no separating space
+		return ""
+	}
+
+	return tag + " "
+}
+
+// SetStrictFormat calls [SetStrictFormat] on the l object.
+func (l *Logger) SetStrictFormat(v bool) {
+	l.execute(func() {
+		l.strictFormat = v
+	})
+}
+
+// Pause calls [Pause] on the l object.
+func (l *Logger) Pause() {
+	atomic.StoreInt32(&l.paused, 1)
+}
+
+// Resume calls [Resume] on the l object.
+func (l *Logger) Resume() {
+	atomic.StoreInt32(&l.paused, 0)
+}
+
+// Dropped calls [Dropped] on the l object.
+func (l *Logger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// SetDrainTimeout calls [SetDrainTimeout] on the l object.
+func (l *Logger) SetDrainTimeout(d time.Duration) {
+	l.drainTimeout = d
+}
+
+// SetErrorHandler calls [SetErrorHandler] on the l object.
+func (l *Logger) SetErrorHandler(eh ErrorHandler) {
+	l.errHandler = eh
+}
+
+// SetWriteRetry calls [SetWriteRetry] on the l object.
+func (l *Logger) SetWriteRetry(attempts int, backoff time.Duration) {
+	l.execute(func() {
+		l.retryAttempts = attempts
+		l.retryBackoff = backoff
+	})
+}
+
+// SetReconnectOnError calls [SetReconnectOnError] on the l object.
+func (l *Logger) SetReconnectOnError(v bool) {
+	l.reconnectOnErr = v
 }
 
 // SetStatFuncs calls [SetStatFuncs] on the l object.
@@ -131,12 +787,23 @@ func (l *Logger) SetStatFuncs(ef, wf StatFunc) {
 	l.wrnEventStat = wf
 }
 
+// SetStatFuncsFormatted calls [SetStatFuncsFormatted] on the l object.
+func (l *Logger) SetStatFuncsFormatted(ef, wf FormattedStatFunc) {
+	l.errFmtStat = ef
+	l.wrnFmtStat = wf
+}
+
+// SetInfoStatFunc calls [SetInfoStatFunc] on the l object.
+func (l *Logger) SetInfoStatFunc(inf StatFunc) {
+	l.infEventStat = inf
+}
+
 // D is an shortcut for Debug.
 func (l *Logger) D(format string, v ...any) {
-	if !l.debug {
+	if atomic.LoadInt32(&l.level) > int32(LevelDebug) {
 		return
 	}
-	l.writeEvent(&logMsg{format: "<D> " + format, args: v})
+	l.writeEvent(&logMsg{level: LevelDebug, format: format, args: v})
 
 	// XXX Enable govet printf checking
 	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
@@ -148,7 +815,12 @@ func (l *Logger) Debug(format string, v ...any) {
 
 // I is an shortcut for Info.
 func (l *Logger) I(format string, v ...any) {
-	l.writeEvent(&logMsg{format: format, args: v})
+	l.writeEvent(&logMsg{level: LevelInfo, format: format, args: v})
+
+	// Call statistic function if was set
+	if l.infEventStat != nil {
+		l.infEventStat(format, v...)
+	}
 
 	// XXX Enable govet printf checking
 	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
@@ -160,7 +832,7 @@ func (l *Logger) Info(format string, v ...any) {
 
 // W is an shortcut for Warn.
 func (l *Logger) W(format string, v ...any) {
-	l.writeEvent(&logMsg{format: "<WRN> " + format, args: v})
+	l.writeEvent(&logMsg{level: LevelWarn, format: format, args: v})
 
 	// Call statistic function if was set
 	if l.wrnEventStat != nil {
@@ -177,13 +849,7 @@ func (l *Logger) Warn(format string, v ...any) {
 
 // E is an shortcut for Err.
 func (l *Logger) E(format string, v ...any) {
-	// If logger output is not stderr
-	if l.logger.Writer() != os.Stderr {
-		// Using default logger to print message to stderr
-		log.Printf("<ERR> " + format, v...)
-	}
-
-	l.writeEvent(&logMsg{format: "<ERR> " + format, args: v})
+	l.writeEvent(&logMsg{level: LevelError, format: format, args: v})
 
 	// Call statistic function if was set
 	if l.errEventStat != nil {
@@ -200,13 +866,7 @@ func (l *Logger) Err(format string, v ...any) {
 
 // F is an shortcut for Fatal.
 func (l *Logger) F(format string, v ...any) {
-	// If logger output is not stderr
-	if l.logger.Writer() != os.Stderr {
-		// Using default logger to print message to stderr
-		log.Printf("<FATAL> " + format, v...)
-	}
-
-	l.writeEvent(&logMsg{format: "<FATAL> " + format, args: v, fatal: true})
+	l.writeEvent(&logMsg{level: LevelFatal, format: format, args: v})
 
 	// XXX Enable govet printf checking
 	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
@@ -216,106 +876,1036 @@ func (l *Logger) Fatal(format string, v ...any) {
 	l.F(format, v...)
 }
 
-// Close calls [Close] on the l object.
-func (l *Logger) Close() error {
-	// Check for log already closed
-	if l.closed {
-		return &ErrLogClosed
+// Msg calls [Msg] on the l object.
+func (l *Logger) Msg(level Level, format string, v ...any) {
+	if !l.WouldLog(level) {
+		return
 	}
+	l.writeEvent(&logMsg{level: level, format: format, args: v})
 
-	// Stop receiving messages
-	l.stpStrCh<-nil
-	// Wait acknowledge message from writer-goroutine
-	<-l.stpStrCh
+	// Call statistic function if was set
+	l.callStat(level, format, v...)
 
-	// Check for empty name of the log file
-	if l.logName == "" {
-		// Standard logger was used, nothing to close
-		return nil
-	}
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
 
-	// Close opened file
-	if closer, ok := l.logger.Writer().(io.Closer); ok {
-		if err := closer.Close(); err != nil {
-			return NewFileError("cannot close log file: %w", err)
+// callStat calls the statistic function of the level if it was set
+func (l *Logger) callStat(level Level, format string, v ...any) {
+	switch level.statLevel() {
+	case LevelError:
+		if l.errEventStat != nil {
+			l.errEventStat(format, v...)
+		}
+	case LevelWarn:
+		if l.wrnEventStat != nil {
+			l.wrnEventStat(format, v...)
+		}
+	case LevelInfo:
+		if l.infEventStat != nil {
+			l.infEventStat(format, v...)
 		}
 	}
+}
 
-	// Set closed flag
-	l.closed = true
+// ISync calls [ISync] on the l object.
+func (l *Logger) ISync(format string, v ...any) error {
+	l.I(format, v...)
+	return l.Barrier()
+}
 
-	// OK
-	return nil
+// ESync calls [ESync] on the l object.
+func (l *Logger) ESync(format string, v ...any) error {
+	l.E(format, v...)
+	return l.Barrier()
 }
 
-// Reopen calls [Reopen] on the l object.
-func (l *Logger) Reopen() error {
-	// Close opened log file
-	if err := l.Close(); err != nil {
-		return err
-	}
+// Barrier calls [Barrier] on the l object.
+func (l *Logger) Barrier() error {
+	var err error
 
-	// Open log file again
-	if err := l.openLog(); err != nil {
-		return err
+	// The function is executed after all messages that are queued before it are written
+	l.execute(func() {
+		err = l.syncTarget()
+	})
+
+	return err
+}
+
+// Flush calls [Flush] on the l object.
+func (l *Logger) Flush() (int, error) {
+	if l.syncMode {
+		// Messages are written by callers immediately
+		return 0, nil
 	}
 
-	// Start mesages processing
-	l.stpStrCh<-nil
+	var n int
+	var err error
 
-	// Log reopened successfully
-	return nil
+	// The function is executed after all messages that are queued before it are written
+	l.execute(func() {
+		l.flushTarget()
+		err = l.syncTarget()
+
+		n, l.unflushed = l.unflushed, 0
+	})
+
+	return n, err
 }
 
-func (l *Logger) openLog() error {
-	if l.logName == DefaultLog {
-		l.logger = log.Default()
-	} else {
-		logFd, err := os.OpenFile(l.logName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, defaultPermMode)
-		if err != nil {
-			return NewFileError("cannot open log file: %w", err)
-		}
+// SetFlushThreshold calls [SetFlushThreshold] on the l object.
+func (l *Logger) SetFlushThreshold(count int) {
+	l.execute(func() {
+		l.flushThreshold = count
+	})
+}
 
-		l.logger = log.New(logFd, "", log.LstdFlags)
+// flushOnThreshold flushes and syncs the log target if enough messages were written since the last sync
+func (l *Logger) flushOnThreshold() {
+	if l.flushThreshold <= 0 {
+		return
 	}
 
-	l.logger.SetFlags(l.logFlags)
-	l.logger.SetPrefix(l.logPrefix)
+	if l.pendingFlush++; l.pendingFlush < l.flushThreshold {
+		return
+	}
 
-	// Configure default logger to print error/fatal messages to stderr
-	log.SetPrefix(l.logPrefix)
-	log.SetFlags(l.logFlags)
+	l.flushTarget()
+	if err := l.syncTarget(); err != nil {
+		l.handleError(err)
+	}
+}
 
-	// Reset closed flag
-	l.closed = false
+// WriteLatency calls [WriteLatency] on the l object.
+func (l *Logger) WriteLatency() (avg, max time.Duration) {
+	n := atomic.LoadUint64(&l.writes)
+	if n == 0 {
+		// No writes yet
+		return 0, 0
+	}
 
-	return nil
+	return time.Duration(atomic.LoadInt64(&l.writesTime) / int64(n)), time.Duration(atomic.LoadInt64(&l.writesMax))
 }
 
-func (l *Logger) setFlags(prefix string, flags int) {
-	// Keep an original prefix value
-	l.origPrefix = prefix
+// Raw calls [Raw] on the l object.
+func (l *Logger) Raw(level Level, b []byte) {
+	l.writeEvent(&logMsg{level: level, raw: rawRecord(b)})
+}
 
-	if flags & NoPID == 0 {
-		// Print PID in each log line
-		l.logPrefix = fmt.Sprintf("%s[%d]: ", prefix, os.Getpid())
-	} else
-	// PID should not be printed
-	if prefix != "" {
-		l.logPrefix = fmt.Sprintf("%s: ", prefix)
-	} // else - do not print any prefix
+// rawRecord copies data to allow the caller to reuse b, the newline is added if missing
+func rawRecord(b []byte) []byte {
+	raw := make([]byte, len(b), len(b) + 1)
+	copy(raw, b)
+	if len(raw) == 0 || raw[len(raw)-1] != '\n' {
+		raw = append(raw, '\n')
+	}
 
-	// Apply mandatory flags
-	l.logFlags = flags | logFlagsAlways
+	return raw
 }
 
-func (l *Logger) writeEvent(event *logMsg) {
-	// Initiate a channel to block call until the message is written
+// Close calls [Close] on the l object.
+func (l *Logger) Close() error {
+	// Check for log already closed
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	// Stop receiving messages
+	if err := l.stopWriterDrain(); err != nil {
+		return err
+	}
+	// Writer is not required anymore
+	defer l.quitWriter()
+
+	// Set closed flag
+	l.closed = true
+
+	return l.closeAll()
+}
+
+// stopWriterDrain stops the writer goroutine, waiting no longer than the drain timeout. If the timeout
+// is exceeded, the log is marked as closed and closing is finished in the background.
+func (l *Logger) stopWriterDrain() error {
+	if l.drainTimeout <= 0 || l.syncMode {
+		l.stopWriter()
+		return nil
+	}
+
+	atomic.StoreInt64(&l.drainDeadline, time.Now().Add(l.drainTimeout).UnixNano())
+
+	stopped := make(chan struct{})
+	go func() {
+		l.stopWriter()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(l.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-timer.C:
+	}
+
+	// Spilled messages are dropped by the writer after the deadline
+	abandoned := atomic.LoadInt64(&l.spoolBacklog)
+	l.closed = true
+
+	go func() {
+		// Finish closing as soon as the log target completes the current write
+		<-stopped
+		_ = l.closeAll()
+		l.quitWriter()
+	}()
+
+	return fmt.Errorf("%w: drain timeout %v exceeded, %d queued messages abandoned", ErrDrainTimeout, l.drainTimeout, abandoned)
+}
+
+// closeAll closes the log target and all auxiliary files, the writer goroutine must be stopped
+func (l *Logger) closeAll() error {
+	// Nothing can fail after closing, so the channel of errors is not needed anymore
+	defer l.closeErrors()
+
+	// Remove the spool file, all spilled messages are already written by the stopped writer
+	sp := l.loadSpool()
+	l.spoolRef.Store((*spool)(nil))
+	spoolErr := sp.remove()
+
+	// Close the fallback file, additional writers and the log target
+	fbErr := l.closeFallback()
+	accessErr := l.closeAccess()
+	sinksErr := l.closeSinks()
+	// Leveled files are closed as additional writers
+	l.leveled = nil
+	if err := l.closeTarget(); err != nil {
+		return err
+	}
+
+	if fbErr != nil {
+		return fbErr
+	}
+	if accessErr != nil {
+		return accessErr
+	}
+	if sinksErr != nil {
+		return sinksErr
+	}
+
+	return spoolErr
+}
+
+// Reopen calls [Reopen] on the l object.
+func (l *Logger) Reopen() error {
+	return l.ReopenContext(context.Background())
+}
+
+// ReopenContext calls [ReopenContext] on the l object.
+func (l *Logger) ReopenContext(ctx context.Context) error {
+	// Is the log target able to rotate itself?
+	if rotator, ok := l.target.(Rotator); ok {
+		return l.rotateTarget(ctx, rotator)
+	}
+
+	// Check for log already closed
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	// Stop receiving messages while the log is being reopened
+	if err := l.stopWriterContext(ctx); err != nil {
+		return err
+	}
+	// Start mesages processing regardless of errors, if the log cannot be opened
+	// again, errors of writing messages will be passed to the error handler
+	defer l.startWriter()
+
+	// Close the fallback file, it will be opened again on the next failure
+	fbErr := l.closeFallback()
+
+	// Reopen the access log and leveled files together with the log file
+	accessErr := l.reopenAccess()
+	leveledErr := l.reopenLeveled()
+
+	// Flush data of the old file to the disk before closing
+	syncErr := l.syncBeforeClose()
+
+	// Close opened log file
+	closeErr := l.closeTarget()
+
+	// Apply settings that affect the prefix, changed since opening
+	l.setFlags(l.origPrefix, l.logFlags)
+
+	// Open log file again
+	if err := l.openLog(); err != nil {
+		return err
+	}
+	// The log target is recovered, stop rerouting messages to the fallback logger
+	l.failedOver = false
+	// The file may be new after renaming by logrotate, start counting its age from the next write
+	l.firstWrite = time.Time{}
+
+	// Report errors of syncing and closing if any
+	if syncErr != nil {
+		return syncErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	if fbErr != nil {
+		return fbErr
+	}
+	if accessErr != nil {
+		return accessErr
+	}
+
+	return leveledErr
+}
+
+// syncBeforeClose syncs the log file before closing it by Reopen or rotation, if enabled by SetSyncOnReopen
+func (l *Logger) syncBeforeClose() error {
+	if !l.syncOnReopen || l.logName == "" {
+		// Syncing is disabled or the target is not owned by the logger
+		return nil
+	}
+
+	// Devices and pipes (e.g. /dev/null) cannot be synced
+	if fd, ok := l.target.(File); ok {
+		if fi, err := fd.Stat(); err != nil || !fi.Mode().IsRegular() {
+			return nil
+		}
+	}
+
+	if s, ok := l.target.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return NewFilePathError(l.logName, "cannot sync log file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (l *Logger) closeTarget() error {
+	// Check for empty name of the log file
+	if l.logName == "" {
+		// Standard logger or the writer owned by the caller was used, nothing to close
+		return nil
+	}
+
+	// Close opened file
+	if closer, ok := l.target.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return NewFilePathError(l.logName, "cannot close log file: %w", err)
+		}
+	}
+
+	// OK
+	return nil
+}
+
+func (l *Logger) rotateTarget(ctx context.Context, rotator Rotator) error {
+	// Check for log already closed
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	// Stop writing messages while the target is being rotated
+	if err := l.stopWriterContext(ctx); err != nil {
+		return err
+	}
+	defer l.startWriter()
+
+	if err := rotator.Rotate(); err != nil {
+		return NewFilePathError(l.logName, "cannot rotate log: %w", err)
+	}
+	l.failedOver = false
+
+	// Reopen the access log and leveled files together with the log target
+	accessErr := l.reopenAccess()
+	leveledErr := l.reopenLeveled()
+	if accessErr != nil {
+		return accessErr
+	}
+
+	return leveledErr
+}
+
+func (l *Logger) openLog() error {
+	if l.logName == DefaultLog && l.opener == nil {
+		// Write to the target of the standard logger
+		l.target = log.Default().Writer()
+	} else {
+		w, err := l.openTarget()
+		if err != nil {
+			// The previous target is already closed, so nothing is written until the next reopening
+			l.target = nil
+			return err
+		}
+
+		l.target = w
+	}
+	l.colorTerm = isTerminal(l.target)
+
+	// Messages are formatted to the line buffer, then the buffer is written to the log target
+	l.logger = log.New(&l.line, l.logPrefix, l.logFlags)
+
+	// Configure default logger to print error/fatal messages to stderr
+	log.SetPrefix(l.logPrefix)
+	log.SetFlags(l.logFlags)
+
+	// Reset closed flag
+	l.closed = false
+
+	return nil
+}
+
+func (l *Logger) openTarget() (io.Writer, error) {
+	// Is non-file target used?
+	if l.opener != nil {
+		return l.opener()
+	}
+
+	logFd, err := l.fileSystem().OpenFile(l.logName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, defaultPermMode)
+	if err != nil {
+		return nil, NewFilePathError(l.logName, "cannot open log file: %w", err)
+	}
+
+	// Check that the file is writable by the zero-byte write, so the file created by logrotate
+	// with the wrong owner is reported now rather than on writing of messages
+	if _, err := logFd.Write(nil); err != nil {
+		_ = logFd.Close()
+		return nil, NewFilePathError(l.logName, "log file is not writable: %w", err)
+	}
+
+	var w io.Writer = logFd
+	if l.compressed() {
+		w = newGzipFile(logFd)
+	}
+
+	if err := l.writeHeader(logFd, w); err != nil {
+		_ = logFd.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// writeHeader writes the header set by SetFileHeader to w if the log file is new (empty)
+func (l *Logger) writeHeader(fd File, w io.Writer) error {
+	if l.fileHeader == nil {
+		return nil
+	}
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return NewFilePathError(l.logName, "cannot get status of log file: %w", err)
+	}
+	if !fi.Mode().IsRegular() || fi.Size() != 0 {
+		// Not a file or the file already has data
+		return nil
+	}
+
+	header := l.fileHeader()
+	if header == "" {
+		return nil
+	}
+	if !strings.HasSuffix(header, "\n") {
+		header += "\n"
+	}
+
+	if _, err := io.WriteString(w, header); err != nil {
+		return NewFilePathError(l.logName, "cannot write header of log file: %w", err)
+	}
+
+	return nil
+}
+
+// SetFileHeader calls [SetFileHeader] on the l object.
+func (l *Logger) SetFileHeader(fn func() string) {
+	l.fileHeader = fn
+}
+
+func (l *Logger) write(msg *logMsg) {
+	if msg.raw != nil {
+		// Write the raw message as is
+		l.output(msg.level, msg.raw)
+		l.writeSinks(msg.level, nil, msg.raw, nil, nil)
+		l.unflushed++
+		l.countWritten(msg.level)
+		l.flushOnThreshold()
+		return
+	}
+
+	if msg.warning != nil {
+		l.handleError(msg.warning)
+	}
+
+	text := fmt.Sprintf(msg.format, msg.args...)
+	if l.strictFormat && strings.Contains(text, "%!") {
+		// Report about the formatting error marker produced by fmt, e.g. %!d(string=...)
+		l.handleError(fmt.Errorf("%w: format %q produced %q", ErrBadFormat, msg.format, text))
+	}
+	if text == "" && len(msg.fields) == 0 && l.skipEmpty {
+		// Drop the empty message
+		return
+	}
+
+	// Drop messages that exceed the rate limit of their sampling key
+	if l.samplingKey != nil && !l.allowKeyRate(msg, text) {
+		return
+	}
+
+	// Drop messages to keep the rate set by the adaptive sampling
+	if !l.allowAdaptive(msg.level) {
+		return
+	}
+
+	// Raise the level of the message by escalation rules
+	l.escalate(msg, text)
+
+	// Notify about errors after the quiet period
+	l.detectBurst(msg.level)
+
+	// Pass the formatted message to statistic functions
+	switch {
+	case msg.level == LevelError && l.errFmtStat != nil:
+		l.errFmtStat(text)
+	case msg.level == LevelWarn && l.wrnFmtStat != nil:
+		l.wrnFmtStat(text)
+	}
+
+	// Replace repeated stack traces by references
+	if l.stacks != nil {
+		text = l.stacks.dedup(text)
+	}
+
+	// Cut huge messages
+	text = l.truncateMessage(text)
+
+	// Let the entry processor change the message
+	text = l.processEntry(msg, text)
+
+	// Send the entry to subscribers if any
+	l.publish(msg, text)
+
+	// Add component, level tag and fields
+	tag, rest := l.levelTag(msg.level), text + l.formatFields(msg.fields) + callerTag(msg.caller) + stackTag(msg.stack)
+	full := l.versionTag + componentTag(msg.component) + tag + rest
+
+	l.mirror(msg, text, full)
+	l.keepRecent(msg.level, full)
+
+	var line []byte
+	var entry *Entry
+
+	// Does the target write structured entries?
+	if ew, ok := l.target.(entryWriter); ok {
+		entry = newEntry(msg, text)
+		if err := ew.writeEntry(l, entry); err != nil {
+			l.handleError(NewFilePathError(l.logName, "cannot write message to log: %w", err))
+		}
+	} else if colored := l.colorTag(msg.level, tag); colored != tag {
+		// Only the terminal gets colors, the plain line for additional writers is made on demand
+		l.output(msg.level, l.render(msg, text, l.versionTag + componentTag(msg.component) + colored + rest))
+	} else {
+		line = l.render(msg, text, full)
+		l.output(msg.level, line)
+	}
+	l.unflushed++
+	l.countWritten(msg.level)
+	l.flushOnThreshold()
+
+	// Write the message to additional writers, the line or the entry is made on demand
+	l.writeSinks(msg.level, entry, line, func() ([]byte, *Entry) {
+		if line == nil {
+			line = l.render(msg, text, full)
+		}
+		if entry == nil {
+			entry = newEntry(msg, text)
+		}
+
+		return line, entry
+	}, func(f OutputFormat) []byte {
+		return l.renderFormat(f, msg, text, full)
+	})
+}
+
+// formatLine formats the log line of the message with the text that includes the level tag and fields
+func (l *Logger) formatLine(msg *logMsg, text string) []byte {
+	// Writing to the buffer cannot fail
+	l.line.Reset()
+	if msg.timeSet {
+		// The standard logger can show only the current time, so write
+		// the timestamp of the message by itself with date and time flags disabled
+		l.line.WriteString(msg.time.Format(l.timeFormat) + " ")
+		l.logger.SetFlags(l.logFlags &^ (log.Ldate | log.Ltime | log.Lmicroseconds))
+		_ = l.logger.Output(2, text)	//nolint:gomnd // the same call depth as used by log.Printf
+		l.logger.SetFlags(l.logFlags)
+	} else {
+		_ = l.logger.Output(2, text)	//nolint:gomnd // the same call depth as used by log.Printf
+	}
+
+	return l.line.Bytes()
+}
+
+func (l *Logger) mirror(msg *logMsg, text, full string) {
+	// Duplicate errors to stderr from the writer goroutine, to keep
+	// the same order of messages in the log and in stderr
+	if msg.level < LevelError {
+		return
+	}
+
+	w := l.mirrorWriter
+	if w == nil {
+		if !l.stderrMirror() {
+			return
+		}
+
+		// Stderr, unless the output of the standard logger is redirected
+		w = log.Default().Writer()
+	} else if w == l.target {
+		// Do not duplicate messages in the log itself
+		return
+	}
+
+	// Format the message in the same way as for the log, the buffer is reset before writing to the log
+	_, _ = w.Write(l.render(msg, text, full))
+}
+
+func (l *Logger) output(level Level, line []byte) {
+	// Is the log target known as failed?
+	if l.writeFailedOver(level, line) {
+		return
+	}
+
+	// Rotate the log file if required
+	l.rotateByAge(l.now())
+
+	err := l.writeTarget(level, line)
+
+	// Retry transient errors, subsequent messages wait
+	for i := 0; err != nil && i < l.retryAttempts; i++ {
+		time.Sleep(l.retryBackoff)
+		err = l.writeTarget(level, line)
+	}
+
+	if err == nil {
+		// Successfully written
+		return
+	}
+
+	l.handleError(NewFilePathError(l.logName, "cannot write message to log: %w", err))
+
+	// Is reconnection allowed and possible?
+	if l.reconnectOnErr && l.logName != DefaultLog {
+		if err = l.reconnect(); err != nil {
+			l.handleError(err)
+		} else if err = l.writeTarget(level, line); err != nil {
+			// Write failed again using the new connection
+			l.handleError(NewFilePathError(l.logName, "cannot write message to log after reconnect: %w", err))
+		} else {
+			// Successfully written after reconnect
+			return
+		}
+	}
+
+	// Message cannot be written to the log target, pass it to the fallback logger or file if set
+	l.failover(level, line)
+}
+
+func (l *Logger) writeTarget(level Level, line []byte) error {
+	var err error
+
+	// Measure the write latency
+	start := time.Now()
+	defer func() {
+		took := int64(time.Since(start))
+
+		atomic.AddUint64(&l.writes, 1)
+		atomic.AddInt64(&l.writesTime, took)
+		if took > atomic.LoadInt64(&l.writesMax) {
+			// Only the writer updates the maximum, so there is no need in CAS
+			atomic.StoreInt64(&l.writesMax, took)
+		}
+	}()
+
+	// Pass the message level to the LevelWriter target
+	if l.target == nil {
+		// The log file cannot be opened by Reopen
+		err = os.ErrClosed
+	} else if lw, ok := l.target.(LevelWriter); ok {
+		_, err = lw.WriteLevel(level, line)
+	} else {
+		_, err = l.target.Write(line)
+	}
+
+	return err
+}
+
+func (l *Logger) syncTarget() error {
+	// Count messages for the flush threshold from the last sync
+	l.pendingFlush = 0
+
+	// Does the target support syncing? (e.g. *os.File)
+	if s, ok := l.target.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return NewFilePathError(l.logName, "cannot sync log file: %w", err)
+		}
+	}
+
+	if s, ok := l.fallbackFd.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return NewFilePathError(l.fallbackName, "cannot sync fallback file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (l *Logger) reconnect() error {
+	// Close the current target, ignore errors because it is already broken
+	if closer, ok := l.target.(io.Closer); ok {
+		_ = closer.Close()
+	}
+
+	w, err := l.openTarget()
+	if err != nil {
+		return err
+	}
+
+	l.target = w
+
+	return nil
+}
+
+func (l *Logger) handleError(err error) {
+	if l.errHandler != nil {
+		l.errHandler(err)
+	}
+
+	l.pushError(err)
+}
+
+// Errors calls [Errors] on the l object.
+func (l *Logger) Errors() <-chan error {
+	l.errsMu.Lock()
+	defer l.errsMu.Unlock()
+
+	if l.errsCh == nil {
+		l.errsCh = make(chan error, errorsBufSize)
+		if l.errsClosed {
+			// The log is already closed, no errors will be sent
+			close(l.errsCh)
+		}
+	}
+
+	return l.errsCh
+}
+
+// pushError sends the error to the channel returned by Errors, the oldest error
+// is dropped if the channel is full
+func (l *Logger) pushError(err error) {
+	l.errsMu.Lock()
+	defer l.errsMu.Unlock()
+
+	if l.errsCh == nil || l.errsClosed {
+		// Nobody receives errors
+		return
+	}
+
+	for {
+		select {
+		case l.errsCh<-err:
+			return
+		default:
+		}
+
+		// Drop the oldest error, it may be already received by the reader
+		select {
+		case <-l.errsCh:
+		default:
+		}
+	}
+}
+
+// closeErrors closes the channel returned by Errors
+func (l *Logger) closeErrors() {
+	l.errsMu.Lock()
+	defer l.errsMu.Unlock()
+
+	if l.errsCh != nil && !l.errsClosed {
+		close(l.errsCh)
+	}
+	l.errsClosed = true
+}
+
+func (l *Logger) setFlags(prefix string, flags int) {
+	// Keep an original prefix value
+	l.origPrefix = prefix
+
+	// Empty separator still has to split the prefix from the message
+	sep := l.prefixSep
+	if sep == "" {
+		sep = " "
+	}
+
+	// Hostname is written before the application name
+	if host := l.hostname(); host != "" {
+		if prefix == "" {
+			prefix = host
+		} else {
+			prefix = host + " " + prefix
+		}
+	}
+
+	if flags & NoPID == 0 {
+		// Print PID in each log line
+		l.logPrefix = fmt.Sprintf("%s[%d]%s", prefix, os.Getpid(), sep)
+	} else
+	// PID should not be printed
+	if prefix != "" {
+		l.logPrefix = prefix + sep
+	} else {
+		// Do not print any prefix
+		l.logPrefix = ""
+	}
+
+	// Apply mandatory flags
+	l.logFlags = flags | logFlagsAlways
+}
+
+func (l *Logger) writeEvent(event *logMsg) {
+	// Fatal messages are written regardless of the level
+	if event.level < Level(atomic.LoadInt32(&l.level)) && !event.level.exits() {
+		return
+	}
+
+	// Add fields of the logger and the time of the message
+	event.fields = l.fields
+	event.component = l.component
+	if l.callerFunc {
+		// The caller has to be found in the calling goroutine
+		event.caller = callerName()
+	}
+	if l.goroutineID {
+		// Do not modify fields shared with other messages
+		event.fields = append(event.fields[:len(event.fields):len(event.fields)], Field{Key: GoroutineKey, Value: goroutineID()})
+	}
+	if !event.timeSet {
+		event.time = l.now()
+	}
+
+	// Fatal messages are written even when logging is paused
+	if atomic.LoadInt32(&l.paused) != 0 && !event.level.exits() {
+		// Drop the message
+		atomic.AddUint64(&l.dropped, 1)
+		return
+	}
+
+	// Drop messages that exceed the rate limit of their level
+	if !event.level.exits() && !l.allowRate(event.level) {
+		return
+	}
+
+	if event.level >= Level(atomic.LoadInt32(&l.stackLevel)) {
+		// The stack has to be captured in the calling goroutine
+		event.stack = callerStack()
+	}
+
+	l.send(event)
+}
+
+func (l *Logger) process(msg *logMsg) {
+	if msg.exec != nil {
+		// Control message, execute it by the writer
+		msg.exec()
+		return
+	}
+
+	// Write message to the log
+	l.write(msg)
+
+	if msg.level.exits() {
+		// The program is terminated without closing, write compressed data to the file
+		l.flushTarget()
+
+		if msg.done != nil {
+			// The caller waits for writing, the program is terminated from its goroutine
+			msg.exiting, msg.exitFn = true, l.exitFunc
+			return
+		}
+
+		exit(l.exitFunc)
+	}
+}
+
+// exit terminates the program after the fatal message by exitFn, nil means os.Exit
+func exit(exitFn func(code int)) {
+	switch {
+	case exitFn != nil:
+		exitFn(1)
+	// XXX This condition is not satisfied only in tests
+	case fatalDoExit:
+		// The same as log.Fatalf does after writing the message
+		os.Exit(1)
+	}
+}
+
+// SetExitFunc calls [SetExitFunc] on the l object.
+func (l *Logger) SetExitFunc(fn func(code int)) {
+	l.execute(func() {
+		l.exitFunc = fn
+	})
+}
+
+func (l *Logger) stopWriter() {
+	l.waitRestarted(l.restarted)
+	l.restarted = nil
+
+	l.stopStarted()
+}
+
+// waitRestarted waits for the writer stopped by the abandoned stopWriterContext to be started again
+func (l *Logger) waitRestarted(restarted chan struct{}) {
+	if restarted != nil {
+		<-restarted
+	}
+}
+
+// stopStarted stops the writer that is known to be started
+func (l *Logger) stopStarted() {
+	if l.syncMode {
+		// Block writing by callers
+		l.mu.Lock()
+		return
+	}
+
+	l.stpStrCh<-nil
+	// Wait acknowledge message from writer-goroutine
+	<-l.stpStrCh
+}
+
+// stopWriterContext works like stopWriter, but gives up when ctx is done. In this case the writer
+// is started again as soon as it is stopped, so the log target is not changed
+func (l *Logger) stopWriterContext(ctx context.Context) error {
+	if ctx.Done() == nil {
+		// The context is never done
+		l.stopWriter()
+		return nil
+	}
+
+	prev := l.restarted
+	l.restarted = nil
+
+	stopped := make(chan struct{})
+	go func() {
+		l.waitRestarted(prev)
+		l.stopStarted()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	restarted := make(chan struct{})
+	l.restarted = restarted
+	go func() {
+		// Continue writing of messages to the current target
+		<-stopped
+		l.startWriter()
+		close(restarted)
+	}()
+
+	return fmt.Errorf("cannot stop writing to reopen log: %w", ctx.Err())
+}
+
+func (l *Logger) startWriter() {
+	if l.syncMode {
+		// Allow writing by callers
+		l.mu.Unlock()
+		return
+	}
+
+	l.stpStrCh<-nil
+}
+
+func (l *Logger) quitWriter() {
+	if l.syncMode {
+		// Allow callers to enter, messages will be dropped because the log is closed
+		l.mu.Unlock()
+		return
+	}
+
+	// Any non-nil value stops the writer goroutine
+	l.stpStrCh<-true
+}
+
+// execute calls fn by the writer goroutine, after all messages sent before. If the log is not opened
+// or closed, the writer goroutine is not running, so the settings are changed by the caller directly
+func (l *Logger) execute(fn func()) {
+	if l.closed {
+		if l.syncMode {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+		}
+
+		fn()
+		return
+	}
+
+	l.send(&logMsg{exec: fn})
+}
+
+func (l *Logger) send(event *logMsg) {
+	if l.syncMode {
+		// Write the message from the caller goroutine
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.closed {
+			// Drop messages written after closing
+			atomic.AddUint64(&l.dropped, 1)
+			return
+		}
+
+		l.process(event)
+
+		return
+	}
+
+	// Control messages always wait for execution, regular messages only if writes are confirmed.
+	// Fatal messages are never spilled and always waited for, the program is terminated after writing
+	if event.exec == nil && !l.confirmWrites && !event.level.exits() {
+		// Spill the message to the disk if the writer goroutine is busy
+		spilled, wait := l.spill(event)
+		if spilled {
+			return
+		}
+
+		if !wait {
+			// Send event to writer goroutine without waiting for writing
+			l.queue(event)
+			return
+		}
+
+		// The message cannot be spilled after previous spilled messages, wait for writing of it,
+		// so the next message of the caller cannot be spilled and written before
+	}
+
+	// Initiate a channel to block call until the message is written
 	event.done = make(chan bool)
 
 	// Send event to writer goroutine
-	l.msgCh<-event
+	l.queue(event)
 
 	// Wait for done signal
 	<-event.done
+
+	if event.exiting {
+		// Terminate the program from the goroutine of the caller, like log.Fatal does
+		exit(event.exitFn)
+	}
 }
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// Maximum number of keys remembered by Once
+const onceMaxKeys = 1024
+
+// Once calls [Once] on the l object.
+func (l *Logger) Once(level Level, key, format string, v ...any) {
+	if !l.WouldLog(level) {
+		// Do not remember the key, the message can be written after changing the level
+		return
+	}
+
+	if !l.firstSeen(key) {
+		return
+	}
+
+	l.Msg(level, format, v...)
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+}
+
+// SetOnceInterval calls [SetOnceInterval] on the l object.
+func (l *Logger) SetOnceInterval(d time.Duration) {
+	l.onceMu.Lock()
+	defer l.onceMu.Unlock()
+
+	l.onceInterval = d
+}
+
+// firstSeen remembers the key, it returns false if the key was seen within the once interval
+func (l *Logger) firstSeen(key string) bool {
+	l.onceMu.Lock()
+	defer l.onceMu.Unlock()
+
+	now := l.now()
+
+	if last, ok := l.onceSeen[key]; ok && (l.onceInterval <= 0 || now.Sub(last) < l.onceInterval) {
+		return false
+	}
+
+	if l.onceSeen == nil {
+		l.onceSeen = map[string]time.Time{}
+	}
+
+	if _, ok := l.onceSeen[key]; !ok && len(l.onceSeen) >= onceMaxKeys {
+		l.forgetOnceKeys(now)
+	}
+
+	l.onceSeen[key] = now
+
+	return true
+}
+
+// forgetOnceKeys removes expired keys, if there are no such keys - the oldest key is removed
+func (l *Logger) forgetOnceKeys(now time.Time) {
+	if l.onceInterval > 0 {
+		for k, t := range l.onceSeen {
+			if now.Sub(t) >= l.onceInterval {
+				delete(l.onceSeen, k)
+			}
+		}
+
+		if len(l.onceSeen) < onceMaxKeys {
+			return
+		}
+	}
+
+	oldest, oldestTime := "", now
+	for k, t := range l.onceSeen {
+		if !t.After(oldestTime) {
+			oldest, oldestTime = k, t
+		}
+	}
+	delete(l.onceSeen, oldest)
+}
+
+// Once writes the message of the level only the first time the key is seen, subsequent calls
+// with the same key are silently dropped, e.g. to write deprecation warnings or startup hints
+// without flooding the log. If the interval is set by [SetOnceInterval], the message with
+// the same key is written again after the interval. Messages of disabled levels do not mark
+// keys as seen. To keep memory usage bounded, no more than 1024 keys are remembered: when
+// the limit is reached, expired keys are forgotten, or, if there are no such keys, the oldest one.
+func Once(level Level, key, format string, v ...any) {
+	logger.Once(level, key, format, v...)
+}
+
+// SetOnceInterval sets the interval after which messages written by [Once] with the same key
+// are written again. Zero or negative interval (default) means once per the lifetime of the log.
+func SetOnceInterval(d time.Duration) {
+	logger.SetOnceInterval(d)
+}
@@ -0,0 +1,234 @@
+package log
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Options contains the log target and all optional settings of the logger, applied by [OpenWithOptions]
+// before the writer goroutine is started. Use [DefaultOptions] to get options with default values,
+// because the zero value of some fields differs from the default: the zero Level is LevelDebug,
+// the empty PrefixSeparator is a single space, ConfirmWrites and SyncOnReopen are disabled.
+// The empty TimeFormat keeps the default format, zero values of other fields disable the setting.
+type Options struct {
+	// Log target, the meaning is the same as for Open. If Writer is not nil,
+	// it is used instead of File, see OpenWriter
+	File	string
+	Writer	io.Writer
+	Prefix	string
+	Flags	int
+
+	// Separator between the prefix and the message, see SetPrefixSeparator
+	PrefixSeparator	string
+
+	// Minimal level of written messages, see SetLevel
+	Level	Level
+	// Synchronous mode, see Logger.SetSync
+	Sync	bool
+	// Skip empty messages, see SkipEmpty
+	SkipEmpty	bool
+	// Destination of duplicated error messages, see SetMirrorWriter
+	MirrorWriter	io.Writer
+	// Add the name of the calling function, see SetCallerFunc
+	CallerFunc	bool
+	// Add the ID of the calling goroutine, see SetGoroutineID
+	GoroutineID	bool
+	// Write the hostname before the prefix, see SetHostname and SetHostnameValue
+	Hostname		bool
+	HostnameValue	string
+	// Version of the program written with each message, see SetVersion
+	Version	string
+	// Version of the schema of JSON objects, see SetSchemaVersion
+	SchemaVersion	string
+	// Maximum number of fields of loggers, see SetMaxFields
+	MaxFields	int
+	// Order of fields and attributes of messages, see SetFieldOrder
+	FieldOrder	[]string
+	// Wait for writing of each message, see SetConfirmWrites
+	ConfirmWrites	bool
+	// Sync the log file before reopening, see SetSyncOnReopen
+	SyncOnReopen	bool
+	// Representation of levels, see SetSeverityStyle and SetLevelWidth
+	SeverityStyle	SeverityStyle
+	LevelWidth		int
+	// Duplication of error messages to stderr, see SetStderrMirror
+	StderrMirror	MirrorMode
+	// Maximum length of texts of messages, see SetMaxMessageBytes
+	MaxMessageBytes	int
+	// Check formatted messages for errors, see SetStrictFormat
+	StrictFormat	bool
+	// Deduplicate stack traces, see SetStackDedup
+	StackDedup	bool
+	// Coloring of level tags, see SetColors and SetLevelColor
+	Colors		bool
+	LevelColors	map[Level]Color
+	// Custom tags of levels, see SetLevelTag
+	LevelTags	map[Level]string
+	// Function called by fatal messages instead of os.Exit, see SetExitFunc
+	ExitFunc	func(code int)
+	// Function that modifies entries before writing, see SetEntryProcessor
+	EntryProcessor	func(*Entry)
+
+	// Maximum duration of writing of queued messages by Close, see SetDrainTimeout
+	DrainTimeout	time.Duration
+
+	// Sync the log target after the number of written messages, see SetFlushThreshold
+	FlushThreshold	int
+
+	// Write errors handling, see SetErrorHandler, SetReconnectOnError and SetFallbackFile
+	ErrorHandler		ErrorHandler
+	ReconnectOnError	bool
+	// Retries of failed writes, see SetWriteRetry
+	WriteRetries		int
+	WriteRetryBackoff	time.Duration
+	FallbackFile		string
+
+	// Format of lines and values of fields, see SetOutputFormat and SetFieldTypes
+	OutputFormat	OutputFormat
+	FieldTypes		FieldTypes
+
+	// Formatting of fields values, see SetDurationFormat and SetTimeFormat
+	DurationFormat	DurationFormat
+	TimeFormat		string
+
+	// Header of new log files, see SetFileHeader
+	FileHeader	func() string
+	// Line written after opening the log, see SetRunSeparator
+	RunSeparator	string
+
+	// Callback of bursts of error messages, see SetErrorBurstCallback
+	ErrorBurstQuiet	time.Duration
+	ErrorBurstFunc	func()
+	// Target rate of messages of the adaptive sampling, see SetAdaptiveSampling
+	AdaptiveSampling	int
+
+	// Maximum age of the log file, see SetRotationAge
+	RotationAge	time.Duration
+	// Source of the current time, see SetClock
+	Clock	func() time.Time
+
+	// File system of log files, see SetFS, nil means the file system set by SetFS
+	FS	FS
+
+	// Compress the log file on the fly, see SetCompressLive
+	CompressLive	bool
+
+	// Source of trace and span IDs, see SetTraceExtractor
+	TraceExtractor	TraceExtractor
+
+	// Statistic functions, see SetStatFuncs and SetInfoStatFunc
+	ErrStat	StatFunc
+	WrnStat	StatFunc
+	InfStat	StatFunc
+	AudStat	StatFunc
+
+	// Statistic functions of formatted messages, see SetStatFuncsFormatted
+	ErrStatFormatted	FormattedStatFunc
+	WrnStatFormatted	FormattedStatFunc
+}
+
+// DefaultOptions returns options with default values of all settings and an empty log target
+// (that is the same as [DefaultLog]).
+func DefaultOptions() Options {
+	return Options{
+		Level:				Level(atomic.LoadInt32(&initLevel)),
+		PrefixSeparator:	DefaultPrefixSeparator,
+		SyncOnReopen:		true,
+		ConfirmWrites:		true,
+		TimeFormat:			DefaultTimeFormat,
+	}
+}
+
+// OpenWithOptions calls [OpenWithOptions] on the l object.
+func (l *Logger) OpenWithOptions(opts Options) error {
+	l.SetLevel(opts.Level)
+	l.SetSync(opts.Sync)
+	l.prefixSep = opts.PrefixSeparator
+	l.skipEmpty = opts.SkipEmpty
+	l.strictFormat = opts.StrictFormat
+	l.maxMsgBytes = opts.MaxMessageBytes
+	l.sevStyle = opts.SeverityStyle
+	l.levelWidth = opts.LevelWidth
+	l.syncOnReopen = opts.SyncOnReopen
+	l.confirmWrites = opts.ConfirmWrites
+	l.callerFunc = opts.CallerFunc
+	l.goroutineID = opts.GoroutineID
+	l.maxFields = opts.MaxFields
+	l.fieldOrder, l.fieldRanks = opts.FieldOrder, fieldRanks(opts.FieldOrder)
+	l.withHost = opts.Hostname
+	l.hostValue = opts.HostnameValue
+	l.setVersion(opts.Version)
+	l.schemaVer = opts.SchemaVersion
+	l.mirrorWriter = opts.MirrorWriter
+	l.stderrMode = opts.StderrMirror
+	if opts.StackDedup {
+		l.stacks = newStackDedup()
+	} else {
+		l.stacks = nil
+	}
+	l.colors = opts.Colors
+	l.levelColors = nil
+	for level, color := range opts.LevelColors {
+		if l.levelColors == nil {
+			l.levelColors = map[Level]Color{}
+		}
+		l.levelColors[level] = color
+	}
+	l.levelTags = nil
+	for level, tag := range opts.LevelTags {
+		if l.levelTags == nil {
+			l.levelTags = map[Level]string{}
+		}
+		l.levelTags[level] = tag
+	}
+	l.exitFunc = opts.ExitFunc
+	l.entryProc = opts.EntryProcessor
+
+	l.drainTimeout = opts.DrainTimeout
+	l.flushThreshold = opts.FlushThreshold
+
+	l.errHandler = opts.ErrorHandler
+	l.reconnectOnErr = opts.ReconnectOnError
+	l.retryAttempts = opts.WriteRetries
+	l.retryBackoff = opts.WriteRetryBackoff
+	l.fallbackName = opts.FallbackFile
+
+	l.outFormat = opts.OutputFormat
+	l.fieldTypes = opts.FieldTypes
+	l.durFormat = opts.DurationFormat
+	if l.timeFormat = opts.TimeFormat; l.timeFormat == "" {
+		l.timeFormat = DefaultTimeFormat
+	}
+
+	l.fileHeader = opts.FileHeader
+	l.runSep = opts.RunSeparator
+	l.burstQuiet, l.burstFn, l.lastErr = opts.ErrorBurstQuiet, opts.ErrorBurstFunc, time.Time{}
+	if opts.AdaptiveSampling > 0 {
+		l.adaptive = newAdaptiveSampler(opts.AdaptiveSampling)
+	} else {
+		l.adaptive = nil
+	}
+
+	l.rotAge = opts.RotationAge
+	l.clock = opts.Clock
+	if opts.FS != nil {
+		l.fsys = opts.FS
+	}
+
+	l.traceExtractor = opts.TraceExtractor
+	l.compressLive = opts.CompressLive
+
+	l.errEventStat = opts.ErrStat
+	l.wrnEventStat = opts.WrnStat
+	l.infEventStat = opts.InfStat
+	l.audEventStat = opts.AudStat
+	l.errFmtStat = opts.ErrStatFormatted
+	l.wrnFmtStat = opts.WrnStatFormatted
+
+	if opts.Writer != nil {
+		return l.OpenWriter(opts.Writer, opts.Prefix, opts.Flags)
+	}
+
+	return l.Open(opts.File, opts.Prefix, opts.Flags)
+}
@@ -0,0 +1,144 @@
+package log
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+//nolint:gochecknoglobals // Messages written by package functions before opening the log, see SetPreOpenBuffer
+var preOpen struct {
+	sync.Mutex
+
+	size	int
+	msgs	[]*logMsg
+	dropped	int
+}
+
+// preOpened buffers the message if the log is not opened yet, it returns false if the message has to be
+// written as usual, i.e. the log is opened or buffering is disabled
+func preOpened(level Level, format string, v []any) bool {
+	if logger != nil {
+		return false
+	}
+
+	// Arguments may be changed by the caller before opening, so the message is formatted now
+	return bufferPreOpen(&logMsg{level: level, format: "%s", args: []any{fmt.Sprintf(format, v...)}})
+}
+
+// preOpenedRaw works like preOpened for raw records
+func preOpenedRaw(level Level, b []byte) bool {
+	if logger != nil {
+		return false
+	}
+
+	return bufferPreOpen(&logMsg{level: level, raw: rawRecord(b)})
+}
+
+func bufferPreOpen(msg *logMsg) bool {
+	preOpen.Lock()
+	defer preOpen.Unlock()
+
+	if preOpen.size == 0 {
+		// Buffering is disabled
+		return false
+	}
+
+	if msg.level.exits() {
+		// Fatal messages cannot wait for opening the log, write all messages to stderr
+		preOpen.msgs = append(preOpen.msgs, msg)
+		writePreOpenStderr(preOpen.msgs)
+		preOpen.msgs = nil
+
+		// XXX This condition is not satisfied only in tests
+		if fatalDoExit {
+			os.Exit(1)
+		}
+
+		return true
+	}
+
+	if msg.level < Level(atomic.LoadInt32(&pkgLevel)) {
+		// The message is below the initial level (see SetInitialLevel)
+		return true
+	}
+
+	if len(preOpen.msgs) >= preOpen.size {
+		// The buffer is full, drop the message
+		preOpen.dropped++
+		return true
+	}
+
+	preOpen.msgs = append(preOpen.msgs, msg)
+
+	return true
+}
+
+// writePreOpenStderr writes buffered messages to stderr by the standard logger
+func writePreOpenStderr(msgs []*logMsg) {
+	for _, msg := range msgs {
+		if msg.raw != nil {
+			_, _ = log.Writer().Write(msg.raw)
+			continue
+		}
+
+		log.Printf(msg.format, msg.args...)
+	}
+}
+
+// flushPreOpen writes messages buffered before opening the log, if l is the package logger
+func (l *Logger) flushPreOpen() {
+	if l != logger {
+		return
+	}
+
+	preOpen.Lock()
+	msgs, dropped := preOpen.msgs, preOpen.dropped
+	preOpen.msgs, preOpen.dropped = nil, 0
+	preOpen.Unlock()
+
+	for _, msg := range msgs {
+		l.writeEvent(msg)
+	}
+
+	if dropped != 0 {
+		l.W("%d messages written before opening the log were dropped, the size of the buffer is too small", dropped)
+	}
+}
+
+// resetPreOpen disables buffering and drops buffered messages
+func resetPreOpen() {
+	preOpen.Lock()
+	defer preOpen.Unlock()
+
+	preOpen.size, preOpen.msgs, preOpen.dropped = 0, nil, 0
+}
+
+// SetPreOpenBuffer enables buffering of messages written by package functions before opening the log
+// (see [Open]), up to n messages are kept in memory and written to the log once it is opened by one of
+// Open functions, in the same order. Messages beyond n are dropped, the warning about the number
+// of dropped messages is written after buffered ones. By default (n == 0) writing messages before
+// opening the log panics. It is intended for libraries and plugins that log from init functions,
+// so SetPreOpenBuffer, like [SetInitialLevel], should be called as early as possible.
+//
+// Buffering applies to [D], [Debug], [I], [Info], [W], [Warn], [E], [Err], [F], [Fatal], [Msg] and [Raw].
+// Messages below the initial level (see [SetInitialLevel]) are not buffered, messages are filtered
+// again by the level of the opened log. Buffered messages get the time of opening the log, statistic
+// functions are not called for them.
+// Fatal messages cannot wait: buffered messages and the fatal message are written to stderr by
+// the standard logger and the program exits. Buffering is disabled by [Reset].
+func SetPreOpenBuffer(n int) {
+	preOpen.Lock()
+	defer preOpen.Unlock()
+
+	preOpen.size = n
+	if n < 0 {
+		preOpen.size = 0
+	}
+	if len(preOpen.msgs) > preOpen.size {
+		preOpen.dropped += len(preOpen.msgs) - preOpen.size
+		preOpen.msgs = preOpen.msgs[:preOpen.size]
+	}
+}
@@ -0,0 +1,163 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// rateLimit is a token bucket that limits the number of messages of a single level
+type rateLimit struct {
+	n		int
+	per		time.Duration
+
+	tokens	float64
+	last	time.Time
+	dropped	uint64	// accessed atomically
+
+	// Buckets of sampling keys, see SetSamplingKey
+	keys	map[string]*rateLimit
+}
+
+// allow takes a token from the bucket, it returns false if the bucket is empty
+func (rl *rateLimit) allow(now time.Time) bool {
+	if !rl.last.IsZero() {
+		// Refill the bucket according to the time elapsed since the last message
+		rl.tokens += float64(rl.n) * float64(now.Sub(rl.last)) / float64(rl.per)
+		if rl.tokens > float64(rl.n) {
+			rl.tokens = float64(rl.n)
+		}
+	}
+	rl.last = now
+
+	if rl.tokens < 1 {
+		return false
+	}
+
+	rl.tokens--
+	return true
+}
+
+// SetRateLimit calls [SetRateLimit] on the l object.
+func (l *Logger) SetRateLimit(level Level, n int, per time.Duration) {
+	if level.exits() {
+		// Fatal messages are never limited
+		return
+	}
+
+	l.limitsMu.Lock()
+	defer l.limitsMu.Unlock()
+
+	if n <= 0 || per <= 0 {
+		delete(l.limits, level)
+		return
+	}
+
+	if l.limits == nil {
+		l.limits = map[Level]*rateLimit{}
+	}
+	l.limits[level] = &rateLimit{n: n, per: per, tokens: float64(n)}
+}
+
+// RateLimited calls [RateLimited] on the l object.
+func (l *Logger) RateLimited(level Level) uint64 {
+	l.limitsMu.Lock()
+	defer l.limitsMu.Unlock()
+
+	if rl := l.limits[level]; rl != nil {
+		return atomic.LoadUint64(&rl.dropped)
+	}
+
+	return 0
+}
+
+// SetSamplingKey calls [SetSamplingKey] on the l object.
+func (l *Logger) SetSamplingKey(fn func(Entry) string) {
+	l.samplingKey = fn
+}
+
+// allowRate returns false if the message of the level exceeds the rate limit set for this level
+func (l *Logger) allowRate(level Level) bool {
+	if l.samplingKey != nil {
+		// Limits are checked by the writer using sampling keys
+		return true
+	}
+
+	l.limitsMu.Lock()
+	defer l.limitsMu.Unlock()
+
+	rl := l.limits[level]
+	if rl == nil {
+		// No limit is set
+		return true
+	}
+
+	if rl.allow(l.now()) {
+		return true
+	}
+
+	atomic.AddUint64(&rl.dropped, 1)
+	return false
+}
+
+// allowKeyRate returns false if the message exceeds the rate limit of its level and sampling key
+func (l *Logger) allowKeyRate(msg *logMsg, text string) bool {
+	if msg.level.exits() {
+		// Fatal messages are never limited
+		return true
+	}
+
+	l.limitsMu.Lock()
+	defer l.limitsMu.Unlock()
+
+	rl := l.limits[msg.level]
+	if rl == nil {
+		// No limit is set
+		return true
+	}
+
+	key := l.samplingKey(*newEntry(msg, text))
+
+	krl := rl.keys[key]
+	if krl == nil {
+		if rl.keys == nil {
+			rl.keys = map[string]*rateLimit{}
+		}
+		krl = &rateLimit{n: rl.n, per: rl.per, tokens: float64(rl.n)}
+		rl.keys[key] = krl
+	}
+
+	if krl.allow(l.now()) {
+		return true
+	}
+
+	// Drops are counted per level
+	atomic.AddUint64(&rl.dropped, 1)
+	return false
+}
+
+// SetRateLimit limits the number of messages of the level to n messages per the period,
+// e.g. SetRateLimit(LevelWarn, 10, time.Second) allows no more than 10 warnings per second,
+// short bursts up to n messages are allowed. Each level has its own limit, messages that exceed
+// the limit are dropped and counted separately for each level (see [RateLimited]).
+// Zero or negative n or per removes the limit of the level. Fatal messages are never limited.
+func SetRateLimit(level Level, n int, per time.Duration) {
+	logger.SetRateLimit(level, n, per)
+}
+
+// RateLimited returns the number of messages of the level dropped because of the rate limit
+// set by [SetRateLimit]. The counter is reset when the limit of the level is changed.
+func RateLimited(level Level) uint64 {
+	return logger.RateLimited(level)
+}
+
+// SetSamplingKey makes rate limits set by [SetRateLimit] to be applied separately to each key
+// returned by fn for the entry of the message instead of all messages of the level, e.g.
+// the function that returns the value of the "user" field prevents one noisy user from suppressing
+// messages about others. Each key gets its own bucket with the limit of the level, dropped messages
+// are still counted per level (see [RateLimited]). Because fields are required, limits are checked
+// by the writer goroutine, so statistic functions (see [SetStatFuncs]) are called for dropped
+// messages too. Buckets of keys are kept until the limit of the level is changed, so fn should
+// return a bounded set of keys. The nil fn restores limiting by levels only.
+func SetSamplingKey(fn func(Entry) string) {
+	logger.SetSamplingKey(fn)
+}
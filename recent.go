@@ -0,0 +1,86 @@
+package log
+
+// recentRing keeps the most recent error messages
+type recentRing struct {
+	msgs	[]string
+	next	int		// index of the next message
+	full	bool	// the ring was filled at least once
+}
+
+// SetRecentErrors calls [SetRecentErrors] on the l object.
+func (l *Logger) SetRecentErrors(size int) {
+	l.recentMu.Lock()
+	defer l.recentMu.Unlock()
+
+	if size <= 0 {
+		l.recent = nil
+		return
+	}
+
+	l.recent = &recentRing{msgs: make([]string, size)}
+}
+
+// RecentErrors calls [RecentErrors] on the l object.
+func (l *Logger) RecentErrors(n int) []string {
+	l.recentMu.Lock()
+	defer l.recentMu.Unlock()
+
+	r := l.recent
+	if r == nil || n <= 0 {
+		return nil
+	}
+
+	// Number of kept messages
+	size := r.next
+	if r.full {
+		size = len(r.msgs)
+	}
+	if n > size {
+		n = size
+	}
+
+	out := make([]string, 0, n)
+	for i := r.next - n; i < r.next; i++ {
+		out = append(out, r.msgs[(i + len(r.msgs)) % len(r.msgs)])
+	}
+
+	return out
+}
+
+// keepRecent adds the error message to the ring of recent errors, if enabled
+func (l *Logger) keepRecent(level Level, text string) {
+	if level < LevelError {
+		return
+	}
+
+	l.recentMu.Lock()
+	defer l.recentMu.Unlock()
+
+	r := l.recent
+	if r == nil {
+		return
+	}
+
+	r.msgs[r.next] = text
+	r.next++
+	if r.next == len(r.msgs) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// SetRecentErrors enables keeping of the last size error and fatal messages in memory, see
+// [RecentErrors]. Zero size (default) disables it, so no messages are kept and the only cost
+// for each error message is a single check.
+func SetRecentErrors(size int) {
+	logger.SetRecentErrors(size)
+}
+
+// RecentErrors returns up to n most recent error and fatal messages, from the oldest to the newest.
+// Messages are returned as written to the log, with level tags and fields, but without the prefix.
+// It requires keeping of messages to be enabled by [SetRecentErrors], otherwise nil is returned.
+// RecentErrors does not interact with the writer goroutine, so it is safe to call it at any time,
+// including deferred functions that recover from a panic, e.g. to add the last errors to a crash report.
+func RecentErrors(n int) []string {
+	return logger.RecentErrors(n)
+}
@@ -0,0 +1,175 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Layout of the date suffix added to names of rotated log files
+const rotationDateFormat = "2006-01-02"
+
+// SetRotationAge calls [SetRotationAge] on the l object.
+func (l *Logger) SetRotationAge(d time.Duration) {
+	l.execute(func() {
+		l.rotAge = d
+	})
+}
+
+// SetClock calls [SetClock] on the l object.
+func (l *Logger) SetClock(clock func() time.Time) {
+	l.execute(func() {
+		l.clock = clock
+	})
+}
+
+func (l *Logger) now() time.Time {
+	if l.clock != nil {
+		return l.clock()
+	}
+
+	return time.Now()
+}
+
+// RotateNow calls [RotateNow] on the l object.
+func (l *Logger) RotateNow() (string, error) {
+	// Check for log already closed
+	if l.closed {
+		return "", &ErrLogClosed
+	}
+
+	var backup string
+	var err error
+
+	// Rotate from the writer goroutine, after all queued messages are written
+	l.execute(func() {
+		if rotator, ok := l.target.(Rotator); ok {
+			// The target rotates itself, the name of the rotated file is unknown
+			err = rotator.Rotate()
+			return
+		}
+
+		if l.opener != nil || l.logName == DefaultLog {
+			err = &ErrNotRotatable
+			return
+		}
+
+		backup, err = l.rotate()
+
+		// Start counting the age of the new file from the next write
+		l.firstWrite = time.Time{}
+	})
+
+	return backup, err
+}
+
+// rotateByAge rotates the log file if it is older than the configured rotation age
+func (l *Logger) rotateByAge(now time.Time) {
+	if l.rotAge <= 0 || l.opener != nil || l.logName == DefaultLog {
+		// Rotation is disabled or the target is not a regular file
+		return
+	}
+
+	if l.firstWrite.IsZero() {
+		// The first write to the file, start counting its age
+		l.firstWrite = now
+		return
+	}
+
+	if now.Sub(l.firstWrite) < l.rotAge {
+		// The file is not old enough
+		return
+	}
+
+	if _, err := l.rotate(); err != nil {
+		l.handleError(err)
+	}
+
+	// Start counting the age of the new file
+	l.firstWrite = now
+}
+
+// rotate renames the current log file by adding the date of its first write to the name,
+// then opens the new log file. It returns the new name of the rotated file.
+func (l *Logger) rotate() (string, error) {
+	date := l.firstWrite
+	if date.IsZero() {
+		// Nothing was written yet
+		date = l.now()
+	}
+
+	backup, err := l.backupName(l.logName, date)
+	if err != nil {
+		return "", err
+	}
+
+	// Flush data of the rotated file to the disk before closing
+	if err = l.syncBeforeClose(); err != nil {
+		return "", err
+	}
+
+	if err = l.closeTarget(); err != nil {
+		return "", err
+	}
+
+	// Rename the file regardless of the close error, the new file has to be opened anyway
+	renameErr := l.fileSystem().Rename(l.logName, backup)
+
+	w, err := l.openTarget()
+	if err != nil {
+		return "", err
+	}
+	l.target = w
+
+	if renameErr != nil {
+		return "", NewFilePathError(l.logName, "cannot rename rotated log file: %w", renameErr)
+	}
+
+	// Leveled files are rotated together with the log file
+	if err = l.rotateLeveled(date); err != nil {
+		return "", err
+	}
+
+	return backup, nil
+}
+
+// backupName returns the name of the rotated file like name-2006-01-02.ext, an additional
+// number is added to the date if the file with such name already exists
+func (l *Logger) backupName(name string, date time.Time) (string, error) {
+	ext := filepath.Ext(name)
+	if ext == gzipSuffix {
+		// Keep the extension of the compressed file, e.g. .log.gz
+		ext = filepath.Ext(strings.TrimSuffix(name, ext)) + ext
+	}
+	base := strings.TrimSuffix(name, ext) + "-" + date.Format(rotationDateFormat)
+
+	for n := 0; ; n++ {
+		backup := base + ext
+		if n != 0 {
+			backup = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+
+		_, err := l.fileSystem().Lstat(backup)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Free name found
+			return backup, nil
+		}
+		if err != nil {
+			return "", NewFilePathError(backup, "cannot check name of rotated log file: %w", err)
+		}
+	}
+}
+
+// RotateNow rotates the log file immediately, regardless of the rotation age (see [SetRotationAge]),
+// and returns the name of the rotated file, so it can be passed to an external log shipper.
+// The file is renamed in the same way as by age-based rotation (e.g. app-2022-11-08.log),
+// all messages written before the call are in the rotated file, the file is synced (see
+// [SetSyncOnReopen]) and, if compressed (see [SetCompressLive]), properly finished before renaming.
+// For targets that rotate themselves (see [Rotator]) the Rotate method is called and closedPath is empty.
+// For other targets that are not regular files, [ErrNotRotatable] is returned.
+func RotateNow() (closedPath string, err error) {
+	return logger.RotateNow()
+}
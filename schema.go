@@ -0,0 +1,21 @@
+package log
+
+// SetSchemaVersion calls [SetSchemaVersion] on the l object.
+func (l *Logger) SetSchemaVersion(version string) {
+	l.execute(func() {
+		l.schemaVer = version
+	})
+}
+
+// SetSchemaVersion sets the version of the schema of JSON objects, written with the "schema" key as the first
+// key of each object in the [FormatJSON] format (see [SetOutputFormat]), so ingestion pipelines can tell
+// records of different schemas apart before parsing them, e.g.
+//
+//	{"schema":"2","time":"2022-11-08 10:00:00","level":"INFO","msg":"message"}
+//
+// The position of the key can be changed by [SetFieldOrder]. The text format and targets that receive
+// structured entries (e.g. journald) are not affected. The empty version (default) omits the key.
+// Use [Options].SchemaVersion to set it when opening the log.
+func SetSchemaVersion(version string) {
+	logger.SetSchemaVersion(version)
+}
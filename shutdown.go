@@ -0,0 +1,49 @@
+package log
+
+import (
+	"context"
+	"fmt"
+)
+
+// Shutdown calls [Shutdown] on the l object.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	// Check for log already closed
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		// Write and sync all queued messages, then close the log
+		syncErr := l.Barrier()
+
+		err := l.Close()
+		if err == nil {
+			err = syncErr
+		}
+
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("log shutdown is not finished: %w", ctx.Err())
+	}
+}
+
+// Shutdown gracefully closes the log: it waits until all messages written before the call are written
+// to the log target, syncs the log file to the storage (like [Barrier]), then closes the log (like [Close]).
+// If ctx is done before the log is closed, Shutdown returns an error that wraps the error of the context
+// (e.g. [context.DeadlineExceeded]). In this case closing continues in the background and finishes
+// as soon as the log target completes the current write, so no goroutines remain after that.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	if err := log.Shutdown(ctx); err != nil {
+//		fmt.Fprintln(os.Stderr, "cannot close log:", err)
+//	}
+func Shutdown(ctx context.Context) error {
+	return logger.Shutdown(ctx)
+}
@@ -0,0 +1,43 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package log
+
+import (
+	"io"
+	"net"
+)
+
+// OpenUnixSocket opens the log on the Unix domain socket specified by the path to write messages
+// with the application prefix. Both datagram (unixgram) and stream (unix) sockets are supported,
+// the datagram socket is tried first. The meaning of prefix and flags is the same as for [Open].
+// Connection failures are returned as [FileError]. [Reopen] reconnects to the socket.
+//
+// See also [SetReconnectOnError] to handle transient send errors.
+func OpenUnixSocket(path, prefix string, flags int) error {
+	logger = newDefaultLogger()
+	return logger.OpenUnixSocket(path, prefix, flags)
+}
+
+// OpenUnixSocket calls [OpenUnixSocket] on the l object.
+func (l *Logger) OpenUnixSocket(path, prefix string, flags int) error {
+	l.opener = func() (io.Writer, error) {
+		return dialUnixSocket(path)
+	}
+
+	return l.open(path, prefix, flags)
+}
+
+func dialUnixSocket(path string) (io.Writer, error) {
+	// Try datagram socket first
+	conn, err := net.Dial("unixgram", path)
+	if err == nil {
+		return conn, nil
+	}
+
+	// Try stream socket
+	if conn, err = net.Dial("unix", path); err != nil {
+		return nil, NewFilePathError(path, "cannot connect to log socket: %w", err)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,167 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package log
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocketDatagram(t *testing.T) {
+	sock := filepath.Join(tempDir(), "log.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Errorf("cannot listen on datagram socket %q: %v", sock, err)
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	if err := OpenUnixSocket(sock, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on socket %q: %v", sock, err)
+		t.FailNow()
+	}
+
+	Info(stubLogFormat, 0, `INFO`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on socket %q: %v", sock, err)
+	}
+
+	// Read written message
+	buf := make([]byte, 1024)	//nolint:gomnd // enough to read a test message
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Errorf("cannot read message from socket %q: %v", sock, err)
+		t.FailNow()
+	}
+
+	if want := stubApp + `: Test #0 - INFO log message` + "\n"; string(buf[:n]) != want {
+		t.Errorf("want %q, got %q", want, buf[:n])
+	}
+}
+
+func TestUnixSocketStream(t *testing.T) {
+	sock := filepath.Join(tempDir(), "log.sock")
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Errorf("cannot listen on stream socket %q: %v", sock, err)
+		t.FailNow()
+	}
+	defer ln.Close()
+
+	// Accept connection and read lines from it
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for scanner := bufio.NewScanner(conn); scanner.Scan(); {
+			lines <- scanner.Text()
+		}
+	}()
+
+	if err := OpenUnixSocket(sock, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on socket %q: %v", sock, err)
+		t.FailNow()
+	}
+
+	Warn(stubLogFormat, 0, `WARNING`)
+
+	if err := Close(); err != nil {
+		t.Errorf("cannot close log on socket %q: %v", sock, err)
+	}
+
+	if want, got := stubApp + `: <WRN> Test #0 - WARNING log message`, <-lines; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestUnixSocketReconnect(t *testing.T) {
+	sock := filepath.Join(tempDir(), "log.sock")
+	addr := &net.UnixAddr{Name: sock, Net: "unixgram"}
+
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Errorf("cannot listen on datagram socket %q: %v", sock, err)
+		t.FailNow()
+	}
+
+	if err = OpenUnixSocket(sock, stubApp, NoPID); err != nil {
+		t.Errorf("cannot open log on socket %q: %v", sock, err)
+		t.FailNow()
+	}
+
+	// Collect write errors
+	errs := []error{}
+	SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	SetReconnectOnError(true)
+
+	// Restart the collector, the logger connection becomes broken
+	conn.Close()
+	os.Remove(sock)
+	if conn, err = net.ListenUnixgram("unixgram", addr); err != nil {
+		t.Errorf("cannot listen on datagram socket %q again: %v", sock, err)
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	Info(stubLogFormat, 1, `INFO`)
+
+	if err = Close(); err != nil {
+		t.Errorf("cannot close log on socket %q: %v", sock, err)
+	}
+
+	// The only error of the first write attempt is expected
+	if len(errs) != 1 {
+		t.Errorf("want 1 write error, got %d: %v", len(errs), errs)
+	}
+
+	buf := make([]byte, 1024)	//nolint:gomnd // enough to read a test message
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Errorf("cannot read message from socket %q: %v", sock, err)
+		t.FailNow()
+	}
+
+	if want := stubApp + `: Test #1 - INFO log message` + "\n"; string(buf[:n]) != want {
+		t.Errorf("want %q, got %q", want, buf[:n])
+	}
+}
+
+func TestUnixSocketFailOpen(t *testing.T) {
+	sock := filepath.Join(tempDir(), "no-such.sock")
+
+	//nolint:errorlint // Try to open log on non-existing socket
+	switch err := OpenUnixSocket(sock, stubApp, NoFlags); err.(type) {
+	case nil:
+		t.Errorf("anormal situation - log opened on non-existing socket %q", sock)
+		if err = Close(); err != nil {
+			panic("Cannot close log opened on non-existing socket: " + err.Error())
+		}
+
+	case *FileError:
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("failed OpenUnixSocket() error is %v, want - %v", err, fs.ErrNotExist)
+		}
+		if path := err.(*FileError).Path(); path != sock {
+			t.Errorf("failed OpenUnixSocket() error path is %q, want - %q", path, sock)
+		}
+
+	default:
+		t.Errorf("unexpected error returned opening log on non-existing socket %q: %v", sock, err)
+	}
+}
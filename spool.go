@@ -0,0 +1,325 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spool keeps messages that cannot be passed to the busy writer goroutine in the file
+type spool struct {
+	mu		sync.Mutex
+	fd		TempFile
+	fsys	FS
+	enc		*json.Encoder
+	pending	bool			// there are messages not replayed yet
+	warnings	[]error		// warnings of spilled messages, in order of their records
+	retired	bool			// the spool is replaced or removed, messages cannot be spilled to it
+	wakeCh	chan struct{}	// notifies the writer goroutine about pending messages
+}
+
+// spoolRecord is the serialized message
+type spoolRecord struct {
+	Level		Level		`json:"l"`
+	Time		time.Time	`json:"t"`
+	TimeSet		bool		`json:"s,omitempty"`
+	Text		string		`json:"m"`
+	Component	string		`json:"c,omitempty"`
+	Caller		string		`json:"f,omitempty"`
+	Fields		[][2]string	`json:"k,omitempty"`
+	Extra		[][2]string	`json:"e,omitempty"`
+	Stack		[]string	`json:"st,omitempty"`
+	Raw			[]byte		`json:"r,omitempty"`
+	Warned		bool		`json:"w,omitempty"`
+
+	// Warning of the message, kept in memory to be passed to the error handler as is
+	warning	error
+}
+
+// SpillToDisk calls [SpillToDisk] on the l object.
+func (l *Logger) SpillToDisk(dir string) error {
+	if l.closed {
+		return &ErrLogClosed
+	}
+
+	if l.syncMode {
+		// Messages are written by callers, nothing to spill
+		return nil
+	}
+
+	fsys := l.fileSystem()
+	fd, err := fsys.CreateTemp(dir, "log-spool-*")
+	if err != nil {
+		return NewFilePathError(dir, "cannot create spool file: %w", err)
+	}
+
+	sp := &spool{fd: fd, fsys: fsys, enc: json.NewEncoder(fd), wakeCh: make(chan struct{}, 1)}
+
+	// Replace the spool from the writer goroutine, after all queued messages are written
+	var old *spool
+	l.execute(func() {
+		old = l.loadSpool()
+		l.spoolRef.Store(sp)
+
+		// Callers that loaded the old spool before replacing cannot spill to it anymore,
+		// so all its messages are written before messages of the new spool
+		old.retire()
+		l.drainFrom(old)
+	})
+
+	return old.remove()
+}
+
+// spill writes the message to the spool file if the writer goroutine is busy, it returns false
+// if the message has to be sent to the writer goroutine as usual, wait is true if the caller
+// has to wait for writing of the message after spilled messages
+func (l *Logger) spill(event *logMsg) (spilled, wait bool) {
+	sp := l.loadSpool()
+	if sp == nil {
+		return false, false
+	}
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if sp.retired {
+		// The spool is being replaced or removed, its messages are written before receiving this one
+		return false, false
+	}
+
+	if !sp.pending {
+		// Try to pass the message to the writer goroutine
+		l.addQueued(1)
+		select {
+		case l.msgCh<-event:
+			return true, false
+		default:
+			l.addQueued(-1)
+		}
+	}
+
+	// The writer goroutine is busy or there are spilled messages, that have to be written before
+	if err := sp.enc.Encode(l.spoolRecord(event)); err != nil {
+		// The writer goroutine writes spilled messages before messages of waiting callers,
+		// so the order of messages is kept
+		return false, true
+	}
+
+	if event.warning != nil {
+		sp.warnings = append(sp.warnings, event.warning)
+	}
+
+	sp.pending = true
+	updatePeak(&l.spoolPeak, atomic.AddInt64(&l.spoolBacklog, 1))
+
+	select {
+	case sp.wakeCh<-struct{}{}:
+	default:
+		// The writer goroutine is already notified
+	}
+
+	return true, false
+}
+
+// spoolRecord converts the message to the serializable record
+func (l *Logger) spoolRecord(event *logMsg) *spoolRecord {
+	text := fmt.Sprintf(event.format, event.args...)
+
+	return &spoolRecord{
+		Level:		event.level,
+		Time:		event.time,
+		TimeSet:	event.timeSet,
+		Text:		text,
+		Component:	event.component,
+		Caller:		event.caller,
+		Fields:		l.spoolFields(event.fields),
+		Extra:		l.spoolFields(event.extra),
+		Stack:		event.stack,
+		Raw:		event.raw,
+		Warned:		event.warning != nil,
+	}
+}
+
+func (l *Logger) spoolFields(fields []Field) [][2]string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	out := make([][2]string, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, [2]string{f.Key, l.fieldValue(f.Value)})
+	}
+
+	return out
+}
+
+func (r *spoolRecord) message() *logMsg {
+	msg := &logMsg{
+		level:		r.Level,
+		format:		"%s",
+		args:		[]any{r.Text},
+		component:	r.Component,
+		caller:		r.Caller,
+		stack:		r.Stack,
+		time:		r.Time,
+		timeSet:	r.TimeSet,
+		raw:		r.Raw,
+		warning:	r.warning,
+	}
+
+	for _, f := range r.Fields {
+		msg.fields = append(msg.fields, Field{Key: f[0], Value: f[1]})
+	}
+	for _, f := range r.Extra {
+		msg.extra = append(msg.extra, Field{Key: f[0], Value: f[1]})
+	}
+
+	return msg
+}
+
+// spoolWake returns the channel that notifies the writer goroutine about spilled messages
+func (l *Logger) spoolWake() <-chan struct{} {
+	sp := l.loadSpool()
+	if sp == nil {
+		return nil
+	}
+
+	return sp.wakeCh
+}
+
+// loadSpool returns the spool set by SpillToDisk, nil if spilling is disabled
+func (l *Logger) loadSpool() *spool {
+	sp, _ := l.spoolRef.Load().(*spool)
+	return sp
+}
+
+// drainSpool writes all spilled messages to the log target, it is called by the writer goroutine
+func (l *Logger) drainSpool() {
+	l.drainFrom(l.loadSpool())
+}
+
+// drainFrom writes all messages spilled to the spool, it is called by the writer goroutine
+func (l *Logger) drainFrom(sp *spool) {
+	if sp == nil {
+		return
+	}
+
+	for {
+		records, err := sp.take()
+		if err != nil {
+			l.handleError(NewFilePathError(sp.fd.Name(), "cannot read spool file: %w", err))
+		}
+
+		if len(records) == 0 {
+			return
+		}
+
+		for i, r := range records {
+			if l.drainExpired() {
+				// Close does not wait anymore, drop the rest of messages
+				atomic.AddUint64(&l.dropped, uint64(len(records) - i))
+				atomic.AddInt64(&l.spoolBacklog, -int64(len(records) - i))
+				break
+			}
+
+			atomic.AddInt64(&l.spoolBacklog, -1)
+			l.process(r.message())
+		}
+	}
+}
+
+// drainExpired returns true if the drain timeout of Close is exceeded
+func (l *Logger) drainExpired() bool {
+	deadline := atomic.LoadInt64(&l.drainDeadline)
+
+	return deadline != 0 && time.Now().UnixNano() > deadline
+}
+
+// take reads all spilled messages and truncates the spool file
+func (sp *spool) take() ([]*spoolRecord, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if !sp.pending {
+		return nil, nil
+	}
+
+	// Nothing is lost on errors, because the file is truncated anyway
+	sp.pending = false
+	warnings := sp.warnings
+	sp.warnings = nil
+	defer func() {
+		_ = sp.fd.Truncate(0)
+		_, _ = sp.fd.Seek(0, io.SeekStart)
+	}()
+
+	if _, err := sp.fd.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var records []*spoolRecord
+	dec := json.NewDecoder(bufio.NewReader(sp.fd))
+	for {
+		r := &spoolRecord{}
+		if err := dec.Decode(r); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			return records, err
+		}
+		if r.Warned && len(warnings) != 0 {
+			r.warning, warnings = warnings[0], warnings[1:]
+		}
+		records = append(records, r)
+	}
+}
+
+// retire forbids spilling of messages to the spool
+func (sp *spool) retire() {
+	if sp == nil {
+		return
+	}
+
+	sp.mu.Lock()
+	sp.retired = true
+	sp.mu.Unlock()
+}
+
+// remove closes and removes the spool file
+func (sp *spool) remove() error {
+	if sp == nil {
+		return nil
+	}
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.retired = true
+
+	if err := sp.fd.Close(); err != nil {
+		return NewFilePathError(sp.fd.Name(), "cannot close spool file: %w", err)
+	}
+
+	if err := sp.fsys.Remove(sp.fd.Name()); err != nil {
+		return NewFilePathError(sp.fd.Name(), "cannot remove spool file: %w", err)
+	}
+
+	return nil
+}
+
+// SpillToDisk enables spilling of messages to the temporary spool file created in the dir directory
+// (the default directory for temporary files if dir is empty) when the writer goroutine is busy,
+// e.g. because the log target is slow. Spilled messages are replayed to the log target in the original
+// order once the writer goroutine catches up, so short bursts of messages neither block callers nor
+// consume memory. It has effect only in the asynchronous mode when writes are not confirmed
+// (see [SetConfirmWrites]), otherwise callers wait for writing of each message anyway.
+// Messages are formatted before spilling, values of fields are stored as strings. Calling SpillToDisk
+// again replaces the spool file, the spool file is removed by [Close].
+// SpillToDisk must be called after opening the log.
+func SpillToDisk(dir string) error {
+	return logger.SpillToDisk(dir)
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// StackKey is the key of the field with the stack trace of the call site, see [SetStackTrace].
+const StackKey = "stack"
+
+// StackTraceOff passed to [SetStackTrace] disables capturing of stack traces.
+const StackTraceOff = Level(math.MaxInt32)
+
+// Maximum number of frames of captured stack traces
+const stackMaxDepth = 32
+
+// SetStackTrace calls [SetStackTrace] on the l object.
+func (l *Logger) SetStackTrace(minLevel Level) {
+	// The level is checked by callers, StackTraceOff is above all levels
+	atomic.StoreInt32(&l.stackLevel, int32(minLevel))
+}
+
+// callerStack returns frames of the calling goroutine, starting from the first function outside
+// of the package, each frame looks like "pkg.Func (/path/to/file.go:42)"
+func callerStack() []string {
+	pcs := make([]uintptr, stackMaxDepth + callerMaxDepth)
+	// Skip runtime.Callers and callerStack itself
+	n := runtime.Callers(2, pcs)	//nolint:gomnd // described above
+
+	var stack []string
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+
+		// Skip frames of the logger, but functions of tests of the package are callers too
+		if stack != nil || !strings.HasPrefix(frame.Function, pkgFuncPrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			stack = append(stack, frame.Function + " (" + frame.File + ":" + strconv.Itoa(frame.Line) + ")")
+		}
+
+		if !more || len(stack) == stackMaxDepth {
+			return stack
+		}
+	}
+}
+
+// stackTag returns the text representation of the stack trace, each frame on its own line
+func stackTag(stack []string) string {
+	if len(stack) == 0 {
+		return ""
+	}
+
+	return "\n\t" + strings.Join(stack, "\n\t")
+}
+
+// SetStackTrace enables capturing of stack traces for messages with levels minLevel and above, e.g.
+// SetStackTrace(LevelError) adds stack traces to errors and fatal messages. The stack trace is captured
+// by the goroutine that calls the logging function, frames of the logger are skipped, so the first frame
+// is the function that wrote the message. Text logs get frames after the message, one per line indented
+// by the tab, the JSON format (see [SetOutputFormat]) and targets that receive structured entries (e.g.
+// journald) get the field with the [StackKey] key, the array of frames in JSON. At most 32 frames are
+// captured. Capturing costs the stack walk and allocations for each message of such levels, so stack
+// traces are disabled by default, [StackTraceOff] disables them again.
+func SetStackTrace(minLevel Level) {
+	logger.SetStackTrace(minLevel)
+}
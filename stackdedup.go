@@ -0,0 +1,74 @@
+package log
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Number of different stack traces remembered to detect duplicates
+const stackDedupWindow = 64
+
+// stackDedup keeps hashes of recently seen stack traces with their sequence numbers
+type stackDedup struct {
+	seqs	map[uint64]int
+	hashes	[]uint64	// ring buffer of hashes to evict the oldest one
+	next	int			// position of the next hash in the ring
+	seq		int			// sequence number of the last seen stack trace
+}
+
+// SetStackDedup calls [SetStackDedup] on the l object.
+func (l *Logger) SetStackDedup(v bool) {
+	l.execute(func() {
+		if !v {
+			l.stacks = nil
+			return
+		}
+
+		if l.stacks == nil {
+			l.stacks = newStackDedup()
+		}
+	})
+}
+
+func newStackDedup() *stackDedup {
+	return &stackDedup{
+		seqs:	map[uint64]int{},
+		hashes:	make([]uint64, 0, stackDedupWindow),
+	}
+}
+
+// dedup replaces the trailing multi-line block of the text (a stack trace) by the reference
+// to the identical block written before, if any. The first occurrence of the block is marked
+// by its sequence number to be referenced by later messages.
+func (sd *stackDedup) dedup(text string) string {
+	// Stack trace is the multi-line block after the first line of the message
+	nl := strings.IndexByte(text, '\n')
+	if nl == -1 || strings.IndexByte(text[nl+1:], '\n') == -1 {
+		// Not a multi-line block
+		return text
+	}
+	head, stack := text[:nl], text[nl+1:]
+
+	h := fnv.New64a()
+	h.Write([]byte(stack))	//nolint:errcheck // never returns an error
+	sum := h.Sum64()
+
+	if seq, ok := sd.seqs[sum]; ok {
+		return fmt.Sprintf("%s\n(stack identical to #%d)", head, seq)
+	}
+
+	// New stack trace, remember it
+	sd.seq++
+	if len(sd.hashes) < stackDedupWindow {
+		sd.hashes = append(sd.hashes, sum)
+	} else {
+		// Evict the oldest hash
+		delete(sd.seqs, sd.hashes[sd.next])
+		sd.hashes[sd.next] = sum
+		sd.next = (sd.next + 1) % stackDedupWindow
+	}
+	sd.seqs[sum] = sd.seq
+
+	return fmt.Sprintf("%s\n(stack #%d)\n%s", head, sd.seq, stack)
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"os"
+)
+
+// MirrorMode defines when error and fatal messages are duplicated to stderr, see [SetStderrMirror].
+type MirrorMode int
+
+// Supported modes of the stderr mirror
+const (
+	// Always duplicate messages to stderr, unless stderr is the log target (default)
+	MirrorAlways	=	MirrorMode(iota)
+	// Duplicate messages only if stderr is a terminal or a file that differs from the log target
+	MirrorAuto
+	// Never duplicate messages to stderr
+	MirrorNever
+)
+
+// SetStderrMirror calls [SetStderrMirror] on the l object.
+func (l *Logger) SetStderrMirror(mode MirrorMode) {
+	l.execute(func() {
+		l.stderrMode = mode
+	})
+}
+
+// stderrMirror reports whether messages have to be duplicated to stderr
+func (l *Logger) stderrMirror() bool {
+	if l.target == os.Stderr {
+		// Messages are already written to stderr
+		return false
+	}
+
+	switch l.stderrMode {
+	case MirrorNever:
+		return false
+	case MirrorAuto:
+		// Checking of stderr requires system calls, so check it only once for each target
+		if l.autoMirrorTarget != l.target {
+			l.autoMirrorTarget = l.target
+			l.autoMirror = autoStderrMirror(l.target)
+		}
+		return l.autoMirror
+	default:
+		return true
+	}
+}
+
+// autoStderrMirror reports whether stderr is a terminal or a file that differs from the target
+func autoStderrMirror(target any) bool {
+	errFi, err := os.Stderr.Stat()
+	if err != nil {
+		// Stderr is closed or inaccessible
+		return false
+	}
+
+	if errFi.Mode() & os.ModeCharDevice != 0 {
+		// Terminal or another character device such as /dev/null
+		nullFi, err := os.Stat(os.DevNull)
+		return err != nil || !os.SameFile(errFi, nullFi)
+	}
+
+	// Get the file of the log target
+	var fd File
+	switch t := target.(type) {
+	case File:
+		fd = t
+	case *gzipFile:
+		fd = t.fd
+	default:
+		// The target is not a file, so it differs from stderr
+		return true
+	}
+
+	targetFi, err := fd.Stat()
+
+	return err != nil || !os.SameFile(errFi, targetFi)
+}
+
+// SetStderrMirror sets when error and fatal messages are duplicated to stderr: [MirrorAlways] (default)
+// duplicates them unless stderr is the log target itself, [MirrorNever] disables duplication and
+// [MirrorAuto] duplicates messages only if stderr is a terminal or it is redirected to a file other than
+// the log file. MirrorAuto avoids duplicated lines when stderr is redirected to the log file (common
+// for containers and supervisors) and useless writes when it is redirected to /dev/null. Terminals are
+// detected by the file mode of stderr (character devices other than /dev/null), stderr is checked once
+// for each log target. The mode does not affect the writer set by [SetMirrorWriter].
+func SetStderrMirror(mode MirrorMode) {
+	logger.SetStderrMirror(mode)
+}
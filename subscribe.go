@@ -0,0 +1,80 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriber receives copies of written entries, see Subscribe
+type subscriber struct {
+	ch		chan Entry
+	once	sync.Once
+}
+
+// Subscribe calls [Subscribe] on the l object.
+func (l *Logger) Subscribe(buf int) (<-chan Entry, func()) {
+	sub := &subscriber{ch: make(chan Entry, buf)}
+
+	l.subsMu.Lock()
+	if l.subs == nil {
+		l.subs = map[*subscriber]struct{}{}
+	}
+	l.subs[sub] = struct{}{}
+	l.subsMu.Unlock()
+
+	unsubscribe := func() {
+		sub.once.Do(func() {
+			l.subsMu.Lock()
+			delete(l.subs, sub)
+			l.subsMu.Unlock()
+
+			// No more entries will be sent
+			close(sub.ch)
+		})
+	}
+
+	return sub.ch, unsubscribe
+}
+
+// SubscribeDropped calls [SubscribeDropped] on the l object.
+func (l *Logger) SubscribeDropped() uint64 {
+	return atomic.LoadUint64(&l.subsDropped)
+}
+
+// publish sends the entry of the message to all subscribers without blocking
+func (l *Logger) publish(msg *logMsg, text string) {
+	l.subsMu.Lock()
+	defer l.subsMu.Unlock()
+
+	if len(l.subs) == 0 {
+		return
+	}
+
+	entry := newEntry(msg, text)
+	for sub := range l.subs {
+		select {
+		case sub.ch <- *entry:
+		default:
+			// The subscriber is too slow, drop the entry for it
+			atomic.AddUint64(&l.subsDropped, 1)
+		}
+	}
+}
+
+// Subscribe returns a channel that receives a copy of each message written to the log
+// as an [Entry] and a function to unsubscribe, that closes the channel. The channel has
+// a buffer of buf entries. The logger never waits for subscribers: if the buffer of
+// the subscriber is full, the entry is dropped for this subscriber and counted (see
+// [SubscribeDropped]). Raw messages (see [Raw]) are not sent to subscribers.
+//
+// Subscribers are bound to the logger object, so subscriptions are lost if the log
+// is opened again by [Open].
+func Subscribe(buf int) (<-chan Entry, func()) {
+	return logger.Subscribe(buf)
+}
+
+// SubscribeDropped returns the number of entries dropped because subscribers
+// (see [Subscribe]) did not read them in time.
+func SubscribeDropped() uint64 {
+	return logger.SubscribeDropped()
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"io"
+	"reflect"
+)
+
+// Formatter formats entries of messages for additional writers, see [AddFormattedWriter].
+// The returned line must be terminated by the newline.
+type Formatter interface {
+	Format(e *Entry) []byte
+}
+
+// sink is an additional writer that receives all messages written to the log
+type sink struct {
+	w		io.Writer
+	f		Formatter	// nil if messages are written in the same form as to the log
+	cached	bool		// lines of the formatter can be reused by other writers with the same formatter
+	name	string		// used in errors, empty for writers of the caller
+	owned	bool		// the writer is closed by the logger
+}
+
+// AddWriter calls [AddWriter] on the l object.
+func (l *Logger) AddWriter(w io.Writer) {
+	l.addSink(&sink{w: w})
+}
+
+// AddFormattedWriter calls [AddFormattedWriter] on the l object.
+func (l *Logger) AddFormattedWriter(w io.Writer, f Formatter) {
+	// Formatters are used as keys to share formatted lines, so they have to be comparable
+	l.addSink(&sink{w: w, f: f, cached: reflect.TypeOf(f).Comparable()})
+}
+
+func (l *Logger) addSink(s *sink) {
+	l.execute(func() {
+		l.sinks = append(l.sinks, s)
+	})
+}
+
+// writeSinks writes the message to additional writers. If the line or the entry are not
+// known yet, build is called to get them (nil for raw messages, that have no entries).
+// The render function formats the line of the message in the output format for writers
+// that use output formats as formatters.
+func (l *Logger) writeSinks(level Level, entry *Entry, line []byte, build func() ([]byte, *Entry), render func(OutputFormat) []byte) {
+	// Lines of formatters of additional writers, made on demand
+	var formatted map[Formatter][]byte
+
+	for _, s := range l.sinks {
+		var err error
+
+		ew, isEntryWriter := s.w.(entryWriter)
+		switch {
+		case isEntryWriter && build != nil:
+			if entry == nil {
+				line, entry = build()
+			}
+			err = ew.writeEntry(l, entry)
+
+		case s.f != nil && build != nil:
+			if entry == nil {
+				line, entry = build()
+			}
+			if formatted == nil {
+				formatted = map[Formatter][]byte{}
+				// Rendering reuses the buffer of the line, so keep the copy of the line for other writers
+				line = append([]byte(nil), line...)
+			}
+			err = writeLevel(s.w, level, l.formatSink(s, entry, render, formatted))
+
+		default:
+			if line == nil {
+				line, entry = build()
+			}
+			err = writeLevel(s.w, level, line)
+		}
+
+		if err != nil {
+			l.handleError(NewFilePathError(s.name, "cannot write message to additional writer: %w", err))
+		}
+	}
+}
+
+// formatSink returns the line of the entry formatted by the formatter of the sink. Lines are
+// kept in the formatted map, so each comparable formatter formats the message only once.
+func (l *Logger) formatSink(s *sink, entry *Entry, render func(OutputFormat) []byte, formatted map[Formatter][]byte) []byte {
+	if s.cached {
+		if line, ok := formatted[s.f]; ok {
+			return line
+		}
+	}
+
+	var line []byte
+	if f, ok := s.f.(OutputFormat); ok {
+		// Output formats are rendered by the logger with its settings to the shared buffer
+		line = append([]byte(nil), render(f)...)
+	} else {
+		line = s.f.Format(entry)
+	}
+
+	if s.cached {
+		formatted[s.f] = line
+	}
+
+	return line
+}
+
+// writeLevel writes the line to w, passing the level to LevelWriter writers
+func writeLevel(w io.Writer, level Level, line []byte) error {
+	if lw, ok := w.(LevelWriter); ok {
+		_, err := lw.WriteLevel(level, line)
+		return err
+	}
+
+	_, err := w.Write(line)
+	return err
+}
+
+// closeSinks closes additional writers owned by the logger and removes all additional writers
+func (l *Logger) closeSinks() error {
+	var err error
+
+	for _, s := range l.sinks {
+		closer, ok := s.w.(io.Closer)
+		if !s.owned || !ok {
+			continue
+		}
+
+		if cErr := closer.Close(); cErr != nil && err == nil {
+			err = NewFilePathError(s.name, "cannot close additional writer: %w", cErr)
+		}
+	}
+
+	l.sinks = nil
+
+	return err
+}
+
+// AddWriter adds the writer that receives all messages written to the log in addition to the log target,
+// e.g. to write messages to the file and to the network at the same time. Messages are written to the
+// writer in the same form as to the log, [LevelWriter] writers get levels of messages. Writing errors
+// are passed to the error handler (see [SetErrorHandler]), they do not affect writing to the log target
+// and to other writers. The writer is not closed by the logger, but it is removed from the logger
+// by [Close]. AddWriter must be called after opening the log.
+//
+// All writers receive the same messages: the level (see [SetLevel]) and other filters are applied
+// once, before writing the message to the log target and to additional writers.
+func AddWriter(w io.Writer) {
+	logger.AddWriter(w)
+}
+
+// AddFormattedWriter works like [AddWriter], but messages are written to the writer in the form made by
+// the formatter f instead of the form of the log, e.g. to write the text log to the file and to send
+// JSON objects to the log collector at the same time. Output formats (e.g. [FormatJSON]) can be used
+// as formatters, they format lines with settings of the log (prefix, flags, time format and so on).
+// Other formatters get entries of messages with the level, the time, the text and fields. Raw messages
+// (see [Raw]) are written as is.
+//
+// Each distinct formatter formats the message once, the line is shared by all writers with the same
+// formatter, but each additional formatter costs formatting of every message written to the log and
+// the copy of the line. Formatters that cannot be compared (e.g. structs with functions) format
+// the message for each writer separately.
+func AddFormattedWriter(w io.Writer, f Formatter) {
+	logger.AddFormattedWriter(w, f)
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"strings"
+)
+
+// Size of the buffer of the subscription used by ExpectFatal to catch fatal messages
+const expectFatalBuf = 64
+
+// TB is the subset of the testing.TB interface used by [NewTestLogger] and [ExpectFatal], it allows
+// to use test helpers without importing the testing package into programs.
+type TB interface {
+	Helper()
+	Log(args ...any)
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// tbWriter writes log lines using the Log method of the test
+type tbWriter struct {
+	tb	TB
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.tb.Helper()
+	w.tb.Log(strings.TrimSuffix(string(p), "\n"))
+
+	return len(p), nil
+}
+
+// NewTestLogger returns the opened logger that writes messages using tb.Log, so messages appear
+// in the output of the test that wrote them (always with go test -v, otherwise only if the test fails).
+// tb is usually *testing.T, *testing.B or *testing.F. The logger works in the synchronous mode (see
+// [Logger.SetSync]), messages are written without the prefix and PID, error messages are not duplicated
+// to stderr. Levels apply as usual. The logger is closed automatically when the test finishes.
+//
+// NOTE: Fatal messages still terminate the program, as for any other logger.
+func NewTestLogger(tb TB) *Logger {
+	w := &tbWriter{tb: tb}
+
+	l := NewLogger()
+	l.SetSync(true)
+	l.SetMirrorWriter(w)
+
+	// Writing to the test cannot fail, so there are no errors to handle
+	_ = l.OpenWriter(w, "", NoPID)
+
+	tb.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	return l
+}
+
+// ExpectFatal runs fn and reports the error to tb if fn does not write the message that terminates
+// the program (see [Fatal]) to the package log. The program is not terminated: the exit function
+// is replaced (see [SetExitFunc]) while fn runs, so the code after the call of Fatal is executed too.
+// It returns the text of the last fatal message, e.g. to check it by the test:
+//
+//	if msg := log.ExpectFatal(t, func() { loadConfig("nonexistent.conf") }); !strings.Contains(msg, "no such file") {
+//		t.Errorf("unexpected fatal message: %q", msg)
+//	}
+//
+// The log must be opened, the original exit function is restored before returning.
+func ExpectFatal(tb TB, fn func()) string {
+	tb.Helper()
+
+	l := logger
+
+	// Fatal messages are sent to subscribers before calling the exit function
+	entries, unsubscribe := l.Subscribe(expectFatalBuf)
+	fatal := make(chan string, 1)
+	go func() {
+		msg := ""
+		for e := range entries {
+			if e.Level.exits() {
+				msg = e.Message
+			}
+		}
+		fatal <- msg
+	}()
+
+	exited := false
+	var prev func(int)
+	l.execute(func() {
+		prev = l.exitFunc
+		l.exitFunc = func(int) {
+			exited = true
+		}
+	})
+
+	fn()
+
+	// Restore the exit function after all messages written by fn
+	l.execute(func() {
+		l.exitFunc = prev
+	})
+	unsubscribe()
+	msg := <-fatal
+
+	if !exited {
+		tb.Errorf("fatal message is expected, but not written")
+	}
+
+	return msg
+}
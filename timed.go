@@ -0,0 +1,33 @@
+package log
+
+import (
+	"fmt"
+)
+
+// ElapsedKey is the key of the field with the duration of the operation, see [Timed].
+const ElapsedKey = "elapsed"
+
+// Timed calls [Timed] on the l object.
+func (l *Logger) Timed(level Level, format string, v ...any) func() {
+	start := l.now()
+	l.Msg(level, format + " started", v...)
+
+	// XXX Enable govet printf checking
+	if govetPrintfStub { _ = fmt.Sprintf(format, v...) }
+
+	return func() {
+		l.With(ElapsedKey, l.now().Sub(start)).Msg(level, format + " finished", v...)
+	}
+}
+
+// Timed writes the message of the level with the " started" suffix and returns the function that
+// writes the same message with the " finished" suffix and the duration elapsed since the call of Timed
+// as the field with the [ElapsedKey] key (formatted according to [SetDurationFormat]), e.g.
+//
+//	defer log.Timed(log.LevelInfo, "processing %s", name)()
+//
+// writes "processing file.txt started" immediately and "processing file.txt finished elapsed=1.5s"
+// on return. The duration is measured using the clock set by [SetClock].
+func Timed(level Level, format string, v ...any) func() {
+	return logger.Timed(level, format, v...)
+}
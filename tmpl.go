@@ -0,0 +1,104 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tmpl calls [Tmpl] on the l object.
+func (l *Logger) Tmpl(level Level, template string, fields map[string]any) {
+	if !l.WouldLog(level) {
+		return
+	}
+
+	// The template is rendered by the caller, because fields can be modified after returning
+	text, missing := l.renderTemplate(template, fields)
+
+	msg := &logMsg{level: level, format: "%s", args: []any{text}, extra: sortedFields(fields)}
+	if len(missing) != 0 {
+		msg.warning = fmt.Errorf("%w: no values for %s in template %q", ErrBadTemplate, strings.Join(missing, ", "), template)
+	}
+
+	l.writeEvent(msg)
+
+	// Call statistic function if was set
+	l.callStat(level, "%s", text)
+}
+
+// renderTemplate substitutes {name} placeholders by values of fields, {{ and }} are replaced by
+// literal braces. It returns the rendered text and names of placeholders without values.
+func (l *Logger) renderTemplate(template string, fields map[string]any) (string, []string) {
+	var b strings.Builder
+	var missing []string
+
+	for len(template) != 0 {
+		i := strings.IndexAny(template, "{}")
+		if i < 0 {
+			b.WriteString(template)
+			break
+		}
+
+		b.WriteString(template[:i])
+		template = template[i:]
+
+		// Escaped braces
+		if strings.HasPrefix(template, "{{") || strings.HasPrefix(template, "}}") {
+			b.WriteByte(template[0])
+			template = template[2:]
+			continue
+		}
+
+		end := strings.IndexByte(template, '}')
+		if template[0] == '}' || end < 0 {
+			// Unpaired brace, write it as is
+			b.WriteByte(template[0])
+			template = template[1:]
+			continue
+		}
+
+		name := template[1:end]
+		if v, ok := fields[name]; ok {
+			b.WriteString(l.fieldValue(v))
+		} else {
+			// Leave the placeholder verbatim
+			b.WriteString(template[:end+1])
+			missing = append(missing, name)
+		}
+		template = template[end+1:]
+	}
+
+	return b.String(), missing
+}
+
+// sortedFields returns fields of the map sorted by keys
+func sortedFields(fields map[string]any) []Field {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	out := make([]Field, 0, len(fields))
+	for k, v := range fields {
+		out = append(out, Field{Key: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+
+	return out
+}
+
+// Tmpl writes the message of the level using the template with named placeholders, e.g.
+//
+//	log.Tmpl(log.LevelWarn, "user {user} failed after {attempts} attempts", map[string]any{
+//		"user":		"root",
+//		"attempts":	3,
+//	})
+//
+// writes "<WRN> user root failed after 3 attempts". Values are formatted in the same way as values
+// of fields (see [With]). Use {{ and }} to write literal braces. Placeholders without values
+// are written verbatim and an error that wraps [ErrBadTemplate] is passed to the error handler
+// (see [SetErrorHandler]). Targets that receive structured entries (e.g. journald) and subscribers
+// (see [Subscribe]) also get fields as entry fields, sorted by keys, the text log contains
+// only the rendered message.
+func Tmpl(level Level, template string, fields map[string]any) {
+	logger.Tmpl(level, template, fields)
+}
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Names of fields added by *Ctx functions
+const (
+	TraceIDKey	=	"trace_id"
+	SpanIDKey	=	"span_id"
+)
+
+// TraceExtractor returns the trace and span IDs carried by the context, ok is false if
+// the context does not belong to any span. It can be set using [SetTraceExtractor].
+type TraceExtractor func(ctx context.Context) (traceID, spanID string, ok bool)
+
+// SetTraceExtractor calls [SetTraceExtractor] on the l object.
+func (l *Logger) SetTraceExtractor(te TraceExtractor) {
+	l.traceExtractor = te
+}
+
+// withTrace returns the child logger with the trace and span IDs from the context
+// or l itself if the IDs cannot be extracted
+func (l *Logger) withTrace(ctx context.Context) *Logger {
+	if l.traceExtractor == nil || ctx == nil {
+		return l
+	}
+
+	traceID, spanID, ok := l.traceExtractor(ctx)
+	if !ok {
+		return l
+	}
+
+	return l.With(TraceIDKey, traceID, SpanIDKey, spanID)
+}
+
+// DebugCtx calls [DebugCtx] on the l object.
+func (l *Logger) DebugCtx(ctx context.Context, format string, v ...any) {
+	if !l.WouldLog(LevelDebug) {
+		// Do not call the extractor for filtered messages
+		return
+	}
+	l.withTrace(ctx).Debug(format, v...)
+}
+
+// InfoCtx calls [InfoCtx] on the l object.
+func (l *Logger) InfoCtx(ctx context.Context, format string, v ...any) {
+	l.withTrace(ctx).Info(format, v...)
+}
+
+// WarnCtx calls [WarnCtx] on the l object.
+func (l *Logger) WarnCtx(ctx context.Context, format string, v ...any) {
+	l.withTrace(ctx).Warn(format, v...)
+}
+
+// ErrCtx calls [ErrCtx] on the l object.
+func (l *Logger) ErrCtx(ctx context.Context, format string, v ...any) {
+	l.withTrace(ctx).Err(format, v...)
+}
+
+// FatalCtx calls [FatalCtx] on the l object.
+func (l *Logger) FatalCtx(ctx context.Context, format string, v ...any) {
+	l.withTrace(ctx).Fatal(format, v...)
+}
+
+// SetTraceExtractor sets the function used by *Ctx functions (e.g. [InfoCtx]) to get
+// the trace and span IDs from the context. The IDs are added to the message as fields
+// named [TraceIDKey] and [SpanIDKey]. The package does not depend on any tracing library,
+// e.g. for OpenTelemetry the extractor can be written as:
+//
+//	log.SetTraceExtractor(func(ctx context.Context) (string, string, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", "", false
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String(), true
+//	})
+//
+// Without the extractor (default) *Ctx functions are the same as the functions without the suffix.
+func SetTraceExtractor(te TraceExtractor) {
+	logger.SetTraceExtractor(te)
+}
+
+// DebugCtx is the same as [Debug] but adds the trace and span IDs from ctx, see [SetTraceExtractor].
+func DebugCtx(ctx context.Context, format string, v ...any) {
+	if !DebugBuild || atomic.LoadInt32(&pkgLevel) > int32(LevelDebug) {
+		return
+	}
+	logger.DebugCtx(ctx, format, v...)
+}
+
+// InfoCtx is the same as [Info] but adds the trace and span IDs from ctx, see [SetTraceExtractor].
+func InfoCtx(ctx context.Context, format string, v ...any) {
+	logger.InfoCtx(ctx, format, v...)
+}
+
+// WarnCtx is the same as [Warn] but adds the trace and span IDs from ctx, see [SetTraceExtractor].
+func WarnCtx(ctx context.Context, format string, v ...any) {
+	logger.WarnCtx(ctx, format, v...)
+}
+
+// ErrCtx is the same as [Err] but adds the trace and span IDs from ctx, see [SetTraceExtractor].
+func ErrCtx(ctx context.Context, format string, v ...any) {
+	logger.ErrCtx(ctx, format, v...)
+}
+
+// FatalCtx is the same as [Fatal] but adds the trace and span IDs from ctx, see [SetTraceExtractor].
+func FatalCtx(ctx context.Context, format string, v ...any) {
+	logger.FatalCtx(ctx, format, v...)
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// SetMaxMessageBytes calls [SetMaxMessageBytes] on the l object.
+func (l *Logger) SetMaxMessageBytes(n int) {
+	// Messages are truncated by the writer goroutine
+	l.execute(func() {
+		l.maxMsgBytes = n
+	})
+}
+
+// truncateMessage cuts the text of the message to the limit set by SetMaxMessageBytes
+func (l *Logger) truncateMessage(text string) string {
+	limit := l.maxMsgBytes
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+
+	// Do not split the rune that crosses the limit
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+
+	return text[:limit] + "…(truncated " + strconv.Itoa(len(text) - limit) + " bytes)"
+}
+
+// SetMaxMessageBytes limits the text of messages to n bytes, e.g. to protect targets and additional writers
+// with limits of the record size from huge dumps. The rest of the text is replaced by the "…(truncated N bytes)"
+// marker, where N is the number of dropped bytes, UTF-8 characters are never split, so the text can be
+// a few bytes shorter than n. Only the text of the message is truncated (the "msg" value of JSON objects,
+// see [SetOutputFormat]), the prefix, the level tag and fields are kept. Statistic functions and escalation
+// rules (see [AddEscalationRule]) get the full text. Zero or negative n (default) means no limit.
+func SetMaxMessageBytes(n int) {
+	logger.SetMaxMessageBytes(n)
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mask of flags supported by Open functions: NoPID and flags of the standard log package
+const validFlags = NoPID | ((log.Lmsgprefix << 1) - 1)
+
+// ValidationError is returned by [Validate] and contains all problems found in options.
+type ValidationError struct {
+	Errs	[]error
+}
+
+func (ve *ValidationError) Error() string {
+	msgs := make([]string, 0, len(ve.Errs))
+	for _, err := range ve.Errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return "invalid log options: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns all found problems, so they can be checked by errors.Is and errors.As.
+func (ve *ValidationError) Unwrap() []error {
+	return ve.Errs
+}
+
+// Is reports whether any of found problems matches the target, for versions of Go
+// which do not support unwrapping of multiple errors.
+func (ve *ValidationError) Is(target error) bool {
+	for _, err := range ve.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate checks opts without opening the log and starting the writer goroutine: the log file and
+// the fallback file have to be writable (existing files are opened for writing and closed immediately,
+// for new files the temporary file is created and removed in their directory, so Validate does not create
+// them), flags and enumerated settings have to be known, durations, retries and limits must not be negative,
+// rotation (see [SetRotationAge]) and compression (see [SetCompressLive]) require the log file. Options
+// do not describe network targets, so they are not checked. Validate returns nil if opts are valid,
+// otherwise the [*ValidationError] with all found problems, e.g. to report misconfiguration at startup
+// instead of the first write to the log.
+func Validate(opts Options) error {
+	var errs []error
+	check := func(ok bool, format string, v ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, v...))
+		}
+	}
+
+	fileTarget := opts.Writer == nil && opts.File != DefaultLog
+	// Files of custom file systems (see SetFS) cannot be checked without creating them
+	osFiles := opts.FS == nil && loadDefaultFS() == nil
+	if fileTarget && osFiles {
+		if err := checkWritable(opts.File); err != nil {
+			errs = append(errs, NewFilePathError(opts.File, "log file is not writable: %w", err))
+		}
+	}
+	if opts.FallbackFile != "" && osFiles {
+		if err := checkWritable(opts.FallbackFile); err != nil {
+			errs = append(errs, NewFilePathError(opts.FallbackFile, "fallback file is not writable: %w", err))
+		}
+	}
+
+	check(opts.Flags & ^validFlags == 0, "unknown flags %#x", opts.Flags & ^validFlags)
+	check(opts.Level.valid(), "unknown level %d", opts.Level)
+	check(opts.SeverityStyle >= SeverityWords && opts.SeverityStyle <= SeverityCompact, "unknown severity style %d", opts.SeverityStyle)
+	check(opts.StderrMirror >= MirrorAlways && opts.StderrMirror <= MirrorNever, "unknown stderr mirror mode %d", opts.StderrMirror)
+	check(opts.OutputFormat >= FormatText && opts.OutputFormat <= FormatJSON, "unknown output format %d", opts.OutputFormat)
+	check(opts.FieldTypes >= FieldsNative && opts.FieldTypes <= FieldsAllStrings, "unknown field types %d", opts.FieldTypes)
+	check(opts.DurationFormat <= DurationNanos, "unknown duration format %d", opts.DurationFormat)
+
+	check(opts.DrainTimeout >= 0, "negative drain timeout %v", opts.DrainTimeout)
+	check(opts.WriteRetries >= 0, "negative number of write retries %d", opts.WriteRetries)
+	check(opts.WriteRetryBackoff >= 0, "negative write retry backoff %v", opts.WriteRetryBackoff)
+	check(opts.MaxFields >= 0, "negative maximum number of fields %d", opts.MaxFields)
+
+	check(opts.RotationAge >= 0, "negative rotation age %v", opts.RotationAge)
+	check(opts.RotationAge == 0 || fileTarget, "rotation age %v is set, but the log target is not a file", opts.RotationAge)
+	check(!opts.CompressLive || fileTarget, "compression is enabled, but the log target is not a file")
+
+	if len(errs) != 0 {
+		return &ValidationError{Errs: errs}
+	}
+
+	return nil
+}
+
+// checkWritable checks that the file can be opened for writing without creating it
+func checkWritable(path string) error {
+	fd, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, defaultPermMode)
+	if err == nil {
+		return fd.Close()
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// The file will be created in the directory
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".log-validate-*")
+	if err != nil {
+		return err
+	}
+
+	closeErr := tmp.Close()
+	if err = os.Remove(tmp.Name()); err != nil {
+		return err
+	}
+
+	return closeErr
+}
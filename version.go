@@ -0,0 +1,33 @@
+package log
+
+// SetVersion calls [SetVersion] on the l object.
+func (l *Logger) SetVersion(version string) {
+	l.execute(func() {
+		l.setVersion(version)
+	})
+}
+
+// setVersion keeps the version and its tag for text lines, so the tag is not built for each message
+func (l *Logger) setVersion(version string) {
+	l.version = version
+	l.versionTag = ""
+	if version != "" {
+		l.versionTag = "{" + version + "} "
+	}
+}
+
+// SetVersion sets the version of the program written with each message, e.g. to tell messages of
+// different releases apart during the rollout. Usually the version is injected by the linker:
+//
+//	var Version string // set by go build -ldflags "-X main.Version=1.4.2"
+//	...
+//	log.SetVersion(Version)
+//
+// In the text format the version is written in braces before the component and the level tag, e.g.
+// "app[1234]: {1.4.2} <ERR> message", in the [FormatJSON] format (see [SetOutputFormat]) it is written
+// with the "version" key after the "pid" key. Targets that receive structured entries (e.g. journald)
+// are not affected. The empty version (default) disables writing of the version.
+// Use [Options].Version to set it when opening the log.
+func SetVersion(version string) {
+	logger.SetVersion(version)
+}
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// lineWriter passes lines written to it to the logger as messages of the level
+type lineWriter struct {
+	l		*Logger
+	level	Level
+
+	mu		sync.Mutex
+	buf		[]byte	// incomplete line
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		n := bytes.IndexByte(w.buf, '\n')
+		if n < 0 {
+			// Keep the incomplete line until the rest of it is written
+			break
+		}
+
+		w.log(string(bytes.TrimSuffix(w.buf[:n], []byte{'\r'})))
+		w.buf = w.buf[n+1:]
+	}
+
+	// Do not keep the grown buffer if it is empty
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+
+	return len(p), nil
+}
+
+func (w *lineWriter) log(line string) {
+	w.l.Msg(w.level, "%s", line)
+}
+
+// Writer calls [Writer] on the l object.
+func (l *Logger) Writer(level Level) io.Writer {
+	return &lineWriter{l: l, level: level}
+}
+
+// TaggedWriter calls [TaggedWriter] on the l object.
+func (l *Logger) TaggedWriter(level Level, tag string) io.Writer {
+	return l.Component(tag).Writer(level)
+}
+
+// Writer returns a writer that writes each line written to it as a message of the level,
+// e.g. to capture the output of libraries that write to io.Writer or to the standard logger.
+// Incomplete lines are buffered until the newline is written. Line endings (\n or \r\n)
+// are removed. The writer is safe for concurrent use.
+func Writer(level Level) io.Writer {
+	return logger.Writer(level)
+}
+
+// TaggedWriter works like [Writer], but marks each line with the tag, in the same way as
+// the child logger returned by [Component], e.g. TaggedWriter(LevelInfo, "http") writes
+// lines like "app: [http] line", so lines captured from different libraries remain distinguishable.
+func TaggedWriter(level Level, tag string) io.Writer {
+	return logger.TaggedWriter(level, tag)
+}